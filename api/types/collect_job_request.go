@@ -23,6 +23,9 @@ type CollectJobRequest struct {
 	// collectors
 	Collectors []string `json:"collectors"`
 
+	// secret of the user and password to connect to TiDB
+	DbSecret string `json:"db_secret,omitempty"`
+
 	// explain sqls
 	ExplainSqls []string `json:"explain_sqls"`
 
@@ -17,7 +17,7 @@ import (
 // swagger:model OperateJobRequest
 type OperateJobRequest struct {
 
-	// operation
+	// retry (collect again from scratch) or resume (continue an interrupted job)
 	Operation string `json:"operation,omitempty"`
 }
 
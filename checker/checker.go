@@ -30,15 +30,18 @@ import (
 
 // Options are configs for checker
 type Options struct {
-	DataPath string
-	Inc      []string
-	OutPath  string
+	DataPath    string
+	Inc         []string
+	OutPath     string
+	RuleSources []string // extra rule files or dirs
+	Formats     []string // formats of the check report
 }
 
 // NewOptions creates a default Options
 func NewOptions() *Options {
 	return &Options{
-		Inc: []string{"config"},
+		Inc:     []string{"config"},
+		Formats: []string{render.FormatText},
 	}
 }
 
@@ -50,6 +53,9 @@ func (opt *Options) RunChecker(ctx context.Context) error {
 	// todo: checker action id
 	// todo: checker action time
 	// todo: version
+	if err := render.CheckFormats(opt.Formats); err != nil {
+		return err
+	}
 	var checkFlag sourcedata.CheckFlag
 	for _, val := range opt.Inc {
 		if val == "config" {
@@ -61,6 +67,12 @@ func (opt *Options) RunChecker(ctx context.Context) error {
 		if val == "default_config" {
 			checkFlag |= sourcedata.DefaultConfigFlag
 		}
+		if val == "metric" {
+			checkFlag |= sourcedata.MetricFlag
+		}
+		if val == "log" {
+			checkFlag |= sourcedata.LogFlag
+		}
 	}
 	// if output is not defined, use an auto generated one.
 	if len(opt.OutPath) == 0 {
@@ -73,18 +85,21 @@ func (opt *Options) RunChecker(ctx context.Context) error {
 		logger.Errorf("error fetching source data: %s", err)
 		return err
 	}
-	ruleSpec, err := config.LoadBetaRuleSpec()
+	ruleSpec, overrides, err := config.LoadRuleSpec(opt.RuleSources)
 	if err != nil {
 		logger.Errorf("error loading rule specs: %s", err)
 		return err
 	}
+	for _, o := range overrides {
+		logger.Infof("rule %d (%s) from %s is overridden by %s", o.ID, o.Name, o.Source, o.By)
+	}
 	data, ruleSet, err := fetch.FetchData(ruleSpec)
 	if err != nil {
 		logger.Errorf("error fetching data: %s", err)
 		return err
 	}
 	inc := strings.Join(opt.Inc, "-")
-	render := render.NewResultWrapper(data, ruleSet, opt.OutPath, inc)
+	render := render.NewResultWrapper(data, ruleSet, opt.OutPath, inc, opt.Formats)
 	wrapper := engine.NewWrapper(data, ruleSet, render)
 	// checkline.Init()
 	// pipe := checkline.GetResultChan()
@@ -27,6 +27,7 @@ var betaRuleStr string
 type RuleItem struct {
 	proto.Rule `yaml:",inline"`
 	Version    proto.VersionRange `yaml:"version" toml:"version"`
+	Source     string             `yaml:"-" toml:"-"` // file the rule is loaded from
 }
 
 type RuleSpec struct {
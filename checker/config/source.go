@@ -0,0 +1,265 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	genginebuilder "github.com/bilibili/gengine/builder"
+	genginecontext "github.com/bilibili/gengine/context"
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/diag/pkg/utils"
+)
+
+const (
+	// RuleDirectoryName is the sub-path name storing user defined rule files
+	// in the data dir of diag, e.g., ~/.tiup/storage/diag/rules
+	RuleDirectoryName = "rules"
+	// BuiltinRuleSource is the source name of the embedded rules
+	BuiltinRuleSource = "builtin"
+)
+
+var (
+	builtinValidateOnce sync.Once
+	builtinValidateErr  error
+)
+
+// validateBuiltinRules validates the built-in rules, they never change so
+// they are compiled only once in a process
+func validateBuiltinRules(spec *RuleSpec) error {
+	builtinValidateOnce.Do(func() {
+		builtinValidateErr = spec.Validate()
+	})
+	return builtinValidateErr
+}
+
+// RuleOverride is a rule replaced by another one with the same ID from a
+// source of higher precedence
+type RuleOverride struct {
+	ID     int64
+	Name   string
+	Source string // source of the replaced rule
+	By     string // source of the new rule
+}
+
+// LoadRuleSpec loads and merges rules from all sources, the precedence from
+// low to high is: the built-in rules, files in the user rule dir, and then
+// the files or dirs in sources. A rule replaces all rules with the same ID
+// from sources of lower precedence, while the same ID in different files of
+// the same precedence is a conflict. All merged rules, including the
+// built-in ones, are validated before returning, so that no check runs with
+// an invalid rule.
+func LoadRuleSpec(sources []string) (*RuleSpec, []RuleOverride, error) {
+	spec, err := LoadBetaRuleSpec()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range spec.Rule {
+		spec.Rule[i].Source = BuiltinRuleSource
+	}
+	if err := validateBuiltinRules(spec); err != nil {
+		return nil, nil, err
+	}
+
+	levels := make([][]string, 0, 2)
+	if dir, err := utils.DiagDataDir(); err == nil {
+		dir = filepath.Join(dir, RuleDirectoryName)
+		if _, err := os.Stat(dir); err == nil {
+			levels = append(levels, []string{dir})
+		}
+	}
+	levels = append(levels, sources)
+
+	overrides := make([]RuleOverride, 0)
+	for _, level := range levels {
+		files, err := listRuleFiles(level)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		levelSpec := &RuleSpec{Rule: []RuleItem{}}
+		defined := make(map[int64]string)
+		for _, file := range files {
+			fileSpec, err := LoadRuleFile(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, item := range fileSpec.Rule {
+				if src, ok := defined[item.ID]; ok {
+					return nil, nil, fmt.Errorf("rule id %d is defined in both %s and %s", item.ID, src, file)
+				}
+				defined[item.ID] = file
+			}
+			levelSpec.Rule = append(levelSpec.Rule, fileSpec.Rule...)
+		}
+		overrides = append(overrides, spec.Merge(levelSpec)...)
+	}
+	// the built-in rules left after merging are validated above
+	extra := &RuleSpec{Rule: []RuleItem{}}
+	for _, item := range spec.Rule {
+		if item.Source != BuiltinRuleSource {
+			extra.Rule = append(extra.Rule, item)
+		}
+	}
+	if err := extra.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return spec, overrides, nil
+}
+
+// listRuleFiles expands dirs in the list to all .toml files in them
+func listRuleFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0)
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".toml") {
+				names = append(names, filepath.Join(p, e.Name()))
+			}
+		}
+		sort.Strings(names)
+		files = append(files, names...)
+	}
+	return files, nil
+}
+
+// LoadRuleFile loads rules from a file
+func LoadRuleFile(path string) (*RuleSpec, error) {
+	ruleSpec := &RuleSpec{Rule: []RuleItem{}}
+	if _, err := toml.DecodeFile(path, ruleSpec); err != nil {
+		return nil, fmt.Errorf("failed to load rules from %s: %s", path, err)
+	}
+	for i := range ruleSpec.Rule {
+		ruleSpec.Rule[i].Source = path
+	}
+	return ruleSpec, nil
+}
+
+// Merge adds rules of other to the spec, existing rules with the same ID
+// are replaced and returned
+func (rs *RuleSpec) Merge(other *RuleSpec) []RuleOverride {
+	ids := make(map[int64]RuleItem, len(other.Rule))
+	for _, item := range other.Rule {
+		ids[item.ID] = item
+	}
+	overrides := make([]RuleOverride, 0)
+	merged := make([]RuleItem, 0, len(rs.Rule)+len(other.Rule))
+	for _, item := range rs.Rule {
+		if by, ok := ids[item.ID]; ok {
+			overrides = append(overrides, RuleOverride{
+				ID:     item.ID,
+				Name:   item.Name,
+				Source: item.Source,
+				By:     by.Source,
+			})
+			continue
+		}
+		merged = append(merged, item)
+	}
+	rs.Rule = append(merged, other.Rule...)
+	return overrides
+}
+
+// Validate checks required fields of all rules and compiles their execute
+// rules, errors of all invalid rules are returned together
+func (rs *RuleSpec) Validate() error {
+	errs := make([]string, 0)
+	for _, item := range rs.Rule {
+		if err := item.validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("rule %d (%s) from %s: %s", item.ID, item.Name, item.Source, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid rules found:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+func (item *RuleItem) validate() error {
+	switch {
+	case item.Name == "":
+		return fmt.Errorf("name is empty")
+	case item.NameStruct == "":
+		return fmt.Errorf("name_struct is empty")
+	case item.CheckType == "":
+		return fmt.Errorf("check_type is empty")
+	case item.CheckType != proto.ConsistencyType && strings.TrimSpace(item.ExecuteRule) == "":
+		return fmt.Errorf("execute_rule is empty")
+	}
+	if _, err := item.Version.Contain("v5.0.0"); err != nil {
+		return fmt.Errorf("invalid version range '%s': %s", item.Version, err)
+	}
+	if item.CheckType == proto.MetricType {
+		if item.Metric == nil {
+			return fmt.Errorf("metric is not set for a metric rule")
+		}
+		if err := item.Metric.Validate(); err != nil {
+			return err
+		}
+	}
+	if item.CheckType == proto.LogType {
+		if item.Log == nil {
+			return fmt.Errorf("log is not set for a log rule")
+		}
+		if err := item.Log.Validate(); err != nil {
+			return err
+		}
+	}
+	if item.CheckType == proto.ConsistencyType {
+		if item.Consistency == nil {
+			return fmt.Errorf("consistency is not set for a consistency rule")
+		}
+		if err := item.Consistency.Validate(item.NameStruct); err != nil {
+			return err
+		}
+		// consistency rules compare keys of all instances without execute_rule,
+		// but it is still compiled if set
+		if strings.TrimSpace(item.ExecuteRule) == "" {
+			return nil
+		}
+	}
+
+	// the result of a rule is collected by the name in execute_rule
+	builder := genginebuilder.NewRuleBuilder(genginecontext.NewDataContext())
+	if err := builder.BuildRuleFromString(item.ExecuteRule); err != nil {
+		return fmt.Errorf("failed to compile execute_rule: %s", err)
+	}
+	if len(builder.Kc.RuleEntities) != 1 {
+		return fmt.Errorf("execute_rule must define exactly one rule, got %d", len(builder.Kc.RuleEntities))
+	}
+	if _, ok := builder.Kc.RuleEntities[item.Name]; !ok {
+		return fmt.Errorf("the rule defined in execute_rule is not named '%s'", item.Name)
+	}
+	return nil
+}
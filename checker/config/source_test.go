@@ -0,0 +1,193 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/stretchr/testify/require"
+)
+
+func testRule(id int, name, cond string) string {
+	return fmt.Sprintf(`
+[[rule]]
+id = %d
+name = "%s"
+description = "test rule"
+variation = "TidbConfig.log.level"
+check_type = "config"
+execute_rule = """
+rule "%s"
+begin
+    if %s {
+        return true
+    } else {
+        return false
+    }
+end
+"""
+name_struct = "TidbConfig"
+expect_res = ""
+warn_level = "warning"
+version = ""
+`, id, name, name, cond)
+}
+
+func TestLoadRuleSpec(t *testing.T) {
+	assert := require.New(t)
+
+	dataDir := t.TempDir()
+	t.Setenv(localdata.EnvNameComponentDataDir, dataDir)
+	builtin, err := LoadBetaRuleSpec()
+	assert.Nil(err)
+
+	// no extra rules
+	spec, overrides, err := LoadRuleSpec(nil)
+	assert.Nil(err)
+	assert.Empty(overrides)
+	assert.Len(spec.Rule, len(builtin.Rule))
+	assert.Equal(BuiltinRuleSource, spec.Rule[0].Source)
+
+	// user rules override built-in ones, and are overridden by extra sources
+	userDir := filepath.Join(dataDir, RuleDirectoryName)
+	assert.Nil(os.MkdirAll(userDir, 0755))
+	assert.Nil(os.WriteFile(filepath.Join(userDir, "house.toml"),
+		[]byte(testRule(7, "user-log-level", "true")+testRule(90001, "user-rule", "true")), 0644))
+	assert.Nil(os.WriteFile(filepath.Join(userDir, "README.md"), []byte("not a rule file"), 0644))
+	extra := filepath.Join(t.TempDir(), "extra.toml")
+	assert.Nil(os.WriteFile(extra, []byte(testRule(90001, "extra-rule", "1 == 1")), 0644))
+
+	spec, overrides, err = LoadRuleSpec([]string{extra})
+	assert.Nil(err)
+	assert.Len(spec.Rule, len(builtin.Rule)+1)
+	assert.Len(overrides, 2)
+	assert.Equal(RuleOverride{ID: 7, Name: "tikv-log-level", Source: BuiltinRuleSource, By: filepath.Join(userDir, "house.toml")}, overrides[0])
+	assert.Equal(RuleOverride{ID: 90001, Name: "user-rule", Source: filepath.Join(userDir, "house.toml"), By: extra}, overrides[1])
+
+	rules := make(map[int64]RuleItem)
+	for _, item := range spec.Rule {
+		rules[item.ID] = item
+	}
+	assert.Equal("user-log-level", rules[7].Name)
+	assert.Equal("extra-rule", rules[90001].Name)
+	assert.Equal(extra, rules[90001].Source)
+
+	// conflicts in the same precedence
+	conflict := filepath.Join(t.TempDir(), "conflict.toml")
+	assert.Nil(os.WriteFile(conflict, []byte(testRule(90001, "conflict-rule", "true")), 0644))
+	_, _, err = LoadRuleSpec([]string{extra, conflict})
+	assert.ErrorContains(err, "rule id 90001 is defined in both")
+
+	// the merged rules are validated
+	invalid := filepath.Join(t.TempDir(), "invalid.toml")
+	assert.Nil(os.WriteFile(invalid, []byte(testRule(90002, "invalid-rule", "(")), 0644))
+	_, _, err = LoadRuleSpec([]string{invalid})
+	assert.ErrorContains(err, "rule 90002 (invalid-rule)")
+
+	// missing source
+	_, _, err = LoadRuleSpec([]string{filepath.Join(t.TempDir(), "missing.toml")})
+	assert.NotNil(err)
+}
+
+func TestValidateRules(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.toml")
+	assert.Nil(os.WriteFile(valid, []byte(testRule(1, "valid", "true")), 0644))
+	spec, err := LoadRuleFile(valid)
+	assert.Nil(err)
+	assert.Nil(spec.Validate())
+
+	for name, update := range map[string]func(item *RuleItem){
+		"syntax":   func(item *RuleItem) { item.ExecuteRule = "rule \"syntax\" begin" },
+		"empty":    func(item *RuleItem) { item.NameStruct = "" },
+		"version":  func(item *RuleItem) { item.Version = "v5.0.0||>>" },
+		"mismatch": func(item *RuleItem) { item.Name = "another-name" },
+		"multiple": func(item *RuleItem) { item.ExecuteRule += "\nrule \"another\"\nbegin\nreturn true\nend\n" },
+	} {
+		spec, err := LoadRuleFile(valid)
+		assert.Nil(err)
+		update(&spec.Rule[0])
+		assert.ErrorContains(spec.Validate(), valid, name)
+	}
+
+	_, err = LoadRuleFile(filepath.Join(dir, "missing.toml"))
+	assert.NotNil(err)
+
+	// metric rules
+	for name, metric := range map[string]*proto.MetricRule{
+		"expr":     {Expr: "sum(rate(a[1m])", Window: "10m"},
+		"window":   {Expr: "a", Window: "ten minutes"},
+		"operator": {Expr: "a", Operator: ">="},
+	} {
+		spec, err := LoadRuleFile(valid)
+		assert.Nil(err)
+		spec.Rule[0].CheckType = proto.MetricType
+		assert.ErrorContains(spec.Validate(), "metric is not set", name)
+		spec.Rule[0].Metric = metric
+		assert.ErrorContains(spec.Validate(), name, name)
+		spec.Rule[0].Metric = &proto.MetricRule{Expr: "a", Window: "10m", Threshold: 1}
+		assert.Nil(spec.Validate())
+	}
+
+	// log rules
+	for name, rule := range map[string]*proto.LogRule{
+		"pattern": {Pattern: "server is (busy"},
+		"field":   {Fields: map[string]string{"region_id": "("}},
+		"level":   {Pattern: "panic", Levels: []string{"fatal", "critical", "severe"}},
+		"bucket":  {Pattern: "panic", Bucket: "a minute"},
+	} {
+		spec, err := LoadRuleFile(valid)
+		assert.Nil(err)
+		spec.Rule[0].CheckType = proto.LogType
+		assert.ErrorContains(spec.Validate(), "log is not set", name)
+		spec.Rule[0].Log = rule
+		assert.ErrorContains(spec.Validate(), name, name)
+		spec.Rule[0].Log = &proto.LogRule{Pattern: "panic", Bucket: "1m", Threshold: 1}
+		assert.Nil(spec.Validate())
+	}
+
+	// consistency rules are valid without execute_rule
+	spec, err = LoadRuleFile(valid)
+	assert.Nil(err)
+	spec.Rule[0].CheckType = proto.ConsistencyType
+	spec.Rule[0].ExecuteRule = ""
+	assert.ErrorContains(spec.Validate(), "consistency is not set")
+	spec.Rule[0].NameStruct = proto.TikvComponentName
+	spec.Rule[0].Consistency = &proto.ConsistencyRule{Keys: []string{"storage.block-cache.capacity", "storage.block-cache.size"}}
+	assert.ErrorContains(spec.Validate(), "unknown key 'storage.block-cache.size'")
+	spec.Rule[0].NameStruct = proto.PerformanceDashboardComponentName
+	assert.ErrorContains(spec.Validate(), "only support config name_structs")
+	spec.Rule[0].NameStruct = proto.TikvComponentName
+	spec.Rule[0].Consistency.Keys = []string{"storage.block-cache.capacity", "log.level"}
+	assert.Nil(spec.Validate())
+	// execute_rule is still compiled if set
+	spec.Rule[0].ExecuteRule = "rule \"syntax\" begin"
+	assert.ErrorContains(spec.Validate(), "failed to compile execute_rule")
+}
+
+func TestValidateBuiltinRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling all built-in rules is slow")
+	}
+	spec, err := LoadBetaRuleSpec()
+	require.Nil(t, err)
+	require.Nil(t, validateBuiltinRules(spec))
+}
@@ -42,6 +42,9 @@ func NewWrapper(sd *proto.SourceDataV2, rs map[string]*proto.Rule, rd *render.Re
 
 func (w *Wrapper) Start(ctx context.Context) error {
 	for _, rule := range w.RuleSet {
+		if rule.CheckType == proto.ConsistencyType {
+			continue // compared in ExecConsistency
+		}
 		dataSet, err := w.GetDataSet(rule.NameStruct)
 		if err != nil {
 			return fmt.Errorf("get DataSet Failed, %s", err.Error())
@@ -59,8 +62,10 @@ func (w *Wrapper) Start(ctx context.Context) error {
 	if err := w.Exec(); err != nil {
 		return err
 	}
-	w.Render.Output(ctx, w.RuleResult) // todo@toto add ruleResultPrint
-	return nil
+	if err := w.ExecConsistency(); err != nil {
+		return err
+	}
+	return w.Render.Output(ctx, w.RuleResult) // todo@toto add ruleResultPrint
 }
 
 func (w *Wrapper) Exec() error {
@@ -79,6 +84,27 @@ func (w *Wrapper) Exec() error {
 	return nil
 }
 
+// ExecConsistency compares keys of consistency rules across all instances of
+// the component, the data of all instances are handled together instead of
+// being crossed into compute units
+func (w *Wrapper) ExecConsistency() error {
+	for _, rule := range w.RuleSet {
+		if rule.CheckType != proto.ConsistencyType {
+			continue
+		}
+		data, err := w.FindData(rule.NameStruct)
+		if err != nil {
+			return fmt.Errorf("get DataSet Failed, %s", err.Error())
+		}
+		hd := &proto.HandleData{UqiTag: rule.NameStruct, Data: data, IsValid: len(data) > 0}
+		if err := w.PackageResult(hd, map[string]interface{}{rule.Name: nil}); err != nil {
+			log.Error(fmt.Sprintf("package result failed, %s", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
 func (w *Wrapper) PackageResult(hd *proto.HandleData, resultset map[string]interface{}) error {
 	for rulename, res := range resultset {
 		rule, isExisted := w.RuleSet[rulename]
@@ -93,6 +119,12 @@ func (w *Wrapper) PackageResult(hd *proto.HandleData, resultset map[string]inter
 				rulePrinter = proto.NewConfPrintTemplate(rule) // todo@toto add new func
 			case proto.PerformanceType:
 				rulePrinter = proto.NewSQLPerformancePrintTemplate(rule) // todo@toto add new func
+			case proto.MetricType:
+				rulePrinter = proto.NewMetricPrintTemplate(rule)
+			case proto.LogType:
+				rulePrinter = proto.NewLogPrintTemplate(rule)
+			case proto.ConsistencyType:
+				rulePrinter = proto.NewConsistencyPrintTemplate(rule)
 			default:
 				log.Error("can't handle such type rule: ", zap.String("checktype", rule.CheckType))
 				return fmt.Errorf("can't handle %s type rule: ", rule.CheckType)
@@ -175,6 +207,16 @@ func (w *Wrapper) FindData(namestruct string) ([]proto.Data, error) {
 	} else if namestruct == "performance.dashboard" {
 		sqlPerformance := w.SourceData.DashboardData
 		return []proto.Data{sqlPerformance}, nil
+	} else if namestruct == proto.MonitorMetricComponentName {
+		if w.SourceData.MetricData == nil {
+			return nil, fmt.Errorf("metric data is not loaded")
+		}
+		return []proto.Data{w.SourceData.MetricData}, nil
+	} else if namestruct == proto.LogPatternComponentName {
+		if w.SourceData.LogData == nil {
+			return nil, fmt.Errorf("log data is not loaded")
+		}
+		return []proto.Data{w.SourceData.LogData}, nil
 	}
 	return nil, fmt.Errorf("no such namestruct: %s", namestruct)
 }
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -202,3 +203,46 @@ func TestWrapper_CrossData(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapper_ExecConsistency(t *testing.T) {
+	sd := &proto.SourceDataV2{NodesData: make(map[string][]proto.Config)}
+	for i, capacity := range []string{"10GiB", "20GiB", "10GiB"} {
+		cfg := proto.NewTikvConfigData()
+		cfg.Host = fmt.Sprintf("10.0.0.%d", i+1)
+		cfg.Port = 20160
+		cfg.Storage.BlockCache.Capacity = capacity
+		cfg.Log.Level = "info"
+		sd.AppendConfig(cfg, proto.TikvComponentName)
+	}
+	missing := proto.NewTikvConfigData()
+	missing.TikvConfig = nil
+	sd.AppendConfig(missing, proto.TikvComponentName)
+
+	w := NewWrapper(sd, map[string]*proto.Rule{
+		"tikv_config_consistency": {
+			Name:        "tikv_config_consistency",
+			CheckType:   proto.ConsistencyType,
+			NameStruct:  proto.TikvComponentName,
+			WarnLevel:   "warning",
+			Consistency: &proto.ConsistencyRule{Keys: []string{"storage.block-cache.capacity", "log.level"}},
+		},
+	}, nil)
+	if err := w.ExecConsistency(); err != nil {
+		t.Fatal(err)
+	}
+	printer, ok := w.RuleResult["tikv_config_consistency"].(*proto.ConsistencyPrintTemplate)
+	if !ok {
+		t.Fatalf("wrong printer: %v", w.RuleResult)
+	}
+	if !printer.ResultAbnormal() {
+		t.Errorf("inconsistent block cache capacity is not found")
+	}
+	capacity, level := printer.InfoList[0], printer.InfoList[1]
+	if capacity.CheckResult != "warning" || level.CheckResult != "OK" {
+		t.Errorf("wrong check results: %s, %s", capacity.CheckResult, level.CheckResult)
+	}
+	expect := "10GiB: 10.0.0.1:20160, 10.0.0.3:20160\n20GiB: 10.0.0.2:20160"
+	if diff := capacity.Diff(); diff != expect {
+		t.Errorf("wrong diff: %s", diff)
+	}
+}
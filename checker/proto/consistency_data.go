@@ -0,0 +1,178 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lensesio/tableprinter"
+)
+
+// ConsistencyRule is the config keys of a consistency rule, a key is
+// abnormal if its values differ between instances of the component in the
+// name_struct, e.g., storage.block-cache.capacity of TiKV
+type ConsistencyRule struct {
+	Keys []string `yaml:"keys" toml:"keys"` // tag paths of the config
+}
+
+// emptyConfigs creates configs to check keys of consistency rules
+var emptyConfigs = map[ComponentName]func() Config{
+	PdComponentName:   func() Config { return NewPdConfigData() },
+	TidbComponentName: func() Config { return NewTidbConfigData() },
+	TikvComponentName: func() Config { return NewTikvConfigData() },
+}
+
+// Validate checks the keys exist in the config of the component
+func (r *ConsistencyRule) Validate(component ComponentName) error {
+	newConfig, ok := emptyConfigs[component]
+	if !ok {
+		return fmt.Errorf("consistency rules only support config name_structs, got '%s'", component)
+	}
+	if len(r.Keys) == 0 {
+		return fmt.Errorf("no keys to compare")
+	}
+	cfg := newConfig()
+	for _, key := range r.Keys {
+		if !cfg.GetValueByTagPath(key).IsValid() {
+			return fmt.Errorf("unknown key '%s' of %s", key, component)
+		}
+	}
+	return nil
+}
+
+type ConsistencyPrintTemplate struct {
+	Rule     *Rule
+	InfoList []*ConsistencyInfo
+}
+
+// ConsistencyInfo is the values of a key grouped by instances, the largest
+// group is the first
+type ConsistencyInfo struct {
+	Key         string
+	Groups      []*ConsistencyGroup
+	CheckResult string
+}
+
+// ConsistencyGroup is the instances with the same value of a key
+type ConsistencyGroup struct {
+	Value     string
+	Instances []string // host:port
+}
+
+// Diff returns the groups of the key as lines of "value: instances"
+func (info *ConsistencyInfo) Diff() string {
+	lines := make([]string, 0, len(info.Groups))
+	for _, g := range info.Groups {
+		lines = append(lines, fmt.Sprintf("%s: %s", g.Value, strings.Join(g.Instances, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type consistencyRow struct {
+	Key         string `header:"Key"`
+	Val         string `header:"val"`
+	Instances   string `header:"Instances"`
+	CheckResult string `header:"CheckResult"`
+}
+
+func NewConsistencyPrintTemplate(rule *Rule) *ConsistencyPrintTemplate {
+	return &ConsistencyPrintTemplate{
+		Rule: rule,
+	}
+}
+
+// CollectResult groups instances by the values of each key, the data of the
+// handle data is the configs of all instances of the component
+func (c *ConsistencyPrintTemplate) CollectResult(hd *HandleData, _ interface{}) error {
+	if hd == nil {
+		return fmt.Errorf("handle data is nil")
+	}
+	configs := make([]Config, 0, len(hd.Data))
+	for _, data := range hd.Data {
+		conf, ok := data.(Config)
+		if !ok {
+			return fmt.Errorf("convert into config failed, %v", reflect.TypeOf(data))
+		}
+		if !conf.CheckNil() {
+			configs = append(configs, conf)
+		}
+	}
+	for _, key := range c.Rule.Consistency.Keys {
+		info := &ConsistencyInfo{Key: key, CheckResult: "OK"}
+		c.InfoList = append(c.InfoList, info)
+		if len(configs) == 0 {
+			info.CheckResult = "nodata"
+			continue
+		}
+		groups := make(map[string]*ConsistencyGroup)
+		for _, conf := range configs {
+			val := fmt.Sprintf("%v", conf.GetValueByTagPath(key))
+			g, ok := groups[val]
+			if !ok {
+				g = &ConsistencyGroup{Value: val}
+				groups[val] = g
+				info.Groups = append(info.Groups, g)
+			}
+			g.Instances = append(g.Instances, fmt.Sprintf("%s:%d", conf.GetHost(), conf.GetPort()))
+		}
+		for _, g := range info.Groups {
+			sort.Strings(g.Instances)
+		}
+		sort.Slice(info.Groups, func(i, j int) bool {
+			a, b := info.Groups[i], info.Groups[j]
+			if len(a.Instances) != len(b.Instances) {
+				return len(a.Instances) > len(b.Instances)
+			}
+			return a.Value < b.Value
+		})
+		if len(info.Groups) > 1 {
+			info.CheckResult = c.Rule.WarnLevel
+		}
+	}
+	return nil
+}
+
+func (c *ConsistencyPrintTemplate) Print(out io.Writer) {
+	printer := tableprinter.New(out)
+	for _, info := range c.InfoList {
+		if len(info.Groups) == 0 {
+			row, nums := tableprinter.StructParser.ParseRow(reflect.ValueOf(&consistencyRow{
+				Key: info.Key, CheckResult: info.CheckResult,
+			}).Elem())
+			printer.RenderRow(row, nums)
+			continue
+		}
+		for i, g := range info.Groups {
+			r := &consistencyRow{Val: g.Value, Instances: strings.Join(g.Instances, ",")}
+			if i == 0 {
+				r.Key, r.CheckResult = info.Key, info.CheckResult
+			}
+			row, nums := tableprinter.StructParser.ParseRow(reflect.ValueOf(r).Elem())
+			printer.RenderRow(row, nums)
+		}
+	}
+}
+
+func (c *ConsistencyPrintTemplate) ResultAbnormal() bool {
+	for _, info := range c.InfoList {
+		if strings.ToLower(info.CheckResult) != "ok" && strings.ToLower(info.CheckResult) != "nodata" {
+			return true
+		}
+	}
+	return false
+}
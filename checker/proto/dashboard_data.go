@@ -9,6 +9,7 @@ type DashboardData struct {
 	TombStoneStatistics struct {
 		Count int
 	}
+	SlowQuery *SlowQueryReport // nil if slow logs are not analyzed
 }
 
 type ExecutionPlanInfo struct {
@@ -34,16 +34,24 @@ const (
 	TikvComponentName                 ComponentName = "TikvConfig"
 	TiflashComponentName              ComponentName = "TiflashConfig"
 	PerformanceDashboardComponentName ComponentName = "performance.dashboard"
+	MonitorMetricComponentName        ComponentName = "monitor.metric"
+	LogPatternComponentName           ComponentName = "log.pattern"
 
 	ConfigType        = "config"
 	PerformanceType   = "performance"
 	DefaultConfigType = "defaultConfig"
+	MetricType        = "metric"
+	LogType           = "log"
+	ConsistencyType   = "consistency"
 )
 
 var CheckTypeOrder = map[string]int{
 	ConfigType:        0,
 	PerformanceType:   1,
 	DefaultConfigType: 2,
+	MetricType:        3,
+	LogType:           4,
+	ConsistencyType:   5,
 }
 
 type SourceDataV2 struct {
@@ -51,6 +59,8 @@ type SourceDataV2 struct {
 	TidbVersion   string
 	NodesData     map[ComponentName][]Config // {"component": {config, config, config, nil}}
 	DashboardData *DashboardData
+	MetricData    *MetricData
+	LogData       *LogData
 }
 
 func (sd *SourceDataV2) AppendConfig(cfg Config, component ComponentName) {
@@ -347,17 +357,31 @@ type DeviceData struct{}
 // ruletag: checkType, datatype, component
 type Rule struct {
 	// version
-	ID           int64  `yaml:"id" toml:"id"`
-	Name         string `yaml:"name" toml:"name"`
-	Description  string `yaml:"description" toml:"description"`
-	ExecuteRule  string `yaml:"execute_rule" toml:"execute_rule"`
-	NameStruct   string `yaml:"name_struct" toml:"name_struct"` // datatype.component
-	CheckType    string `yaml:"check_type" toml:"check_type"`
-	ExpectRes    string `yaml:"expect_res" toml:"expect_res"`
-	WarnLevel    string `yaml:"warn_level" toml:"warn_level"`
-	Variation    string `yaml:"variation" toml:"variation"` // e.g. tidb.file.max_days,
-	AlertingRule string `yaml:"alerting_rule" toml:"alerting_rule"`
-	Suggestion   string `yaml:"suggestion" toml:"suggestion"`
+	ID           int64            `yaml:"id" toml:"id"`
+	Name         string           `yaml:"name" toml:"name"`
+	Description  string           `yaml:"description" toml:"description"`
+	ExecuteRule  string           `yaml:"execute_rule" toml:"execute_rule"`
+	NameStruct   string           `yaml:"name_struct" toml:"name_struct"` // datatype.component
+	CheckType    string           `yaml:"check_type" toml:"check_type"`
+	ExpectRes    string           `yaml:"expect_res" toml:"expect_res"`
+	WarnLevel    string           `yaml:"warn_level" toml:"warn_level"`
+	Variation    string           `yaml:"variation" toml:"variation"` // e.g. tidb.file.max_days,
+	AlertingRule string           `yaml:"alerting_rule" toml:"alerting_rule"`
+	Suggestion   string           `yaml:"suggestion" toml:"suggestion"`
+	Default      DefaultValue     `yaml:"default" toml:"default"`         // expected value of default config rules
+	Metric       *MetricRule      `yaml:"metric" toml:"metric"`           // expression of metric rules
+	Log          *LogRule         `yaml:"log" toml:"log"`                 // pattern of log rules
+	Consistency  *ConsistencyRule `yaml:"consistency" toml:"consistency"` // keys of consistency rules
+}
+
+// DefaultValue is the default value of a config, it is written as a string
+// in most rules but could be of any type of TOML
+type DefaultValue string
+
+// UnmarshalTOML implements the toml.Unmarshaler interface
+func (v *DefaultValue) UnmarshalTOML(data interface{}) error {
+	*v = DefaultValue(fmt.Sprint(data))
+	return nil
 }
 
 type RuleSet map[string]*Rule //  TODO e.g {"Config": {"TidbConfigData": {&Rule{}, &Rule{}}}, "Dashboard": {}}
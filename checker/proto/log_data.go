@@ -0,0 +1,232 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lensesio/tableprinter"
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/pingcap/diag/collector/log/parser"
+)
+
+// LogRule is the pattern of a log rule, an instance is abnormal if the logs
+// matching the pattern in a time bucket reach the threshold, e.g., more than
+// 10 "server is busy" logs of a TiKV in 1m
+type LogRule struct {
+	Pattern    string            `yaml:"pattern" toml:"pattern"`       // regexp matching the log content
+	Fields     map[string]string `yaml:"fields" toml:"fields"`         // regexps matching values of [key=value] fields
+	Components []string          `yaml:"components" toml:"components"` // all components if empty
+	Levels     []string          `yaml:"levels" toml:"levels"`         // all levels if empty
+	Bucket     string            `yaml:"bucket" toml:"bucket"`         // duration, the whole time range is a bucket if empty
+	Threshold  int               `yaml:"threshold" toml:"threshold"`   // min count of a bucket to be abnormal, default to 1
+}
+
+// Validate checks the patterns, levels and bucket of the rule
+func (l *LogRule) Validate() error {
+	if l.Pattern == "" && len(l.Fields) == 0 {
+		return fmt.Errorf("neither pattern nor fields is set")
+	}
+	if _, err := regexp.Compile(l.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %s", l.Pattern, err)
+	}
+	for key, pattern := range l.Fields {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern '%s' of field '%s': %s", pattern, key, err)
+		}
+	}
+	if _, err := l.LevelTypes(); err != nil {
+		return err
+	}
+	if _, err := l.BucketDuration(); err != nil {
+		return err
+	}
+	if l.Threshold < 0 {
+		return fmt.Errorf("invalid threshold %d", l.Threshold)
+	}
+	return nil
+}
+
+// LevelTypes parses the levels of the rule
+func (l *LogRule) LevelTypes() ([]item.LevelType, error) {
+	levels := make([]item.LevelType, 0, len(l.Levels))
+	for _, s := range l.Levels {
+		level := parser.ParseLogLevel([]byte(s))
+		if level == item.LevelInvalid {
+			return nil, fmt.Errorf("invalid level '%s'", s)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// BucketDuration parses the bucket of the rule
+func (l *LogRule) BucketDuration() (time.Duration, error) {
+	if l.Bucket == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(l.Bucket)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid bucket '%s'", l.Bucket)
+	}
+	return d, nil
+}
+
+// MinCount returns the threshold of the rule
+func (l *LogRule) MinCount() int {
+	if l.Threshold < 1 {
+		return 1
+	}
+	return l.Threshold
+}
+
+// LogData is the result of log rules evaluated on collected logs
+type LogData struct {
+	Results map[string]*LogResult // by rule names
+}
+
+// LogResult is the result of a log rule
+type LogResult struct {
+	Error     string // the rule is not evaluated, e.g., no logs collected
+	Instances []*LogInstanceResult
+}
+
+// LogInstanceResult is the matched logs of an instance of a log rule
+type LogInstanceResult struct {
+	Component string
+	Instance  string // host:port
+	Count     int
+	MaxCount  int       // the max count of a bucket
+	MaxBucket time.Time // start time of the bucket with the max count
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Samples   []string // the earliest matched logs
+	Abnormal  bool
+}
+
+func (d *LogData) ActingName() string {
+	return "log"
+}
+
+// Passed returns true if no instance of the rule is abnormal, it is used by
+// execute rules of log rules
+func (d *LogData) Passed(name string) bool {
+	r, ok := d.Results[name]
+	if !ok {
+		return true
+	}
+	for _, inst := range r.Instances {
+		if inst.Abnormal {
+			return false
+		}
+	}
+	return true
+}
+
+type LogPrintTemplate struct {
+	Rule     *Rule
+	InfoList []*LogInfo
+}
+
+type LogInfo struct {
+	Instance    string `header:"Instance"`
+	Val         string `header:"val"`
+	CheckResult string `header:"CheckResult"`
+	Samples     []string
+}
+
+func NewLogPrintTemplate(rule *Rule) *LogPrintTemplate {
+	return &LogPrintTemplate{
+		Rule: rule,
+	}
+}
+
+func (c *LogPrintTemplate) CollectResult(hd *HandleData, retValue interface{}) error {
+	if hd == nil {
+		return fmt.Errorf("handle data is nil")
+	}
+	if !hd.IsValid {
+		c.InfoList = append(c.InfoList, &LogInfo{Instance: hd.UqiTag, CheckResult: "nodata"})
+		return nil
+	}
+	data, ok := hd.Data[0].(*LogData)
+	if !ok {
+		return fmt.Errorf("convert into log data failed, %v", reflect.TypeOf(hd.Data[0]))
+	}
+	if _, ok := retValue.(bool); !ok {
+		return fmt.Errorf("retValue can't change to bool")
+	}
+	result, ok := data.Results[c.Rule.Name]
+	switch {
+	case !ok:
+		c.InfoList = append(c.InfoList, &LogInfo{Instance: "-", Val: "not evaluated", CheckResult: "nodata"})
+		return nil
+	case result.Error != "":
+		c.InfoList = append(c.InfoList, &LogInfo{Instance: "-", Val: result.Error, CheckResult: "nodata"})
+		return nil
+	case len(result.Instances) == 0:
+		c.InfoList = append(c.InfoList, &LogInfo{Instance: "-", Val: "no matched logs", CheckResult: "OK"})
+		return nil
+	}
+	for _, inst := range result.Instances {
+		info := &LogInfo{
+			Instance: fmt.Sprintf("%s %s", inst.Component, inst.Instance),
+			Val: fmt.Sprintf("%d matched, max %d in a bucket at %s, first at %s, last at %s",
+				inst.Count, inst.MaxCount, formatLogTime(inst.MaxBucket),
+				formatLogTime(inst.FirstSeen), formatLogTime(inst.LastSeen)),
+			CheckResult: "OK",
+			Samples:     inst.Samples,
+		}
+		if inst.Abnormal {
+			info.CheckResult = c.Rule.WarnLevel
+		}
+		c.InfoList = append(c.InfoList, info)
+	}
+	return nil
+}
+
+func formatLogTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func (c *LogPrintTemplate) Print(out io.Writer) {
+	printer := tableprinter.New(out)
+	for _, info := range c.InfoList {
+		row, nums := tableprinter.StructParser.ParseRow(reflect.ValueOf(info).Elem())
+		printer.RenderRow(row, nums)
+	}
+	for _, info := range c.InfoList {
+		if len(info.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "Sample logs of %s:\n", info.Instance)
+		for _, s := range info.Samples {
+			fmt.Fprintln(out, s)
+		}
+	}
+}
+
+func (c *LogPrintTemplate) ResultAbnormal() bool {
+	for _, info := range c.InfoList {
+		if strings.ToLower(info.CheckResult) != "ok" && strings.ToLower(info.CheckResult) != "nodata" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lensesio/tableprinter"
+	"github.com/pingcap/diag/pkg/promql"
+)
+
+// operators of metric rules
+const (
+	MetricOpAbove = ">"
+	MetricOpBelow = "<"
+)
+
+// MetricRule is the expression of a metric rule, a series of the expression
+// is abnormal if its value keeps above (or below) the threshold for at least
+// the window, e.g., raftstore CPU > 0.8 for 10m
+type MetricRule struct {
+	Expr      string  `yaml:"expr" toml:"expr"`     // PromQL-like expression, see pkg/promql
+	Window    string  `yaml:"window" toml:"window"` // duration, any violating sample is abnormal if empty
+	Threshold float64 `yaml:"threshold" toml:"threshold"`
+	Operator  string  `yaml:"operator" toml:"operator"` // ">" or "<", default to ">"
+}
+
+// Validate checks the expression, window and operator of the rule
+func (m *MetricRule) Validate() error {
+	if _, err := promql.ParseExpr(m.Expr); err != nil {
+		return fmt.Errorf("invalid expr '%s': %s", m.Expr, err)
+	}
+	if _, err := m.WindowDuration(); err != nil {
+		return err
+	}
+	switch m.Operator {
+	case "", MetricOpAbove, MetricOpBelow:
+		return nil
+	default:
+		return fmt.Errorf("invalid operator '%s', available values are '%s' and '%s'", m.Operator, MetricOpAbove, MetricOpBelow)
+	}
+}
+
+// WindowDuration parses the window of the rule
+func (m *MetricRule) WindowDuration() (time.Duration, error) {
+	if m.Window == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(m.Window)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid window '%s'", m.Window)
+	}
+	return d, nil
+}
+
+// Violated returns true if the value is beyond the threshold
+func (m *MetricRule) Violated(v float64) bool {
+	if m.Operator == MetricOpBelow {
+		return v < m.Threshold
+	}
+	return v > m.Threshold
+}
+
+// MetricData is the result of metric rules evaluated on collected metrics
+type MetricData struct {
+	Results map[string]*MetricResult // by rule names
+}
+
+// MetricResult is the result of a metric rule
+type MetricResult struct {
+	Error  string // the rule is not evaluated, e.g., no metrics collected
+	Series []*MetricSeriesResult
+}
+
+// MetricSeriesResult is the result of a series of a metric rule
+type MetricSeriesResult struct {
+	Labels   string
+	Peak     float64       // max value for ">" and min value for "<"
+	Duration time.Duration // the longest time the threshold is violated continuously
+	Abnormal bool
+}
+
+func (d *MetricData) ActingName() string {
+	return "metric"
+}
+
+// Passed returns true if no series of the rule is abnormal, it is used by
+// execute rules of metric rules
+func (d *MetricData) Passed(name string) bool {
+	r, ok := d.Results[name]
+	if !ok {
+		return true
+	}
+	for _, s := range r.Series {
+		if s.Abnormal {
+			return false
+		}
+	}
+	return true
+}
+
+type MetricPrintTemplate struct {
+	Rule     *Rule
+	InfoList []*MetricInfo
+}
+
+type MetricInfo struct {
+	Series      string `header:"Series"`
+	Val         string `header:"val"`
+	CheckResult string `header:"CheckResult"`
+}
+
+func NewMetricPrintTemplate(rule *Rule) *MetricPrintTemplate {
+	return &MetricPrintTemplate{
+		Rule: rule,
+	}
+}
+
+func (c *MetricPrintTemplate) CollectResult(hd *HandleData, retValue interface{}) error {
+	if hd == nil {
+		return fmt.Errorf("handle data is nil")
+	}
+	if !hd.IsValid {
+		c.InfoList = append(c.InfoList, &MetricInfo{Series: hd.UqiTag, CheckResult: "nodata"})
+		return nil
+	}
+	data, ok := hd.Data[0].(*MetricData)
+	if !ok {
+		return fmt.Errorf("convert into metric data failed, %v", reflect.TypeOf(hd.Data[0]))
+	}
+	if _, ok := retValue.(bool); !ok {
+		return fmt.Errorf("retValue can't change to bool")
+	}
+	result, ok := data.Results[c.Rule.Name]
+	switch {
+	case !ok:
+		c.InfoList = append(c.InfoList, &MetricInfo{Series: "-", Val: "not evaluated", CheckResult: "nodata"})
+		return nil
+	case result.Error != "":
+		c.InfoList = append(c.InfoList, &MetricInfo{Series: "-", Val: result.Error, CheckResult: "nodata"})
+		return nil
+	case len(result.Series) == 0:
+		c.InfoList = append(c.InfoList, &MetricInfo{Series: "-", Val: "no series", CheckResult: "nodata"})
+		return nil
+	}
+	for _, s := range result.Series {
+		info := &MetricInfo{
+			Series:      s.Labels,
+			Val:         fmt.Sprintf("peak %g", s.Peak),
+			CheckResult: "OK",
+		}
+		if s.Duration > 0 {
+			info.Val = fmt.Sprintf("%s, beyond %g for %s", info.Val, c.Rule.Metric.Threshold, s.Duration)
+		}
+		if s.Abnormal {
+			info.CheckResult = c.Rule.WarnLevel
+		}
+		c.InfoList = append(c.InfoList, info)
+	}
+	return nil
+}
+
+func (c *MetricPrintTemplate) Print(out io.Writer) {
+	printer := tableprinter.New(out)
+	for _, info := range c.InfoList {
+		row, nums := tableprinter.StructParser.ParseRow(reflect.ValueOf(info).Elem())
+		printer.RenderRow(row, nums)
+	}
+}
+
+func (c *MetricPrintTemplate) ResultAbnormal() bool {
+	for _, info := range c.InfoList {
+		if strings.ToLower(info.CheckResult) != "ok" && strings.ToLower(info.CheckResult) != "nodata" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+// SlowQueryReport is the analysis of slow queries in the collected time
+// range, latencies are in seconds
+type SlowQueryReport struct {
+	BeginTime   string                 `json:"begin_time"`
+	EndTime     string                 `json:"end_time"`
+	Count       int64                  `json:"count"`
+	Digests     int                    `json:"digests"`      // number of distinct digests
+	TopByTotal  []*SlowQueryDigest     `json:"top_by_total"` // top digests by total latency
+	TopByAvg    []*SlowQueryDigest     `json:"top_by_avg"`
+	TopByP99    []*SlowQueryDigest     `json:"top_by_p99"`
+	Users       []*SlowQueryGroup      `json:"users"`
+	DBs         []*SlowQueryGroup      `json:"dbs"`
+	Backoffs    []*SlowQueryBackoff    `json:"backoffs"`
+	PlanChanges []*SlowQueryPlanChange `json:"plan_changes"`
+}
+
+// SlowQueryDigest is the summary of slow queries of a digest
+type SlowQueryDigest struct {
+	Digest       string  `json:"digest"`
+	SampleSQL    string  `json:"sample_sql"`
+	Count        int64   `json:"count"`
+	TotalLatency float64 `json:"total_latency"`
+	AvgLatency   float64 `json:"avg_latency"`
+	MaxLatency   float64 `json:"max_latency"`
+	P99Latency   float64 `json:"p99_latency"`
+	ProcessTime  float64 `json:"process_time"` // total time processed in TiKV
+	WaitTime     float64 `json:"wait_time"`    // total time waiting in TiKV
+	BackoffTime  float64 `json:"backoff_time"` // total time of backoff
+	CopTasks     int64   `json:"cop_tasks"`
+	CopProcMax   float64 `json:"cop_proc_max"`
+	CopWaitMax   float64 `json:"cop_wait_max"`
+	Plans        int     `json:"plans"` // number of distinct plan digests
+	FirstSeen    string  `json:"first_seen"`
+	LastSeen     string  `json:"last_seen"`
+}
+
+// SlowQueryGroup is the summary of slow queries of a user or a database
+type SlowQueryGroup struct {
+	Name         string  `json:"name"`
+	Count        int64   `json:"count"`
+	Digests      int     `json:"digests"`
+	TotalLatency float64 `json:"total_latency"`
+	AvgLatency   float64 `json:"avg_latency"`
+	MaxLatency   float64 `json:"max_latency"`
+}
+
+// SlowQueryBackoff is the summary of a type of coprocessor backoff
+type SlowQueryBackoff struct {
+	Type      string  `json:"type"`
+	Times     int64   `json:"times"`
+	TotalTime float64 `json:"total_time"`
+	Queries   int64   `json:"queries"` // number of slow queries met the backoff
+}
+
+// SlowQueryPlan is the summary of slow queries of a plan digest
+type SlowQueryPlan struct {
+	PlanDigest string  `json:"plan_digest"`
+	Count      int64   `json:"count"`
+	AvgLatency float64 `json:"avg_latency"`
+	FirstSeen  string  `json:"first_seen"`
+	LastSeen   string  `json:"last_seen"`
+}
+
+// SlowQueryPlanChange lists the plans of a digest executed with more than
+// one plan in the time range, ordered by the time first seen; it is a
+// regression if the average latency of the last plan is much higher than
+// the best of the earlier plans
+type SlowQueryPlanChange struct {
+	Digest     string           `json:"digest"`
+	SampleSQL  string           `json:"sample_sql"`
+	Plans      []*SlowQueryPlan `json:"plans"`
+	Regression bool             `json:"regression"`
+}
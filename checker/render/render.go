@@ -31,14 +31,21 @@ type ResultWrapper struct {
 	Data      *proto.SourceDataV2
 	storePath string
 	include   string
+	formats   []string
 }
 
-func NewResultWrapper(data *proto.SourceDataV2, rs map[string]*proto.Rule, sp string, inc string) *ResultWrapper {
+// NewResultWrapper creates a ResultWrapper saving reports of the formats
+// to sp, the text format is used if formats is empty
+func NewResultWrapper(data *proto.SourceDataV2, rs map[string]*proto.Rule, sp string, inc string, formats []string) *ResultWrapper {
+	if len(formats) == 0 {
+		formats = []string{FormatText}
+	}
 	return &ResultWrapper{
 		RuleSet:   rs,
 		Data:      data,
 		storePath: sp,
 		include:   inc,
+		formats:   formats,
 	}
 }
 
@@ -73,13 +80,31 @@ func (w *ResultWrapper) Output(ctx context.Context, checkresult map[string]proto
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 	// todo@toto find rule check result
 	// print OutputMetaData
-	defer func() {
-		logger.Infof("Result report and record are saved at %s", w.storePath)
-	}()
-	if err := w.OutputSummary(logger, checkresult); err != nil {
+	for _, format := range w.formats {
+		if format != FormatText {
+			continue
+		}
+		if err := w.OutputSummary(logger, checkresult); err != nil {
+			return err
+		}
+		break
+	}
+	// the detailed record is saved whatever the formats are
+	if err := w.SaveDetail(checkresult); err != nil {
 		return err
 	}
-	return w.SaveDetail(checkresult)
+	logger.Infof("Result record is saved at %s", w.storePath)
+
+	report := w.BuildReport(checkresult)
+	files, err := w.SaveReports(report, w.formats)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		logger.Infof("Result report is saved at %s", f)
+	}
+	logger.Infof("%d rules were executed, %d of them were abnormal", report.Total, report.Abnormal)
+	return nil
 }
 
 func (w *ResultWrapper) OutputSummary(logger *logprinter.Logger, checkresult map[string]proto.PrintTemplate) error {
@@ -107,7 +132,7 @@ func (w *ResultWrapper) OutputSummary(logger *logprinter.Logger, checkresult map
 	writer.WriteString(logger, fmt.Sprint("- Sampling Date: ", w.Data.ClusterInfo.BeginTime))
 	writer.WriteString(logger, fmt.Sprint("- Sample Content:: ", w.Data.ClusterInfo.Collectors))
 
-	total, abnormalTotalCnt, abnormalConfigCnt, abnormalDefaultConfigCnt := 0, 0, 0, 0
+	total, abnormalTotalCnt, abnormalConfigCnt, abnormalDefaultConfigCnt, abnormalMetricCnt, abnormalLogCnt, abnormalConsistencyCnt := 0, 0, 0, 0, 0, 0, 0
 	typeRules, keys := w.GroupByType()
 	for _, ruleType := range keys {
 		rules := typeRules[ruleType]
@@ -126,6 +151,12 @@ func (w *ResultWrapper) OutputSummary(logger *logprinter.Logger, checkresult map
 				abnormalConfigCnt++
 			} else if ruleType == proto.DefaultConfigType {
 				abnormalDefaultConfigCnt++
+			} else if ruleType == proto.MetricType {
+				abnormalMetricCnt++
+			} else if ruleType == proto.LogType {
+				abnormalLogCnt++
+			} else if ruleType == proto.ConsistencyType {
+				abnormalConsistencyCnt++
 			}
 		}
 	}
@@ -145,6 +176,18 @@ func (w *ResultWrapper) OutputSummary(logger *logprinter.Logger, checkresult map
 			writer.WriteString(logger, "\n### Default Configuration Summary")
 			writer.WriteString(logger, fmt.Sprintf("The default configuration rules can find out which configurations are inconsistent with the default values.\nIf configurations were modified inadvertently, you can change they back to the default value based on this feedback.\nThere were **%v** abnormal results.",
 				abnormalDefaultConfigCnt))
+		} else if ruleType == proto.MetricType {
+			writer.WriteString(logger, "\n### Metric Summary")
+			writer.WriteString(logger, fmt.Sprintf("The metric rules check the runtime behavior of the cluster with the collected metrics.\nA series is abnormal if its value keeps beyond the threshold of the rule for the window.\nThere were **%v** abnormal results.",
+				abnormalMetricCnt))
+		} else if ruleType == proto.LogType {
+			writer.WriteString(logger, "\n### Log Summary")
+			writer.WriteString(logger, fmt.Sprintf("The log rules search the collected logs for known error patterns.\nAn instance is abnormal if its matched logs in a time bucket reach the threshold of the rule.\nThere were **%v** abnormal results.",
+				abnormalLogCnt))
+		} else if ruleType == proto.ConsistencyType {
+			writer.WriteString(logger, "\n### Configuration Consistency Summary")
+			writer.WriteString(logger, fmt.Sprintf("The consistency rules compare configurations across all instances of a component.\nA configuration is abnormal if its values differ between instances, the instances are grouped by values.\nThere were **%v** abnormal results.",
+				abnormalConsistencyCnt))
 		}
 		for _, rule := range rules {
 			printer, ok := checkresult[rule.Name]
@@ -173,6 +216,9 @@ func (w *ResultWrapper) OutputSummary(logger *logprinter.Logger, checkresult map
 		}
 	}
 
+	if w.Data.DashboardData != nil && w.Data.DashboardData.SlowQuery != nil {
+		return w.OutputSlowQuery(logger, writer, 4)
+	}
 	return nil
 }
 
@@ -200,6 +246,12 @@ func (w *ResultWrapper) SaveDetail(checkresult map[string]proto.PrintTemplate) e
 			writer.SaveString("\n### SQL Performance")
 		} else if ruleType == proto.DefaultConfigType {
 			writer.SaveString("\n### Default Configuration")
+		} else if ruleType == proto.MetricType {
+			writer.SaveString("\n### Metric")
+		} else if ruleType == proto.LogType {
+			writer.SaveString("\n### Log")
+		} else if ruleType == proto.ConsistencyType {
+			writer.SaveString("\n### Configuration Consistency")
 		}
 		for _, rule := range rules {
 			printer, ok := checkresult[rule.Name]
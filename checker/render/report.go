@@ -0,0 +1,571 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/checker/proto"
+)
+
+// formats of check reports
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+	FormatHTML  = "html"
+	FormatSARIF = "sarif"
+)
+
+// result values of a node other than the warn level of the rule
+const (
+	resultOK     = "OK"
+	resultNoData = "nodata"
+)
+
+var reportFiles = map[string]string{
+	FormatJSON:  "check-report.json",
+	FormatJUnit: "check-report.junit.xml",
+	FormatHTML:  "check-report.html",
+	FormatSARIF: "check-report.sarif",
+}
+
+// CheckFormats returns an error if any of the formats is not supported
+func CheckFormats(formats []string) error {
+	for _, f := range formats {
+		if _, ok := reportFiles[f]; !ok && f != FormatText {
+			return fmt.Errorf("unsupported report format '%s', available values are [%s, %s, %s, %s, %s]",
+				f, FormatText, FormatJSON, FormatJUnit, FormatHTML, FormatSARIF)
+		}
+	}
+	return nil
+}
+
+// Report is the structured result of a check
+type Report struct {
+	ClusterName    string        `json:"cluster_name"`
+	ClusterID      string        `json:"cluster_id"`
+	ClusterVersion string        `json:"cluster_version"`
+	Session        string        `json:"session"`
+	BeginTime      string        `json:"begin_time"`
+	Collectors     []string      `json:"collectors"`
+	Total          int           `json:"total"`
+	Abnormal       int           `json:"abnormal"`
+	Rules          []*RuleReport `json:"rules"`
+
+	SlowQuery *proto.SlowQueryReport `json:"slow_query,omitempty"`
+}
+
+// RuleReport is the result of a rule
+type RuleReport struct {
+	ID          int64         `json:"id"`
+	Name        string        `json:"name"`
+	CheckType   string        `json:"check_type"`
+	WarnLevel   string        `json:"warn_level"`
+	Description string        `json:"description,omitempty"`
+	Variation   string        `json:"variation,omitempty"`
+	Expected    string        `json:"expected,omitempty"`
+	Reference   string        `json:"reference,omitempty"`
+	Suggestion  string        `json:"suggestion,omitempty"`
+	Abnormal    bool          `json:"abnormal"`
+	Results     []*NodeResult `json:"results"`
+}
+
+// NodeResult is the result of a rule on a node, or on a set of nodes for
+// rules checking more than one component
+type NodeResult struct {
+	Node   string `json:"node"`
+	Actual string `json:"actual"`
+	Result string `json:"result"` // OK, nodata or the warn level of the rule
+}
+
+// abnormal returns true if the rule is not passed on the node
+func (r *NodeResult) abnormal() bool {
+	return !strings.EqualFold(r.Result, resultOK) && !strings.EqualFold(r.Result, resultNoData)
+}
+
+// BuildReport converts the check results to a report, rules are ordered by
+// check type and id, and results of a rule are ordered by node, so reports
+// of different collections could be compared directly
+func (w *ResultWrapper) BuildReport(checkresult map[string]proto.PrintTemplate) *Report {
+	report := &Report{
+		ClusterVersion: w.Data.TidbVersion,
+		Rules:          make([]*RuleReport, 0),
+	}
+	if info := w.Data.ClusterInfo; info != nil {
+		report.ClusterName = info.ClusterName
+		report.ClusterID = info.ClusterID
+		report.Session = info.Session
+		report.BeginTime = info.BeginTime
+		report.Collectors = info.Collectors
+	}
+	if w.Data.DashboardData != nil {
+		report.SlowQuery = w.Data.DashboardData.SlowQuery
+	}
+
+	typeRules, keys := w.GroupByType()
+	for _, ruleType := range keys {
+		for _, rule := range typeRules[ruleType] {
+			printer, ok := checkresult[rule.Name]
+			if !ok {
+				continue
+			}
+			rr := &RuleReport{
+				ID:          rule.ID,
+				Name:        rule.Name,
+				CheckType:   rule.CheckType,
+				WarnLevel:   rule.WarnLevel,
+				Description: rule.Description,
+				Variation:   rule.Variation,
+				Expected:    string(rule.Default),
+				Reference:   rule.ExpectRes,
+				Suggestion:  rule.Suggestion,
+				Abnormal:    printer.ResultAbnormal(),
+				Results:     nodeResults(rule, printer),
+			}
+			report.Total++
+			if rr.Abnormal {
+				report.Abnormal++
+			}
+			report.Rules = append(report.Rules, rr)
+		}
+	}
+	return report
+}
+
+func nodeResults(rule *proto.Rule, printer proto.PrintTemplate) []*NodeResult {
+	results := make([]*NodeResult, 0)
+	switch p := printer.(type) {
+	case *proto.ConfPrintTemplate:
+		for _, info := range p.InfoList {
+			if info == nil {
+				continue
+			}
+			results = append(results, &NodeResult{
+				Node:   info.UniTag,
+				Actual: info.Val,
+				Result: info.CheckResult,
+			})
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Node < results[j].Node
+		})
+	case *proto.MetricPrintTemplate:
+		for _, info := range p.InfoList {
+			results = append(results, &NodeResult{
+				Node:   info.Series,
+				Actual: info.Val,
+				Result: info.CheckResult,
+			})
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Node < results[j].Node
+		})
+	case *proto.LogPrintTemplate:
+		for _, info := range p.InfoList {
+			results = append(results, &NodeResult{
+				Node:   info.Instance,
+				Actual: strings.Join(append([]string{info.Val}, info.Samples...), "\n"),
+				Result: info.CheckResult,
+			})
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Node < results[j].Node
+		})
+	case *proto.ConsistencyPrintTemplate:
+		for _, info := range p.InfoList {
+			results = append(results, &NodeResult{
+				Node:   info.Key,
+				Actual: info.Diff(),
+				Result: info.CheckResult,
+			})
+		}
+	case *proto.SQLPerformancePrintTemplate:
+		result := resultOK
+		if p.ResultAbnormal() {
+			result = rule.WarnLevel
+		}
+		results = append(results, &NodeResult{
+			Node:   rule.NameStruct,
+			Actual: p.InfoList.NumDigest,
+			Result: result,
+		})
+	}
+	return results
+}
+
+// SaveReports writes the report to files of the given formats in the
+// report dir, the text format is handled by Output and skipped here
+func (w *ResultWrapper) SaveReports(report *Report, formats []string) ([]string, error) {
+	files := make([]string, 0, len(formats))
+	for _, format := range formats {
+		name, ok := reportFiles[format]
+		if !ok {
+			continue
+		}
+		fp := filepath.Join(w.storePath, name)
+		if err := saveReport(fp, format, report); err != nil {
+			return nil, err
+		}
+		files = append(files, fp)
+	}
+	return files, nil
+}
+
+func saveReport(fp, format string, report *Report) error {
+	if err := os.MkdirAll(filepath.Dir(fp), 0o777); err != nil {
+		return err
+	}
+	f, err := os.Create(fp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case FormatJSON:
+		err = WriteJSONReport(f, report)
+	case FormatJUnit:
+		err = WriteJUnitReport(f, report)
+	case FormatHTML:
+		err = WriteHTMLReport(f, report)
+	case FormatSARIF:
+		err = WriteSARIFReport(f, report)
+	default:
+		err = fmt.Errorf("unsupported report format '%s'", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s report %s: %s", format, fp, err)
+	}
+	return f.Close()
+}
+
+// WriteJSONReport writes the report as JSON
+func WriteJSONReport(out io.Writer, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnitReport writes the report as JUnit XML, each check type is a test
+// suite and the result of a rule on each node is a test case, abnormal
+// results are failures typed with the warn level of the rule
+func WriteJUnitReport(out io.Writer, report *Report) error {
+	suites := &junitTestSuites{Name: fmt.Sprintf("diag check %s", report.ClusterName)}
+	suiteOf := make(map[string]*junitTestSuite)
+	for _, rule := range report.Rules {
+		suite, ok := suiteOf[rule.CheckType]
+		if !ok {
+			suite = &junitTestSuite{Name: rule.CheckType}
+			suiteOf[rule.CheckType] = suite
+			suites.Suites = append(suites.Suites, suite)
+		}
+		for _, res := range rule.Results {
+			tc := &junitTestCase{
+				Name:      res.Node,
+				ClassName: fmt.Sprintf("%d.%s", rule.ID, rule.Name),
+			}
+			switch {
+			case strings.EqualFold(res.Result, resultNoData):
+				tc.Skipped = &junitSkipped{Message: "no data collected"}
+				suite.Skipped++
+			case res.abnormal():
+				text := []string{fmt.Sprintf("actual: %s", res.Actual)}
+				if rule.Expected != "" {
+					text = append(text, fmt.Sprintf("expected: %s", rule.Expected))
+				}
+				if rule.Reference != "" {
+					text = append(text, fmt.Sprintf("reference: %s", rule.Reference))
+				}
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("rule %d (%s) is not passed", rule.ID, rule.Name),
+					Type:    res.Result,
+					Text:    strings.Join(text, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suites.Tests += len(rule.Results)
+	}
+	for _, suite := range suites.Suites {
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string       `json:"name"`
+	Rules []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     *sarifMessage      `json:"shortDescription,omitempty"`
+	Help                 *sarifMessage      `json:"help,omitempty"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// sarifLevel converts the warn level of a rule to a SARIF level
+func sarifLevel(warnLevel string) string {
+	switch strings.ToLower(warnLevel) {
+	case "info", "notice":
+		return "note"
+	case "error", "critical", "fatal":
+		return "error"
+	default:
+		return "warning"
+	}
+}
+
+// WriteSARIFReport writes the report as a SARIF 2.1.0 log, every executed
+// rule is a rule of the tool and each abnormal result of a rule on a node
+// is a result located at the node
+func WriteSARIFReport(out io.Writer, report *Report) error {
+	run := &sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "diag check", Rules: make([]*sarifRule, 0, len(report.Rules))}},
+		Results: make([]*sarifResult, 0),
+	}
+	for i, rule := range report.Rules {
+		sr := &sarifRule{
+			ID:                   fmt.Sprint(rule.ID),
+			Name:                 rule.Name,
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.WarnLevel)},
+		}
+		if rule.Description != "" {
+			sr.ShortDescription = &sarifMessage{Text: rule.Description}
+		}
+		if rule.Suggestion != "" {
+			sr.Help = &sarifMessage{Text: rule.Suggestion}
+		}
+		if strings.HasPrefix(rule.Reference, "http") {
+			sr.HelpURI = rule.Reference
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+
+		for _, res := range rule.Results {
+			if !res.abnormal() {
+				continue
+			}
+			text := fmt.Sprintf("rule %d (%s) is not passed on %s, actual: %s", rule.ID, rule.Name, res.Node, res.Actual)
+			if rule.Expected != "" {
+				text += fmt.Sprintf(", expected: %s", rule.Expected)
+			}
+			run.Results = append(run.Results, &sarifResult{
+				RuleID:    sr.ID,
+				RuleIndex: i,
+				Level:     sarifLevel(res.Result),
+				Message:   sarifMessage{Text: text},
+				Locations: []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: res.Node}}}},
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(&sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []*sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"abnormal": func(r *NodeResult) bool { return r.abnormal() },
+	"seconds":  formatSeconds,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Check Result Report - {{.ClusterName}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+tr.abnormal td { background: #fde2e2; }
+td.value { font-family: monospace; word-break: break-all; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Check Result Report</h1>
+<ul>
+<li>Cluster Name: {{.ClusterName}}</li>
+<li>Cluster ID: {{.ClusterID}}</li>
+<li>Cluster Version: {{.ClusterVersion}}</li>
+<li>Sample ID: {{.Session}}</li>
+<li>Sampling Date: {{.BeginTime}}</li>
+</ul>
+<p>In this inspection, {{.Total}} rules were executed, the results of <b>{{.Abnormal}}</b> rules were abnormal.</p>
+<table>
+<tr><th>Rule ID</th><th>Name</th><th>Type</th><th>Warn Level</th><th>Node</th><th>Actual</th><th>Expected</th><th>Result</th></tr>
+{{- range $rule := .Rules}}{{range .Results}}
+<tr{{if abnormal .}} class="abnormal"{{end}}><td>{{$rule.ID}}</td><td>{{if $rule.Reference}}<a href="{{$rule.Reference}}">{{$rule.Name}}</a>{{else}}{{$rule.Name}}{{end}}</td><td>{{$rule.CheckType}}</td><td>{{$rule.WarnLevel}}</td><td>{{.Node}}</td><td class="value">{{.Actual}}</td><td class="value">{{$rule.Expected}}</td><td>{{.Result}}</td></tr>
+{{- end}}{{end}}
+</table>
+{{- with .SlowQuery}}
+<h2>Slow Query Analysis</h2>
+<p>From {{.BeginTime}} to {{.EndTime}}, there were <b>{{.Count}}</b> slow queries of {{.Digests}} digests.</p>
+<h3>Top SQL by Total Latency</h3>
+{{template "digests" .TopByTotal}}
+<h3>Top SQL by Average Latency</h3>
+{{template "digests" .TopByAvg}}
+<h3>Top SQL by P99 Latency</h3>
+{{template "digests" .TopByP99}}
+<h3>Slow Queries by User</h3>
+{{template "groups" .Users}}
+<h3>Slow Queries by Database</h3>
+{{template "groups" .DBs}}
+<h3>Coprocessor Backoffs</h3>
+<table>
+<tr><th>Type</th><th>Times</th><th>Total (s)</th><th>Queries</th></tr>
+{{- range .Backoffs}}
+<tr><td>{{.Type}}</td><td>{{.Times}}</td><td>{{seconds .TotalTime}}</td><td>{{.Queries}}</td></tr>
+{{- end}}
+</table>
+<h3>Plan Changes</h3>
+<table>
+<tr><th>Digest</th><th>Plan Digest</th><th>Count</th><th>Avg (s)</th><th>First Seen</th><th>Last Seen</th></tr>
+{{- range $change := .PlanChanges}}{{range .Plans}}
+<tr{{if $change.Regression}} class="abnormal"{{end}}><td class="value">{{$change.Digest}}</td><td class="value">{{.PlanDigest}}</td><td>{{.Count}}</td><td>{{seconds .AvgLatency}}</td><td>{{.FirstSeen}}</td><td>{{.LastSeen}}</td></tr>
+{{- end}}{{end}}
+</table>
+{{- end}}
+</body>
+</html>
+{{- define "digests"}}
+<table>
+<tr><th>Digest</th><th>Count</th><th>Total (s)</th><th>Avg (s)</th><th>P99 (s)</th><th>Process (s)</th><th>Wait (s)</th><th>Backoff (s)</th><th>Cop Tasks</th><th>Plans</th><th>Sample SQL</th></tr>
+{{- range .}}
+<tr><td class="value">{{.Digest}}</td><td>{{.Count}}</td><td>{{seconds .TotalLatency}}</td><td>{{seconds .AvgLatency}}</td><td>{{seconds .P99Latency}}</td><td>{{seconds .ProcessTime}}</td><td>{{seconds .WaitTime}}</td><td>{{seconds .BackoffTime}}</td><td>{{.CopTasks}}</td><td>{{.Plans}}</td><td class="value">{{.SampleSQL}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- define "groups"}}
+<table>
+<tr><th>Name</th><th>Count</th><th>Digests</th><th>Total (s)</th><th>Avg (s)</th><th>Max (s)</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{.Digests}}</td><td>{{seconds .TotalLatency}}</td><td>{{seconds .AvgLatency}}</td><td>{{seconds .MaxLatency}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+`))
+
+// WriteHTMLReport writes the report as a single HTML page
+func WriteHTMLReport(out io.Writer, report *Report) error {
+	return htmlReportTemplate.Execute(out, report)
+}
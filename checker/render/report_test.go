@@ -0,0 +1,195 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/diag/collector"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWrapper(dir string) (*ResultWrapper, map[string]proto.PrintTemplate) {
+	rules := map[string]*proto.Rule{
+		"tikv-log-level": {
+			ID: 7, Name: "tikv-log-level", CheckType: proto.ConfigType, WarnLevel: "info",
+			Variation: "TikvConfig.log-level", ExpectRes: "https://example.com/7",
+		},
+		"PdConfig.lease": {
+			ID: 1009, Name: "PdConfig.lease", CheckType: proto.DefaultConfigType, WarnLevel: "warning",
+			Variation: "PdConfig.lease", Default: "3",
+		},
+		"not-executed": {ID: 1, Name: "not-executed", CheckType: proto.ConfigType},
+	}
+	results := map[string]proto.PrintTemplate{
+		"tikv-log-level": &proto.ConfPrintTemplate{
+			Rule: rules["tikv-log-level"],
+			InfoList: []*proto.ConfInfo{
+				{UniTag: "TikvConfig_10.0.0.2:20160", Val: "TikvConfig.log-level:info", CheckResult: "OK"},
+				{UniTag: "TikvConfig_10.0.0.1:20160", Val: "", CheckResult: "nodata"},
+			},
+		},
+		"PdConfig.lease": &proto.ConfPrintTemplate{
+			Rule: rules["PdConfig.lease"],
+			InfoList: []*proto.ConfInfo{
+				{UniTag: "PdConfig_10.0.0.1:2379", Val: "PdConfig.lease:5", CheckResult: "warning"},
+			},
+		},
+	}
+	data := &proto.SourceDataV2{
+		ClusterInfo: &collector.ClusterJSON{ClusterName: "test-cluster", ClusterID: "42"},
+		TidbVersion: "v6.5.0",
+	}
+	return NewResultWrapper(data, rules, dir, "config", []string{FormatJSON, FormatJUnit, FormatHTML, FormatSARIF}), results
+}
+
+func TestBuildReport(t *testing.T) {
+	assert := require.New(t)
+
+	w, results := newTestWrapper(t.TempDir())
+	report := w.BuildReport(results)
+	assert.Equal("test-cluster", report.ClusterName)
+	assert.Equal(2, report.Total)
+	assert.Equal(1, report.Abnormal)
+	assert.Len(report.Rules, 2)
+
+	// ordered by check type, and then nodes
+	assert.EqualValues(7, report.Rules[0].ID)
+	assert.False(report.Rules[0].Abnormal)
+	assert.Equal([]*NodeResult{
+		{Node: "TikvConfig_10.0.0.1:20160", Actual: "", Result: "nodata"},
+		{Node: "TikvConfig_10.0.0.2:20160", Actual: "TikvConfig.log-level:info", Result: "OK"},
+	}, report.Rules[0].Results)
+	assert.EqualValues(1009, report.Rules[1].ID)
+	assert.True(report.Rules[1].Abnormal)
+	assert.Equal("3", report.Rules[1].Expected)
+
+	assert.Nil(CheckFormats([]string{FormatText, FormatJUnit}))
+	assert.Nil(CheckFormats([]string{FormatSARIF}))
+	assert.NotNil(CheckFormats([]string{"pdf"}))
+}
+
+func TestSaveReports(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	w, results := newTestWrapper(dir)
+	report := w.BuildReport(results)
+	files, err := w.SaveReports(report, append([]string{FormatText}, w.formats...))
+	assert.Nil(err)
+	assert.Equal([]string{
+		filepath.Join(dir, "check-report.json"),
+		filepath.Join(dir, "check-report.junit.xml"),
+		filepath.Join(dir, "check-report.html"),
+		filepath.Join(dir, "check-report.sarif"),
+	}, files)
+
+	data, err := os.ReadFile(files[0])
+	assert.Nil(err)
+	decoded := &Report{}
+	assert.Nil(json.Unmarshal(data, decoded))
+	assert.Equal(report, decoded)
+
+	data, err = os.ReadFile(files[1])
+	assert.Nil(err)
+	suites := &junitTestSuites{}
+	assert.Nil(xml.Unmarshal(data, suites))
+	assert.Equal(3, suites.Tests)
+	assert.Equal(1, suites.Failures)
+	assert.Equal(1, suites.Skipped)
+	assert.Len(suites.Suites, 2)
+	failure := suites.Suites[1].Cases[0].Failure
+	assert.NotNil(failure)
+	assert.Equal("warning", failure.Type)
+	assert.Contains(failure.Text, "expected: 3")
+
+	data, err = os.ReadFile(files[2])
+	assert.Nil(err)
+	assert.True(bytes.Contains(data, []byte(`<tr class="abnormal"><td>1009</td>`)))
+	assert.True(bytes.Contains(data, []byte(`<a href="https://example.com/7">tikv-log-level</a>`)))
+
+	data, err = os.ReadFile(files[3])
+	assert.Nil(err)
+	sarif := &sarifLog{}
+	assert.Nil(json.Unmarshal(data, sarif))
+	assert.Equal("2.1.0", sarif.Version)
+	assert.Len(sarif.Runs, 1)
+	run := sarif.Runs[0]
+	assert.Len(run.Tool.Driver.Rules, 2)
+	assert.Equal("https://example.com/7", run.Tool.Driver.Rules[0].HelpURI)
+	assert.Equal("note", run.Tool.Driver.Rules[0].DefaultConfiguration.Level)
+	assert.Len(run.Results, 1)
+	assert.Equal("1009", run.Results[0].RuleID)
+	assert.Equal(1, run.Results[0].RuleIndex)
+	assert.Equal("warning", run.Results[0].Level)
+	assert.Equal("PdConfig_10.0.0.1:2379", run.Results[0].Locations[0].LogicalLocations[0].FullyQualifiedName)
+	assert.Contains(run.Results[0].Message.Text, "expected: 3")
+}
+
+func TestOutputDetail(t *testing.T) {
+	assert := require.New(t)
+
+	// the detailed record is saved even if text is not one of the formats
+	dir := t.TempDir()
+	w, results := newTestWrapper(dir)
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, logprinter.NewLogger(""))
+	assert.Nil(w.Output(ctx, results))
+	_, err := os.Stat(filepath.Join(dir, "detailed-check-record.txt"))
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(dir, "check-report.txt"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestSlowQueryReport(t *testing.T) {
+	assert := require.New(t)
+
+	w, results := newTestWrapper(t.TempDir())
+	w.Data.DashboardData = &proto.DashboardData{
+		SlowQuery: &proto.SlowQueryReport{
+			Count:   3,
+			Digests: 1,
+			TopByTotal: []*proto.SlowQueryDigest{
+				{Digest: "abcdef0123456789abcdef", Count: 3, TotalLatency: 4.5, SampleSQL: "select 1"},
+			},
+			PlanChanges: []*proto.SlowQueryPlanChange{{
+				Digest:     "abcdef0123456789abcdef",
+				Plans:      []*proto.SlowQueryPlan{{PlanDigest: "p1"}, {PlanDigest: "p2"}},
+				Regression: true,
+			}},
+		},
+	}
+	report := w.BuildReport(results)
+	assert.Equal(w.Data.DashboardData.SlowQuery, report.SlowQuery)
+
+	out := &bytes.Buffer{}
+	assert.Nil(WriteHTMLReport(out, report))
+	assert.Contains(out.String(), "<h2>Slow Query Analysis</h2>")
+	assert.Contains(out.String(), "<td>4.500</td>")
+	assert.Contains(out.String(), `<tr class="abnormal"><td class="value">abcdef0123456789abcdef</td><td class="value">p2</td>`)
+
+	out.Reset()
+	renderSlowDigests(out, report.SlowQuery.TopByTotal)
+	assert.Contains(out.String(), "abcdef0123456789...")
+	out.Reset()
+	renderSlowBackoffs(out, nil)
+	assert.Equal("None\n", out.String())
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/lensesio/tableprinter"
+	"github.com/pingcap/diag/checker/proto"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+)
+
+// max length of digests and SQL shown in the text report, the full values are
+// in the CSV files
+const (
+	textDigestLength = 16
+	textSQLLength    = 64
+)
+
+func shorten(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func formatSeconds(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+func renderTable(out io.Writer, headers []string, rows [][]string) {
+	if len(rows) < 1 {
+		fmt.Fprintln(out, "None")
+		return
+	}
+	tableprinter.Render(out, headers, rows, nil, false)
+}
+
+func renderSlowDigests(out io.Writer, digests []*proto.SlowQueryDigest) {
+	rows := make([][]string, 0, len(digests))
+	for _, d := range digests {
+		rows = append(rows, []string{
+			shorten(d.Digest, textDigestLength), strconv.FormatInt(d.Count, 10),
+			formatSeconds(d.TotalLatency), formatSeconds(d.AvgLatency), formatSeconds(d.P99Latency),
+			formatSeconds(d.ProcessTime), formatSeconds(d.WaitTime), formatSeconds(d.BackoffTime),
+			strconv.FormatInt(d.CopTasks, 10), strconv.Itoa(d.Plans), shorten(d.SampleSQL, textSQLLength),
+		})
+	}
+	renderTable(out, []string{"Digest", "Count", "Total(s)", "Avg(s)", "P99(s)",
+		"Process(s)", "Wait(s)", "Backoff(s)", "Cop Tasks", "Plans", "Sample SQL"}, rows)
+}
+
+func renderSlowGroups(out io.Writer, name string, groups []*proto.SlowQueryGroup) {
+	rows := make([][]string, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, []string{
+			g.Name, strconv.FormatInt(g.Count, 10), strconv.Itoa(g.Digests),
+			formatSeconds(g.TotalLatency), formatSeconds(g.AvgLatency), formatSeconds(g.MaxLatency),
+		})
+	}
+	renderTable(out, []string{name, "Count", "Digests", "Total(s)", "Avg(s)", "Max(s)"}, rows)
+}
+
+func renderSlowBackoffs(out io.Writer, backoffs []*proto.SlowQueryBackoff) {
+	rows := make([][]string, 0, len(backoffs))
+	for _, b := range backoffs {
+		rows = append(rows, []string{
+			b.Type, strconv.FormatInt(b.Times, 10), formatSeconds(b.TotalTime), strconv.FormatInt(b.Queries, 10),
+		})
+	}
+	renderTable(out, []string{"Type", "Times", "Total(s)", "Queries"}, rows)
+}
+
+func renderSlowPlanChanges(out io.Writer, changes []*proto.SlowQueryPlanChange) {
+	rows := make([][]string, 0, len(changes))
+	for _, c := range changes {
+		for _, p := range c.Plans {
+			rows = append(rows, []string{
+				shorten(c.Digest, textDigestLength), shorten(p.PlanDigest, textDigestLength),
+				strconv.FormatInt(p.Count, 10), formatSeconds(p.AvgLatency), p.FirstSeen, p.LastSeen,
+				strconv.FormatBool(c.Regression),
+			})
+		}
+	}
+	renderTable(out, []string{"Digest", "Plan Digest", "Count", "Avg(s)", "First Seen", "Last Seen", "Regression"}, rows)
+}
+
+// OutputSlowQuery prints the slow query analysis as a section of the text
+// report
+func (w *ResultWrapper) OutputSlowQuery(logger *logprinter.Logger, writer *CheckerWriter, section int) error {
+	report := w.Data.DashboardData.SlowQuery
+	writer.WriteString(logger, fmt.Sprintf("\n## %d. Slow Query Analysis", section))
+	writer.WriteString(logger, fmt.Sprintf("From %s to %s, there were **%d** slow queries of %d digests.\nThe full results are saved as slow_query_*.csv.",
+		report.BeginTime, report.EndTime, report.Count, report.Digests))
+
+	tables := []struct {
+		title  string
+		render func(io.Writer)
+	}{
+		{"Top SQL by Total Latency", func(out io.Writer) { renderSlowDigests(out, report.TopByTotal) }},
+		{"Top SQL by Average Latency", func(out io.Writer) { renderSlowDigests(out, report.TopByAvg) }},
+		{"Top SQL by P99 Latency", func(out io.Writer) { renderSlowDigests(out, report.TopByP99) }},
+		{"Slow Queries by User", func(out io.Writer) { renderSlowGroups(out, "User", report.Users) }},
+		{"Slow Queries by Database", func(out io.Writer) { renderSlowGroups(out, "DB", report.DBs) }},
+		{"Coprocessor Backoffs", func(out io.Writer) { renderSlowBackoffs(out, report.Backoffs) }},
+		{"Plan Changes", func(out io.Writer) { renderSlowPlanChanges(out, report.PlanChanges) }},
+	}
+	for _, t := range tables {
+		writer.WriteString(logger, fmt.Sprint("\n### ", t.title))
+		loggerWrapper := writer.WrapLogger(logger)
+		t.render(loggerWrapper)
+		if err := loggerWrapper.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
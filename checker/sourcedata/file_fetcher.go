@@ -19,6 +19,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/pingcap/diag/checker/proto"
 	"github.com/pingcap/diag/collector"
 	"github.com/pingcap/diag/pkg/models"
+	"github.com/pingcap/diag/pkg/utils"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
@@ -45,6 +47,8 @@ const (
 	ConfigFlag CheckFlag = 1 << iota // rules summarized from on-call issues.
 	PerformanceFlag
 	DefaultConfigFlag // rules check default value.
+	MetricFlag        // rules check collected metrics.
+	LogFlag           // rules check collected logs.
 )
 
 type CheckFlag int
@@ -61,6 +65,14 @@ func (cf CheckFlag) checkDefaultConfig() bool {
 	return cf&DefaultConfigFlag > 0
 }
 
+func (cf CheckFlag) checkMetric() bool {
+	return cf&MetricFlag > 0
+}
+
+func (cf CheckFlag) checkLog() bool {
+	return cf&LogFlag > 0
+}
+
 // FileFetcher load all needed data from file
 type FileFetcher struct {
 	dataDirPath string // dataDirPath point to a folder
@@ -129,8 +141,12 @@ func (f *FileFetcher) FetchData(rules *config.RuleSpec) (*proto.SourceDataV2, pr
 			return f.checkFlag.checkDefaultConfig(), nil
 		case proto.PerformanceType:
 			return f.checkFlag.checkPerformance(), nil
-		case proto.ConfigType:
+		case proto.ConfigType, proto.ConsistencyType:
 			return f.checkFlag.checkConfig(), nil
+		case proto.MetricType:
+			return f.checkFlag.checkMetric(), nil
+		case proto.LogType:
+			return f.checkFlag.checkLog(), nil
 		}
 		return false, nil
 	}
@@ -176,6 +192,14 @@ func (f *FileFetcher) FetchData(rules *config.RuleSpec) (*proto.SourceDataV2, pr
 			return nil, nil, err
 		}
 	}
+	// evaluate metric rules on collected metrics
+	if f.checkFlag.checkMetric() {
+		f.loadMetrics(sourceData, rSet)
+	}
+	// evaluate log rules on collected logs
+	if f.checkFlag.checkLog() {
+		f.loadLogs(sourceData, rSet)
+	}
 
 	return sourceData, rSet, nil
 }
@@ -268,7 +292,17 @@ func (f *FileFetcher) loadRealTimeConfig(_ context.Context, sourceData *proto.So
 // todo sourceData will be updated
 func (f *FileFetcher) loadSlowLog(ctx context.Context, sourceData *proto.SourceDataV2) (err error) {
 	header := []string{"Time", "Digest", "Plan_digest", "Process_time", "Process_keys", "Rocksdb_delete_skipped_count", "Total_keys"}
+	for _, col := range slowQueryAnalysisColumns {
+		if !slices.Contains(header, col) {
+			header = append(header, col)
+		}
+	}
 	idxLookUp := NewIdxLookup(header)
+	beginTime, endTime := f.collectedTimeRange()
+	slowQueryAcc, err := NewSlowQueryAnalysisAccumulator(idxLookUp, time.Local)
+	if err != nil {
+		return err
+	}
 	avgProcessTimePlanAcc, err := NewAvgProcessTimePlanAccumulator(idxLookUp)
 	if err != nil {
 		return err
@@ -311,8 +345,7 @@ func (f *FileFetcher) loadSlowLog(ctx context.Context, sourceData *proto.SourceD
 		if deployDir, ok := spec.Attributes()["deploy_dir"]; ok {
 			slowLogPath = path.Join(f.dataDirPath, spec.Host(), deployDir.(string), "log", "tidb_slow_query.log")
 		}
-		// todo
-		retriever, err := NewSlowQueryRetriever(5, time.Local, header, slowLogPath, WithTimeRanges(time.Now().AddDate(0, 0, -7), time.Now()))
+		retriever, err := NewSlowQueryRetriever(5, time.Local, header, slowLogPath, WithTimeRanges(beginTime, endTime))
 		if err != nil {
 			return err
 		}
@@ -339,6 +372,9 @@ func (f *FileFetcher) loadSlowLog(ctx context.Context, sourceData *proto.SourceD
 				if err := skipDeletedCntPlanAcc.feed(row); err != nil {
 					log.Warn("feed row to accumulator failed", zap.Error(err))
 				}
+				if err := slowQueryAcc.feed(row); err != nil {
+					log.Warn("feed row to accumulator failed", zap.Error(err))
+				}
 			}
 		}
 	}
@@ -366,10 +402,48 @@ func (f *FileFetcher) loadSlowLog(ctx context.Context, sourceData *proto.SourceD
 		}
 		sourceData.DashboardData.TombStoneStatistics.Count = len(digestPair)
 	}
+	{
+		report, digests := slowQueryAcc.build()
+		report.BeginTime = beginTime.Format(time.RFC3339)
+		report.EndTime = endTime.Format(time.RFC3339)
+		if len(f.outDirPath) > 0 {
+			if err := saveSlowQueryReport(f.outDirPath, report, digests); err != nil {
+				return err
+			}
+		}
+		sourceData.DashboardData.SlowQuery = report
+	}
 	log.Debug("read all slow log", zap.Int64("row cnt", cnt))
 	return nil
 }
 
+// collectedTimeRange returns the time range of the collection, the last 7
+// days to the end time is used if the begin time is unknown, and the end
+// time is now if it is unknown
+func (f *FileFetcher) collectedTimeRange() (time.Time, time.Time) {
+	endTime := time.Now()
+	beginTime := time.Time{}
+	if f.clusterJSON != nil {
+		if t, err := utils.ParseTime(f.clusterJSON.EndTime); err == nil {
+			endTime = t
+		}
+		begin := f.clusterJSON.BeginTime
+		// a minus integer is the offset in hours and a minus duration is the
+		// offset to the end time, as they are accepted by the collector
+		if offset, err := strconv.Atoi(begin); err == nil && offset < 0 {
+			beginTime = endTime.Add(time.Hour * time.Duration(offset))
+		} else if d, err := time.ParseDuration(begin); err == nil && d < 0 {
+			beginTime = endTime.Add(d)
+		} else if t, err := utils.ParseTime(begin); err == nil {
+			beginTime = t
+		}
+	}
+	if beginTime.IsZero() || beginTime.After(endTime) {
+		beginTime = endTime.AddDate(0, 0, -7)
+	}
+	return beginTime, endTime
+}
+
 func (f *FileFetcher) loadSlowPlanData(reader io.Reader) (data map[string][2]proto.ExecutionPlanInfo, err error) {
 	csvReader := csv.NewReader(reader)
 	header, err := csvReader.Read()
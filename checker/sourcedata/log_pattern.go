@@ -0,0 +1,265 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/pingcap/diag/collector/log/iterator"
+	"github.com/pingcap/diag/collector/log/parser"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// max number and length of sample logs kept for an instance of a log rule
+const (
+	logRuleSamples      = 3
+	logRuleSampleLength = 512
+)
+
+// logRuleMatcher counts logs matching a log rule by instances
+type logRuleMatcher struct {
+	rule      *proto.Rule
+	pattern   *regexp.Regexp
+	fields    map[string]*regexp.Regexp
+	levels    []item.LevelType
+	bucket    time.Duration
+	instances map[string]*logInstanceCounter
+}
+
+type logInstanceCounter struct {
+	result  *proto.LogInstanceResult
+	buckets map[time.Time]int
+	samples []logSample
+}
+
+type logSample struct {
+	time    time.Time
+	content string
+}
+
+func newLogRuleMatcher(rule *proto.Rule) (*logRuleMatcher, error) {
+	if err := rule.Log.Validate(); err != nil {
+		return nil, err
+	}
+	m := &logRuleMatcher{
+		rule:      rule,
+		pattern:   regexp.MustCompile(rule.Log.Pattern),
+		fields:    make(map[string]*regexp.Regexp),
+		instances: make(map[string]*logInstanceCounter),
+	}
+	for key, pattern := range rule.Log.Fields {
+		m.fields[key] = regexp.MustCompile(pattern)
+	}
+	m.levels, _ = rule.Log.LevelTypes()
+	m.bucket, _ = rule.Log.BucketDuration()
+	return m, nil
+}
+
+// match checks the component, level, content and fields of a log
+func (m *logRuleMatcher) match(it item.Item) bool {
+	if len(m.rule.Log.Components) > 0 && !slices.Contains(m.rule.Log.Components, it.GetComponent()) {
+		return false
+	}
+	if len(m.levels) > 0 && !slices.Contains(m.levels, it.GetLevel()) {
+		return false
+	}
+	content := it.GetContent()
+	if !m.pattern.Match(content) {
+		return false
+	}
+	for key, pattern := range m.fields {
+		v, ok := logFieldValue(content, key)
+		if !ok || !pattern.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// add counts a matched log to its instance and bucket
+func (m *logRuleMatcher) add(it item.Item) {
+	instance := fmt.Sprintf("%s:%s", it.GetHost(), it.GetPort())
+	key := it.GetComponent() + " " + instance
+	counter, ok := m.instances[key]
+	if !ok {
+		counter = &logInstanceCounter{
+			result:  &proto.LogInstanceResult{Component: it.GetComponent(), Instance: instance},
+			buckets: make(map[time.Time]int),
+		}
+		m.instances[key] = counter
+	}
+	ts := it.GetTime()
+	r := counter.result
+	if r.Count == 0 || ts.Before(r.FirstSeen) {
+		r.FirstSeen = ts
+	}
+	if r.Count == 0 || ts.After(r.LastSeen) {
+		r.LastSeen = ts
+	}
+	r.Count++
+
+	var bucket time.Time
+	if m.bucket > 0 {
+		bucket = ts.Truncate(m.bucket)
+	}
+	counter.buckets[bucket]++
+
+	// keep the earliest logs as samples, logs of an instance may be read
+	// from several files so they are not always in order
+	i := sort.Search(len(counter.samples), func(i int) bool {
+		return counter.samples[i].time.After(ts)
+	})
+	if i < logRuleSamples {
+		content := string(it.GetContent())
+		if len(content) > logRuleSampleLength {
+			content = content[:logRuleSampleLength] + "..."
+		}
+		counter.samples = slices.Insert(counter.samples, i, logSample{time: ts, content: content})
+		if len(counter.samples) > logRuleSamples {
+			counter.samples = counter.samples[:logRuleSamples]
+		}
+	}
+}
+
+// result returns the matched logs of instances ordered by component and
+// instance
+func (m *logRuleMatcher) result() *proto.LogResult {
+	result := &proto.LogResult{Instances: make([]*proto.LogInstanceResult, 0, len(m.instances))}
+	for _, counter := range m.instances {
+		r := counter.result
+		for bucket, cnt := range counter.buckets {
+			if cnt > r.MaxCount || (cnt == r.MaxCount && bucket.Before(r.MaxBucket)) {
+				r.MaxCount, r.MaxBucket = cnt, bucket
+			}
+		}
+		if m.bucket == 0 {
+			r.MaxBucket = r.FirstSeen
+		}
+		for _, s := range counter.samples {
+			r.Samples = append(r.Samples, s.content)
+		}
+		r.Abnormal = r.MaxCount >= m.rule.Log.MinCount()
+		result.Instances = append(result.Instances, r)
+	}
+	sort.Slice(result.Instances, func(i, j int) bool {
+		a, b := result.Instances[i], result.Instances[j]
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		return a.Instance < b.Instance
+	})
+	return result
+}
+
+// logFieldValue returns the value of a [key=value] field of a log in the
+// unified log format, quoted values are unquoted
+func logFieldValue(content []byte, key string) (string, bool) {
+	prefix := []byte("[" + key + "=")
+	i := bytes.Index(content, prefix)
+	if i < 0 {
+		return "", false
+	}
+	v := content[i+len(prefix):]
+	if len(v) > 0 && v[0] == '"' {
+		if quoted, err := strconv.QuotedPrefix(string(v)); err == nil {
+			if s, err := strconv.Unquote(quoted); err == nil {
+				return s, true
+			}
+		}
+	}
+	if j := bytes.IndexByte(v, ']'); j >= 0 {
+		v = v[:j]
+	}
+	return string(v), true
+}
+
+// loadLogs evaluates log rules on the logs collected in the time range of
+// the collection, a rule is not evaluated if there is no log collected
+func (f *FileFetcher) loadLogs(sourceData *proto.SourceDataV2, rSet proto.RuleSet) {
+	matchers := make([]*logRuleMatcher, 0)
+	for _, rule := range rSet {
+		if rule.CheckType != proto.LogType || rule.Log == nil {
+			continue
+		}
+		m, err := newLogRuleMatcher(rule)
+		if err != nil {
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+
+	data := &proto.LogData{Results: make(map[string]*proto.LogResult)}
+	sourceData.LogData = data
+	if len(matchers) == 0 {
+		return
+	}
+	files, err := parser.ResolveDataDir(f.dataDirPath)
+	if err == nil {
+		files = slices.DeleteFunc(files, func(fw *parser.FileWrapper) bool {
+			return strings.Contains(fw.Filename, "slow")
+		})
+		if len(files) == 0 {
+			err = fmt.Errorf("no log files found")
+		}
+	}
+	if err != nil {
+		for _, m := range matchers {
+			data.Results[m.rule.Name] = &proto.LogResult{Error: fmt.Sprintf("no logs collected: %s", err)}
+		}
+		return
+	}
+
+	begin, end := f.collectedTimeRange()
+	for _, fw := range files {
+		iter, err := iterator.New(fw, begin, end)
+		if err != nil {
+			if err != io.EOF {
+				log.Warn("open log file failed", zap.String("file", fw.Filename), zap.Error(err))
+			}
+			continue
+		}
+		for {
+			it, err := iter.Next()
+			if err != nil {
+				if err != io.EOF {
+					log.Warn("read log file failed", zap.String("file", fw.Filename), zap.Error(err))
+				}
+				break
+			}
+			if it == nil {
+				continue
+			}
+			for _, m := range matchers {
+				if m.match(it) {
+					m.add(it)
+				}
+			}
+		}
+		iter.Close()
+	}
+	for _, m := range matchers {
+		data.Results[m.rule.Name] = m.result()
+	}
+}
@@ -0,0 +1,126 @@
+package sourcedata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/diag/collector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFieldValue(t *testing.T) {
+	assert := require.New(t)
+
+	content := []byte(`[2021/11/15 18:00:00.000 +08:00] [WARN] [region.rs:10] ["server is busy"] [region_id=42] [reason="write stall [L0]"]`)
+	v, ok := logFieldValue(content, "region_id")
+	assert.True(ok)
+	assert.Equal("42", v)
+	v, ok = logFieldValue(content, "reason")
+	assert.True(ok)
+	assert.Equal("write stall [L0]", v)
+	_, ok = logFieldValue(content, "store_id")
+	assert.False(ok)
+}
+
+func TestLoadLogs(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	writeLog := func(host, deploy, name string, lines ...string) {
+		logDir := filepath.Join(dir, host, "deploy", deploy, "log")
+		assert.Nil(os.MkdirAll(logDir, 0755))
+		assert.Nil(os.WriteFile(filepath.Join(logDir, name), []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	}
+	writeLog("10.0.0.1", "tikv-20160", "tikv.log",
+		`[2021/11/15 18:00:01.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=1]`,
+		`[2021/11/15 18:00:02.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=2]`,
+		`[2021/11/15 18:00:03.000 +08:00] [INFO] [kv.rs:1] ["server is busy"] [region_id=3]`,
+		`[2021/11/15 18:02:01.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=4]`,
+		`[2021/11/15 18:03:01.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=5]`,
+	)
+	// the earliest logs are the samples no matter which file is read first
+	writeLog("10.0.0.1", "tikv-20160", "tikv-2021-11-15T17-59-00.log",
+		`[2021/11/15 17:59:59.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=0]`,
+	)
+	writeLog("10.0.0.2", "tikv-20160", "tikv.log",
+		`[2021/11/15 18:00:01.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=1]`,
+		`[2021/11/15 18:05:01.000 +08:00] [WARN] [kv.rs:1] ["server is busy"] [region_id=2]`,
+	)
+	writeLog("10.0.0.3", "tidb-4000", "tidb.log",
+		`[2021/11/15 18:00:01.000 +08:00] [ERROR] [conn.go:1] ["server is busy"]`,
+		`[2021/11/15 18:00:02.000 +08:00] [FATAL] [main.go:1] ["panic in the recoverable goroutine"]`,
+		`goroutine 1 [running]:`,
+		`[2021/11/15 18:00:03.000 +08:00] [INFO] [main.go:1] ["welcome"]`,
+	)
+
+	f := &FileFetcher{dataDirPath: dir, clusterJSON: &collector.ClusterJSON{
+		BeginTime: "2021-11-15T09:00:00Z",
+		EndTime:   "2021-11-15T11:00:00Z",
+	}}
+	sourceData := &proto.SourceDataV2{}
+	f.loadLogs(sourceData, proto.RuleSet{
+		"busy": {Name: "busy", CheckType: proto.LogType, Log: &proto.LogRule{
+			Pattern: "server is busy", Components: []string{"tikv"}, Levels: []string{"warn"},
+			Bucket: "1m", Threshold: 2,
+		}},
+		"region": {Name: "region", CheckType: proto.LogType, Log: &proto.LogRule{
+			Fields: map[string]string{"region_id": "^[45]$"},
+		}},
+		"panic": {Name: "panic", CheckType: proto.LogType, Log: &proto.LogRule{Pattern: `\bpanic`}},
+		"conf":  {Name: "conf", CheckType: proto.ConfigType},
+	})
+	data := sourceData.LogData
+	assert.Len(data.Results, 3)
+
+	busy := data.Results["busy"]
+	assert.Len(busy.Instances, 2)
+	inst := busy.Instances[0]
+	assert.Equal("tikv", inst.Component)
+	assert.Equal("10.0.0.1:20160", inst.Instance)
+	assert.Equal(5, inst.Count)
+	assert.Equal(2, inst.MaxCount)
+	assert.Equal("2021-11-15T10:00:00Z", inst.MaxBucket.UTC().Format("2006-01-02T15:04:05Z"))
+	assert.Equal("2021-11-15T09:59:59Z", inst.FirstSeen.UTC().Format("2006-01-02T15:04:05Z"))
+	assert.Equal("2021-11-15T10:03:01Z", inst.LastSeen.UTC().Format("2006-01-02T15:04:05Z"))
+	assert.Len(inst.Samples, logRuleSamples)
+	assert.Contains(inst.Samples[0], "region_id=0")
+	assert.Contains(inst.Samples[2], "region_id=2")
+	assert.True(inst.Abnormal)
+	assert.Equal("10.0.0.2:20160", busy.Instances[1].Instance)
+	assert.False(busy.Instances[1].Abnormal)
+	assert.False(data.Passed("busy"))
+
+	assert.Len(data.Results["region"].Instances, 1)
+	assert.Equal(2, data.Results["region"].Instances[0].Count)
+
+	panics := data.Results["panic"]
+	assert.Len(panics.Instances, 1)
+	assert.Equal("tidb", panics.Instances[0].Component)
+	assert.Contains(panics.Instances[0].Samples[0], "goroutine 1 [running]:")
+
+	printer := proto.NewLogPrintTemplate(&proto.Rule{Name: "busy", WarnLevel: "warning", Log: &proto.LogRule{}})
+	assert.Nil(printer.CollectResult(proto.NewHandleData([]proto.Data{data}), false))
+	assert.Len(printer.InfoList, 2)
+	assert.Equal("warning", printer.InfoList[0].CheckResult)
+	assert.Equal("OK", printer.InfoList[1].CheckResult)
+	assert.True(printer.ResultAbnormal())
+}
+
+func TestLoadLogsWithoutData(t *testing.T) {
+	assert := require.New(t)
+
+	f := &FileFetcher{dataDirPath: t.TempDir()}
+	sourceData := &proto.SourceDataV2{}
+	f.loadLogs(sourceData, proto.RuleSet{
+		"panic": {Name: "panic", CheckType: proto.LogType, Log: &proto.LogRule{Pattern: "panic"}},
+	})
+	assert.Contains(sourceData.LogData.Results["panic"].Error, "no logs collected")
+
+	printer := proto.NewLogPrintTemplate(&proto.Rule{Name: "panic", Log: &proto.LogRule{}})
+	assert.Nil(printer.CollectResult(proto.NewHandleData([]proto.Data{sourceData.LogData}), true))
+	assert.Equal("nodata", printer.InfoList[0].CheckResult)
+	assert.False(printer.ResultAbnormal())
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedata
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/diag/collector"
+	"github.com/pingcap/diag/pkg/promql"
+)
+
+// metricRuleStep is the resolution evaluating metric rules, each sample of
+// the result stands for a step of time
+const metricRuleStep = time.Minute
+
+// loadMetrics evaluates metric rules on the metrics collected under
+// monitor/metrics, a rule is not evaluated if there is no metric collected
+func (f *FileFetcher) loadMetrics(sourceData *proto.SourceDataV2, rSet proto.RuleSet) {
+	rules := make([]*proto.Rule, 0)
+	names := make([]string, 0)
+	for _, rule := range rSet {
+		if rule.CheckType != proto.MetricType || rule.Metric == nil {
+			continue
+		}
+		expr, err := promql.ParseExpr(rule.Metric.Expr)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+		names = append(names, promql.MetricNames(expr)...)
+	}
+
+	data := &proto.MetricData{Results: make(map[string]*proto.MetricResult)}
+	sourceData.MetricData = data
+	if len(rules) == 0 {
+		return
+	}
+	storage, err := collector.LoadMetricStorage(f.dataDirPath, names)
+	var begin, end time.Time
+	if err == nil {
+		begin, end, err = storage.TimeRange()
+	}
+	for _, rule := range rules {
+		if err != nil {
+			data.Results[rule.Name] = &proto.MetricResult{Error: fmt.Sprintf("no metrics collected: %s", err)}
+			continue
+		}
+		data.Results[rule.Name] = evalMetricRule(storage, rule.Metric, begin, end)
+	}
+}
+
+// evalMetricRule finds series of the rule violating the threshold for at
+// least the window in the time range
+func evalMetricRule(storage *promql.Storage, m *proto.MetricRule, begin, end time.Time) *proto.MetricResult {
+	window, err := m.WindowDuration()
+	if err != nil {
+		return &proto.MetricResult{Error: err.Error()}
+	}
+	matrix, err := storage.Query(m.Expr, begin, end, metricRuleStep)
+	if err != nil {
+		return &proto.MetricResult{Error: err.Error()}
+	}
+
+	result := &proto.MetricResult{Series: make([]*proto.MetricSeriesResult, 0, len(matrix))}
+	for _, ss := range matrix {
+		sr := &proto.MetricSeriesResult{Labels: ss.Metric.String(), Peak: math.NaN()}
+		var runStart, prev time.Time
+		violated := false
+		for _, v := range ss.Values {
+			val := float64(v.Value)
+			if math.IsNaN(val) {
+				violated = false
+				continue
+			}
+			if math.IsNaN(sr.Peak) || beyond(m, val, sr.Peak) {
+				sr.Peak = val
+			}
+			ts := v.Timestamp.Time()
+			if !m.Violated(val) {
+				violated = false
+				continue
+			}
+			// a gap in the series breaks the run
+			if !violated || ts.Sub(prev) > metricRuleStep {
+				runStart = ts
+			}
+			violated, prev = true, ts
+			if d := ts.Sub(runStart) + metricRuleStep; d > sr.Duration {
+				sr.Duration = d
+			}
+		}
+		sr.Abnormal = sr.Duration > 0 && sr.Duration >= window
+		result.Series = append(result.Series, sr)
+	}
+	return result
+}
+
+// beyond returns true if v is further than peak in the direction of the
+// operator
+func beyond(m *proto.MetricRule, v, peak float64) bool {
+	if m.Operator == proto.MetricOpBelow {
+		return v < peak
+	}
+	return v > peak
+}
@@ -0,0 +1,85 @@
+package sourcedata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/diag/pkg/promql"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalMetricRule(t *testing.T) {
+	assert := require.New(t)
+
+	begin := time.Unix(1700000000, 0)
+	series := func(instance string, values ...float64) *model.SampleStream {
+		ss := &model.SampleStream{Metric: model.Metric{
+			model.MetricNameLabel: "cpu",
+			"instance":            model.LabelValue(instance),
+		}}
+		for i, v := range values {
+			ss.Values = append(ss.Values, model.SamplePair{
+				Timestamp: model.TimeFromUnixNano(begin.Add(time.Duration(i) * time.Minute).UnixNano()),
+				Value:     model.SampleValue(v),
+			})
+		}
+		return ss
+	}
+	storage := promql.NewStorage()
+	storage.Add(
+		// above the threshold for 3 minutes
+		series("a", 0.5, 0.9, 0.95, 0.9, 0.5, 0.9),
+		// above the threshold for 2 minutes only
+		series("b", 0.9, 0.99, 0.5, 0.9, 0.5, 0.5),
+		series("c", 0.1, 0.2, 0.3, 0.2, 0.1, 0.1),
+	)
+	end := begin.Add(5 * time.Minute)
+
+	rule := &proto.MetricRule{Expr: "cpu", Window: "3m", Threshold: 0.8}
+	result := evalMetricRule(storage, rule, begin, end)
+	assert.Empty(result.Error)
+	assert.Len(result.Series, 3)
+	assert.Equal(`cpu{instance="a"}`, result.Series[0].Labels)
+	assert.True(result.Series[0].Abnormal)
+	assert.Equal(3*time.Minute, result.Series[0].Duration)
+	assert.Equal(0.95, result.Series[0].Peak)
+	assert.False(result.Series[1].Abnormal)
+	assert.Equal(2*time.Minute, result.Series[1].Duration)
+	assert.False(result.Series[2].Abnormal)
+	assert.Zero(result.Series[2].Duration)
+
+	// any violation is abnormal without a window
+	rule = &proto.MetricRule{Expr: "max(cpu) by (instance)", Threshold: 0.2, Operator: proto.MetricOpBelow}
+	result = evalMetricRule(storage, rule, begin, end)
+	assert.Len(result.Series, 3)
+	assert.False(result.Series[0].Abnormal)
+	assert.True(result.Series[2].Abnormal)
+	assert.Equal(0.1, result.Series[2].Peak)
+
+	data := &proto.MetricData{Results: map[string]*proto.MetricResult{"cpu": result}}
+	assert.False(data.Passed("cpu"))
+	assert.True(data.Passed("missing"))
+
+	result = evalMetricRule(storage, &proto.MetricRule{Expr: "rate(cpu)"}, begin, end)
+	assert.NotEmpty(result.Error)
+}
+
+func TestLoadMetricsWithoutData(t *testing.T) {
+	assert := require.New(t)
+
+	f := &FileFetcher{dataDirPath: t.TempDir()}
+	sourceData := &proto.SourceDataV2{}
+	f.loadMetrics(sourceData, proto.RuleSet{
+		"cpu": {Name: "cpu", CheckType: proto.MetricType, Metric: &proto.MetricRule{Expr: "cpu"}},
+		"log": {Name: "log", CheckType: proto.ConfigType},
+	})
+	assert.Len(sourceData.MetricData.Results, 1)
+	assert.Contains(sourceData.MetricData.Results["cpu"].Error, "no metrics collected")
+
+	printer := proto.NewMetricPrintTemplate(&proto.Rule{Name: "cpu", Metric: &proto.MetricRule{}})
+	assert.Nil(printer.CollectResult(proto.NewHandleData([]proto.Data{sourceData.MetricData}), true))
+	assert.Equal("nodata", printer.InfoList[0].CheckResult)
+	assert.False(printer.ResultAbnormal())
+}
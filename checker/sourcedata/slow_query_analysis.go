@@ -0,0 +1,514 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedata
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/diag/checker/proto"
+	"github.com/pingcap/errors"
+)
+
+const (
+	slowQueryTopN            = 10
+	slowQuerySampleSQLLength = 256
+	// the last plan of a digest is a regression if its average latency is
+	// at least this ratio of the best earlier plan
+	planRegressionRatio  = 2.0
+	slowLogRowTimeFormat = "2006-01-02 15:04:05.999999"
+)
+
+// slow query files saved to the output dir
+const (
+	fileNameSlowQueryDigests     = "slow_query_digests.csv"
+	fileNameSlowQueryUsers       = "slow_query_users.csv"
+	fileNameSlowQueryDBs         = "slow_query_dbs.csv"
+	fileNameSlowQueryBackoffs    = "slow_query_backoffs.csv"
+	fileNameSlowQueryPlanChanges = "slow_query_plan_changes.csv"
+)
+
+// slowQueryAnalysisColumns are the slow log columns needed by the analysis
+var slowQueryAnalysisColumns = []string{
+	SlowLogTimeStr, SlowLogDigestStr, SlowLogPlanDigest, SlowLogQueryTimeStr,
+	SlowLogUserStr, SlowLogDBStr, SlowLogQuerySQLStr,
+	"Process_time", "Wait_time", SlowLogBackoffTotal, SlowLogNumCopTasksStr,
+	SlowLogCopProcMax, SlowLogCopWaitMax, SlowLogBackoffDetail,
+}
+
+type slowPlanStats struct {
+	cnt          int64
+	totalLatency float64
+	first        time.Time
+	last         time.Time
+}
+
+type slowDigestStats struct {
+	sampleSQL   string
+	latencies   []float64
+	total       float64
+	max         float64
+	processTime float64
+	waitTime    float64
+	backoffTime float64
+	copTasks    int64
+	copProcMax  float64
+	copWaitMax  float64
+	first       time.Time
+	last        time.Time
+	plans       map[string]*slowPlanStats
+}
+
+type slowGroupStats struct {
+	cnt     int64
+	total   float64
+	max     float64
+	digests map[string]struct{}
+}
+
+func updateSlowGroup(groups map[string]*slowGroupStats, name, digest string, latency float64) {
+	gs, ok := groups[name]
+	if !ok {
+		gs = &slowGroupStats{digests: make(map[string]struct{})}
+		groups[name] = gs
+	}
+	gs.cnt++
+	gs.total += latency
+	gs.max = math.Max(gs.max, latency)
+	gs.digests[digest] = struct{}{}
+}
+
+type slowQueryAnalysisAccumulator struct {
+	idxLookUp map[string]int
+	location  *time.Location
+	cnt       int64
+	digests   map[string]*slowDigestStats
+	users     map[string]*slowGroupStats
+	dbs       map[string]*slowGroupStats
+	backoffs  map[string]*proto.SlowQueryBackoff
+}
+
+// NewSlowQueryAnalysisAccumulator creates an accumulator summarizing slow
+// queries, idxLookUp must contain slowQueryAnalysisColumns
+func NewSlowQueryAnalysisAccumulator(idxLookUp map[string]int, location *time.Location) (*slowQueryAnalysisAccumulator, error) {
+	for _, col := range slowQueryAnalysisColumns {
+		if _, ok := idxLookUp[col]; !ok {
+			return nil, errors.Errorf("idxLookUp must contain %s", col)
+		}
+	}
+	return &slowQueryAnalysisAccumulator{
+		idxLookUp: idxLookUp,
+		location:  location,
+		digests:   make(map[string]*slowDigestStats),
+		users:     make(map[string]*slowGroupStats),
+		dbs:       make(map[string]*slowGroupStats),
+		backoffs:  make(map[string]*proto.SlowQueryBackoff),
+	}, nil
+}
+
+// parseFloatField returns 0 for an empty field, as fields not printed in
+// the slow log are empty
+func parseFloatField(v string) (float64, error) {
+	if len(v) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func (acc *slowQueryAnalysisAccumulator) feed(row []string) error {
+	if len(acc.idxLookUp) > len(row) {
+		return errors.New("invalid slow log row")
+	}
+	field := func(name string) string {
+		return row[acc.idxLookUp[name]]
+	}
+	occurTime, err := time.ParseInLocation(slowLogRowTimeFormat, field(SlowLogTimeStr), acc.location)
+	if err != nil {
+		return err
+	}
+	floats := make(map[string]float64)
+	for _, name := range []string{SlowLogQueryTimeStr, "Process_time", "Wait_time", SlowLogBackoffTotal, SlowLogCopProcMax, SlowLogCopWaitMax} {
+		if floats[name], err = parseFloatField(field(name)); err != nil {
+			return errors.Annotatef(err, "invalid %s", name)
+		}
+	}
+	copTasks, err := parseFloatField(field(SlowLogNumCopTasksStr))
+	if err != nil {
+		return errors.Annotatef(err, "invalid %s", SlowLogNumCopTasksStr)
+	}
+	latency := floats[SlowLogQueryTimeStr]
+	digest := field(SlowLogDigestStr)
+
+	acc.cnt++
+	ds, ok := acc.digests[digest]
+	if !ok {
+		ds = &slowDigestStats{
+			sampleSQL: field(SlowLogQuerySQLStr),
+			first:     occurTime,
+			last:      occurTime,
+			plans:     make(map[string]*slowPlanStats),
+		}
+		acc.digests[digest] = ds
+	}
+	ds.latencies = append(ds.latencies, latency)
+	ds.total += latency
+	ds.max = math.Max(ds.max, latency)
+	ds.processTime += floats["Process_time"]
+	ds.waitTime += floats["Wait_time"]
+	ds.backoffTime += floats[SlowLogBackoffTotal]
+	ds.copTasks += int64(copTasks)
+	ds.copProcMax = math.Max(ds.copProcMax, floats[SlowLogCopProcMax])
+	ds.copWaitMax = math.Max(ds.copWaitMax, floats[SlowLogCopWaitMax])
+	if occurTime.Before(ds.first) {
+		ds.first = occurTime
+	}
+	if occurTime.After(ds.last) {
+		ds.last = occurTime
+	}
+
+	planDigest := field(SlowLogPlanDigest)
+	ps, ok := ds.plans[planDigest]
+	if !ok {
+		ps = &slowPlanStats{first: occurTime, last: occurTime}
+		ds.plans[planDigest] = ps
+	}
+	ps.cnt++
+	ps.totalLatency += latency
+	if occurTime.Before(ps.first) {
+		ps.first = occurTime
+	}
+	if occurTime.After(ps.last) {
+		ps.last = occurTime
+	}
+
+	updateSlowGroup(acc.users, field(SlowLogUserStr), digest, latency)
+	updateSlowGroup(acc.dbs, field(SlowLogDBStr), digest, latency)
+
+	acc.feedBackoffDetail(field(SlowLogBackoffDetail))
+	return nil
+}
+
+// feedBackoffDetail sums up the backoff details of a slow query, which are
+// like "Cop_backoff_regionMiss_total_times: 2 Cop_backoff_regionMiss_total_time: 0.002 ..."
+func (acc *slowQueryAnalysisAccumulator) feedBackoffDetail(detail string) {
+	fields := strings.Fields(detail)
+	met := make(map[string]struct{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		key := strings.TrimSuffix(fields[i], ":")
+		if !strings.HasPrefix(key, SlowLogCopBackoffPrefix) {
+			continue
+		}
+		key = strings.TrimPrefix(key, SlowLogCopBackoffPrefix)
+		var typ string
+		var isTimes bool
+		switch {
+		case strings.HasSuffix(key, "_total_times"):
+			typ, isTimes = strings.TrimSuffix(key, "_total_times"), true
+		case strings.HasSuffix(key, "_total_time"):
+			typ = strings.TrimSuffix(key, "_total_time")
+		default:
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[i+1], 64)
+		if err != nil {
+			continue
+		}
+		bs, ok := acc.backoffs[typ]
+		if !ok {
+			bs = &proto.SlowQueryBackoff{Type: typ}
+			acc.backoffs[typ] = bs
+		}
+		if isTimes {
+			bs.Times += int64(value)
+		} else {
+			bs.TotalTime += value
+		}
+		if _, ok := met[typ]; !ok {
+			met[typ] = struct{}{}
+			bs.Queries++
+		}
+	}
+}
+
+// percentile returns the nearest-rank percentile of the values, which are
+// sorted in place
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	idx := int(math.Ceil(p*float64(len(values)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return values[idx]
+}
+
+func truncateSQL(sql string) string {
+	if len(sql) <= slowQuerySampleSQLLength {
+		return sql
+	}
+	return sql[:slowQuerySampleSQLLength] + "..."
+}
+
+func formatSlowLogTime(t time.Time) string {
+	return t.Format(slowLogRowTimeFormat)
+}
+
+// digestSummaries returns summaries of all digests ordered by total latency
+func (acc *slowQueryAnalysisAccumulator) digestSummaries() []*proto.SlowQueryDigest {
+	result := make([]*proto.SlowQueryDigest, 0, len(acc.digests))
+	for digest, ds := range acc.digests {
+		cnt := int64(len(ds.latencies))
+		result = append(result, &proto.SlowQueryDigest{
+			Digest:       digest,
+			SampleSQL:    truncateSQL(ds.sampleSQL),
+			Count:        cnt,
+			TotalLatency: ds.total,
+			AvgLatency:   ds.total / float64(cnt),
+			MaxLatency:   ds.max,
+			P99Latency:   percentile(ds.latencies, 0.99),
+			ProcessTime:  ds.processTime,
+			WaitTime:     ds.waitTime,
+			BackoffTime:  ds.backoffTime,
+			CopTasks:     ds.copTasks,
+			CopProcMax:   ds.copProcMax,
+			CopWaitMax:   ds.copWaitMax,
+			Plans:        len(ds.plans),
+			FirstSeen:    formatSlowLogTime(ds.first),
+			LastSeen:     formatSlowLogTime(ds.last),
+		})
+	}
+	sortDigests(result, func(d *proto.SlowQueryDigest) float64 { return d.TotalLatency })
+	return result
+}
+
+// sortDigests orders digests by the key descending, and then by digest
+func sortDigests(digests []*proto.SlowQueryDigest, key func(*proto.SlowQueryDigest) float64) {
+	sort.SliceStable(digests, func(i, j int) bool {
+		if ki, kj := key(digests[i]), key(digests[j]); ki != kj {
+			return ki > kj
+		}
+		return digests[i].Digest < digests[j].Digest
+	})
+}
+
+func topDigests(digests []*proto.SlowQueryDigest, key func(*proto.SlowQueryDigest) float64) []*proto.SlowQueryDigest {
+	sorted := append([]*proto.SlowQueryDigest{}, digests...)
+	sortDigests(sorted, key)
+	if len(sorted) > slowQueryTopN {
+		sorted = sorted[:slowQueryTopN]
+	}
+	return sorted
+}
+
+func groupSummaries(groups map[string]*slowGroupStats) []*proto.SlowQueryGroup {
+	result := make([]*proto.SlowQueryGroup, 0, len(groups))
+	for name, gs := range groups {
+		result = append(result, &proto.SlowQueryGroup{
+			Name:         name,
+			Count:        gs.cnt,
+			Digests:      len(gs.digests),
+			TotalLatency: gs.total,
+			AvgLatency:   gs.total / float64(gs.cnt),
+			MaxLatency:   gs.max,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalLatency != result[j].TotalLatency {
+			return result[i].TotalLatency > result[j].TotalLatency
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// planChanges returns digests executed with more than one plan, regressions
+// first and then ordered by digest
+func (acc *slowQueryAnalysisAccumulator) planChanges() []*proto.SlowQueryPlanChange {
+	result := make([]*proto.SlowQueryPlanChange, 0)
+	for digest, ds := range acc.digests {
+		if len(ds.plans) < 2 {
+			continue
+		}
+		type plan struct {
+			*proto.SlowQueryPlan
+			first time.Time
+		}
+		plans := make([]plan, 0, len(ds.plans))
+		for planDigest, ps := range ds.plans {
+			plans = append(plans, plan{
+				SlowQueryPlan: &proto.SlowQueryPlan{
+					PlanDigest: planDigest,
+					Count:      ps.cnt,
+					AvgLatency: ps.totalLatency / float64(ps.cnt),
+					FirstSeen:  formatSlowLogTime(ps.first),
+					LastSeen:   formatSlowLogTime(ps.last),
+				},
+				first: ps.first,
+			})
+		}
+		sort.Slice(plans, func(i, j int) bool {
+			if !plans[i].first.Equal(plans[j].first) {
+				return plans[i].first.Before(plans[j].first)
+			}
+			return plans[i].PlanDigest < plans[j].PlanDigest
+		})
+
+		change := &proto.SlowQueryPlanChange{
+			Digest:    digest,
+			SampleSQL: truncateSQL(ds.sampleSQL),
+		}
+		best := math.MaxFloat64
+		for i, p := range plans {
+			change.Plans = append(change.Plans, p.SlowQueryPlan)
+			if i < len(plans)-1 {
+				best = math.Min(best, p.AvgLatency)
+			}
+		}
+		change.Regression = plans[len(plans)-1].AvgLatency >= best*planRegressionRatio
+		result = append(result, change)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Regression != result[j].Regression {
+			return result[i].Regression
+		}
+		return result[i].Digest < result[j].Digest
+	})
+	return result
+}
+
+// build summarizes the slow queries, the summaries of all digests are also
+// returned to be saved
+func (acc *slowQueryAnalysisAccumulator) build() (*proto.SlowQueryReport, []*proto.SlowQueryDigest) {
+	digests := acc.digestSummaries()
+	report := &proto.SlowQueryReport{
+		Count:       acc.cnt,
+		Digests:     len(digests),
+		TopByTotal:  topDigests(digests, func(d *proto.SlowQueryDigest) float64 { return d.TotalLatency }),
+		TopByAvg:    topDigests(digests, func(d *proto.SlowQueryDigest) float64 { return d.AvgLatency }),
+		TopByP99:    topDigests(digests, func(d *proto.SlowQueryDigest) float64 { return d.P99Latency }),
+		Users:       groupSummaries(acc.users),
+		DBs:         groupSummaries(acc.dbs),
+		Backoffs:    make([]*proto.SlowQueryBackoff, 0, len(acc.backoffs)),
+		PlanChanges: acc.planChanges(),
+	}
+	for _, bs := range acc.backoffs {
+		report.Backoffs = append(report.Backoffs, bs)
+	}
+	sort.Slice(report.Backoffs, func(i, j int) bool {
+		if report.Backoffs[i].TotalTime != report.Backoffs[j].TotalTime {
+			return report.Backoffs[i].TotalTime > report.Backoffs[j].TotalTime
+		}
+		return report.Backoffs[i].Type < report.Backoffs[j].Type
+	})
+	return report, digests
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// saveSlowQueryReport writes the summaries as CSV files to dir
+func saveSlowQueryReport(dir string, report *proto.SlowQueryReport, digests []*proto.SlowQueryDigest) error {
+	files := map[string]func(w *csv.Writer) error{
+		fileNameSlowQueryDigests: func(w *csv.Writer) error {
+			if err := w.Write([]string{"Digest", "Count", "Total_latency", "Avg_latency", "P99_latency", "Max_latency",
+				"Process_time", "Wait_time", "Backoff_time", "Cop_tasks", "Cop_proc_max", "Cop_wait_max",
+				"Plans", "First_seen", "Last_seen", "Sample_sql"}); err != nil {
+				return err
+			}
+			for _, d := range digests {
+				if err := w.Write([]string{d.Digest, strconv.FormatInt(d.Count, 10), formatFloat(d.TotalLatency),
+					formatFloat(d.AvgLatency), formatFloat(d.P99Latency), formatFloat(d.MaxLatency),
+					formatFloat(d.ProcessTime), formatFloat(d.WaitTime), formatFloat(d.BackoffTime),
+					strconv.FormatInt(d.CopTasks, 10), formatFloat(d.CopProcMax), formatFloat(d.CopWaitMax),
+					strconv.Itoa(d.Plans), d.FirstSeen, d.LastSeen, d.SampleSQL}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		fileNameSlowQueryUsers: func(w *csv.Writer) error { return writeSlowQueryGroups(w, "User", report.Users) },
+		fileNameSlowQueryDBs:   func(w *csv.Writer) error { return writeSlowQueryGroups(w, "DB", report.DBs) },
+		fileNameSlowQueryBackoffs: func(w *csv.Writer) error {
+			if err := w.Write([]string{"Type", "Times", "Total_time", "Queries"}); err != nil {
+				return err
+			}
+			for _, b := range report.Backoffs {
+				if err := w.Write([]string{b.Type, strconv.FormatInt(b.Times, 10), formatFloat(b.TotalTime),
+					strconv.FormatInt(b.Queries, 10)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		fileNameSlowQueryPlanChanges: func(w *csv.Writer) error {
+			if err := w.Write([]string{"Digest", "Plan_digest", "Count", "Avg_latency", "First_seen", "Last_seen", "Regression"}); err != nil {
+				return err
+			}
+			for _, c := range report.PlanChanges {
+				for _, p := range c.Plans {
+					if err := w.Write([]string{c.Digest, p.PlanDigest, strconv.FormatInt(p.Count, 10),
+						formatFloat(p.AvgLatency), p.FirstSeen, p.LastSeen, strconv.FormatBool(c.Regression)}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+	for name, write := range files {
+		if err := writeCSVFile(path.Join(dir, name), write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSlowQueryGroups(w *csv.Writer, name string, groups []*proto.SlowQueryGroup) error {
+	if err := w.Write([]string{name, "Count", "Digests", "Total_latency", "Avg_latency", "Max_latency"}); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := w.Write([]string{g.Name, strconv.FormatInt(g.Count, 10), strconv.Itoa(g.Digests),
+			formatFloat(g.TotalLatency), formatFloat(g.AvgLatency), formatFloat(g.MaxLatency)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSVFile(fp string, write func(w *csv.Writer) error) error {
+	f, err := os.Create(fp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
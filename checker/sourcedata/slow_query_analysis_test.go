@@ -0,0 +1,135 @@
+package sourcedata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/diag/collector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryAnalysisAccumulator(t *testing.T) {
+	assert := require.New(t)
+
+	header := append([]string{}, slowQueryAnalysisColumns...)
+	idxLookUp := NewIdxLookup(header)
+	acc, err := NewSlowQueryAnalysisAccumulator(idxLookUp, time.Local)
+	assert.Nil(err)
+
+	row := func(ts, digest, plan, latency, user, db, backoff string) []string {
+		r := make([]string, len(header))
+		r[idxLookUp[SlowLogTimeStr]] = ts
+		r[idxLookUp[SlowLogDigestStr]] = digest
+		r[idxLookUp[SlowLogPlanDigest]] = plan
+		r[idxLookUp[SlowLogQueryTimeStr]] = latency
+		r[idxLookUp[SlowLogUserStr]] = user
+		r[idxLookUp[SlowLogDBStr]] = db
+		r[idxLookUp[SlowLogQuerySQLStr]] = "select " + digest + ";"
+		r[idxLookUp[SlowLogNumCopTasksStr]] = "2"
+		r[idxLookUp[SlowLogBackoffDetail]] = backoff
+		return r
+	}
+	rows := [][]string{
+		row("2021-11-15 18:00:00", "a", "a1", "1", "root", "test", ""),
+		row("2021-11-15 18:01:00", "a", "a1", "1", "root", "test", ""),
+		row("2021-11-15 18:02:00", "a", "a2", "3", "app", "test",
+			"Cop_backoff_regionMiss_total_times: 2 Cop_backoff_regionMiss_total_time: 0.5 Cop_backoff_regionMiss_max_time: 0.3"),
+		row("2021-11-15 18:03:00", "b", "b1", "0.5", "app", "db2",
+			"Cop_backoff_regionMiss_total_times: 1 Cop_backoff_regionMiss_total_time: 0.1 Cop_backoff_tikvRPC_total_times: 3 Cop_backoff_tikvRPC_total_time: 0.2"),
+	}
+	for _, r := range rows {
+		assert.Nil(acc.feed(r))
+	}
+	assert.NotNil(acc.feed(row("invalid", "c", "c1", "1", "root", "test", "")))
+
+	report, digests := acc.build()
+	assert.EqualValues(4, report.Count)
+	assert.Equal(2, report.Digests)
+	assert.Len(digests, 2)
+
+	a := report.TopByTotal[0]
+	assert.Equal("a", a.Digest)
+	assert.EqualValues(3, a.Count)
+	assert.InDelta(5.0, a.TotalLatency, 1e-9)
+	assert.InDelta(5.0/3, a.AvgLatency, 1e-9)
+	assert.InDelta(3.0, a.P99Latency, 1e-9)
+	assert.EqualValues(6, a.CopTasks)
+	assert.Equal(2, a.Plans)
+	assert.Equal("2021-11-15 18:00:00", a.FirstSeen)
+	assert.Equal("2021-11-15 18:02:00", a.LastSeen)
+	assert.Equal("a", report.TopByAvg[0].Digest)
+
+	assert.Equal("app", report.Users[0].Name)
+	assert.EqualValues(2, report.Users[0].Count)
+	assert.Equal(2, report.Users[0].Digests)
+	assert.Len(report.DBs, 2)
+
+	assert.Len(report.Backoffs, 2)
+	assert.Equal("regionMiss", report.Backoffs[0].Type)
+	assert.EqualValues(3, report.Backoffs[0].Times)
+	assert.InDelta(0.6, report.Backoffs[0].TotalTime, 1e-9)
+	assert.EqualValues(2, report.Backoffs[0].Queries)
+
+	// a2 is 3 times slower than a1
+	assert.Len(report.PlanChanges, 1)
+	change := report.PlanChanges[0]
+	assert.True(change.Regression)
+	assert.Equal("a1", change.Plans[0].PlanDigest)
+	assert.Equal("a2", change.Plans[1].PlanDigest)
+
+	dir := t.TempDir()
+	assert.Nil(saveSlowQueryReport(dir, report, digests))
+	data, err := os.ReadFile(filepath.Join(dir, fileNameSlowQueryPlanChanges))
+	assert.Nil(err)
+	assert.Contains(string(data), "a,a2,1,3,2021-11-15 18:02:00,2021-11-15 18:02:00,true")
+}
+
+func TestSlowQueryAnalysisFromLog(t *testing.T) {
+	assert := require.New(t)
+
+	header := append([]string{}, slowQueryAnalysisColumns...)
+	acc, err := NewSlowQueryAnalysisAccumulator(NewIdxLookup(header), time.Local)
+	assert.Nil(err)
+	begin := time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC)
+	retriever, err := NewSlowQueryRetriever(1, time.Local, header, "../testdata/tidb_slow_query.log",
+		WithTimeRanges(begin, begin.Add(24*time.Hour)))
+	assert.Nil(err)
+	defer retriever.Close()
+	for {
+		rows, err := retriever.retrieve(context.Background())
+		assert.Nil(err)
+		if len(rows) == 0 {
+			break
+		}
+		for _, r := range rows {
+			assert.Nil(acc.feed(r))
+		}
+	}
+	report, _ := acc.build()
+	assert.EqualValues(5, report.Count)
+	assert.Equal("root", report.Users[0].Name)
+	assert.Equal("select sleep(2);", report.TopByTotal[0].SampleSQL)
+}
+
+func TestFileFetcher_collectedTimeRange(t *testing.T) {
+	assert := require.New(t)
+
+	f := &FileFetcher{clusterJSON: &collector.ClusterJSON{
+		BeginTime: "-4h",
+		EndTime:   "2021-11-10T08:45:51Z",
+	}}
+	begin, end := f.collectedTimeRange()
+	assert.Equal("2021-11-10T08:45:51Z", end.UTC().Format(time.RFC3339))
+	assert.Equal(4*time.Hour, end.Sub(begin))
+
+	f.clusterJSON.BeginTime = "2021-11-10T06:00:00Z"
+	begin, _ = f.collectedTimeRange()
+	assert.Equal("2021-11-10T06:00:00Z", begin.UTC().Format(time.RFC3339))
+
+	f.clusterJSON.BeginTime = "invalid"
+	begin, end = f.collectedTimeRange()
+	assert.Equal(7*24*time.Hour, end.Sub(begin))
+}
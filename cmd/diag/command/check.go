@@ -28,6 +28,16 @@ func newCheckCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "check <collected-datadir>",
 		Short: "Check config collected from a TiDB cluster",
+		Long: `Check config collected from a TiDB cluster.
+
+Besides the built-in rules, rules are also loaded from .toml files in the
+"rules" dir under the data dir of diag (~/.tiup/storage/diag/rules by default),
+and then from files or dirs set by --rules. A rule overrides rules with the
+same id loaded before it.
+
+The report is printed and saved as text by default, use --report-format to also
+save it as JSON, JUnit XML, HTML or SARIF in the output dir, e.g.:
+  diag check <collected-datadir> --report-format text,json,junit`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return cmd.Help()
@@ -50,6 +60,8 @@ func newCheckCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&logLevel, "loglevel", "info", "log level, supported value is debug, info")
 	cmd.Flags().StringVarP(&opt.OutPath, "output", "o", "", "dir to save check report. report will be saved in datapath if not set")
-	cmd.Flags().StringSliceVar(&opt.Inc, "include", opt.Inc, "types of data to check, supported value is config, performance, default_config")
+	cmd.Flags().StringSliceVar(&opt.Inc, "include", opt.Inc, "types of data to check, supported value is config, performance, default_config, metric, log")
+	cmd.Flags().StringSliceVar(&opt.Formats, "report-format", opt.Formats, "formats of the check report, supported value is text, json, junit, html, sarif")
+	cmd.Flags().StringSliceVar(&opt.RuleSources, "rules", nil, "extra rule files or dirs, rules in them override the built-in ones with the same id")
 	return cmd
 }
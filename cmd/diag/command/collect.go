@@ -37,6 +37,7 @@ func newCollectCmd() *cobra.Command {
 	var metricsConf string
 	var labels []string
 	var promEndpoint string
+	var resumeDir string
 	opt := collector.BaseOptions{
 		SSH: &tui.SSHConnectionProps{
 			IdentityFile: path.Join(tiuputils.UserHome(), ".ssh", "id_rsa"),
@@ -50,13 +51,32 @@ func newCollectCmd() *cobra.Command {
 		Use:   "collect <cluster-name>",
 		Short: "Collect information and metrics from the cluster.",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// the cluster name could be read from the interrupted collection
+			if resumeDir != "" && len(args) == 0 {
+				info, err := collector.GetClusterInfoFromFile(resumeDir)
+				if err != nil {
+					return err
+				}
+				args = []string{info.ClusterName}
+			}
 			if len(args) != 1 {
 				return cmd.Help()
 			}
+			if err := collector.ValidateCollectorConcurrency(cOpt.Concurrency, cOpt.HostConcurrency); err != nil {
+				return err
+			}
+			if resumeDir != "" {
+				cOpt.Dir = resumeDir
+				cOpt.Resume = true
+			}
 			cOpt.DiagMode = collector.DiagModeCmd
 			cOpt.RawRequest = strings.Join(os.Args[1:], " ")
 
 			log.SetDisplayModeFromString(gOpt.DisplayMode)
+			if cOpt.DryRun {
+				// keep stdout for the plan
+				log.SetStdout(os.Stderr)
+			}
 			spec.Initialize("cluster")
 			tidbSpec := spec.GetSpecManager()
 			cm := collector.NewManager("tidb", tidbSpec, log)
@@ -155,6 +175,9 @@ func newCollectCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&collectAll, "all", false, "Collect all data")
 	cmd.Flags().StringSliceVar(&inc, "include", []string{"system", "config", "monitor", "log.std", "log.slow"}, "types of data to collect")
 	cmd.Flags().StringSliceVar(&ext, "exclude", nil, "types of data not to collect")
+	cmd.Flags().BoolVar(&cOpt.LogSlice, "log-slice", false, "Only collect log entries in the time range instead of whole log files, the entries are sliced on the nodes")
+	cmd.Flags().StringSliceVar(&cOpt.LogLevels, "log-level", nil, "Only collect log entries of specified levels, implies --log-slice")
+	cmd.Flags().StringArrayVar(&cOpt.LogKeywords, "log-keyword", nil, "Only collect log entries containing any of the keywords, implies --log-slice")
 	cmd.Flags().StringSliceVar(&cOpt.MetricsFilter, "metricsfilter", nil, "prefix of metrics to collect")
 	cmd.Flags().StringSliceVar(&cOpt.MetricsExclude, "metricsexclude", []string{"node_interrupts_total"}, "prefix of metrics to exclude")
 	cmd.Flags().IntVar(&cOpt.MetricsLimit, "metricslimit", 10000, "metric size limit of single request, specified in series*hour per request")
@@ -164,16 +187,25 @@ func newCollectCmd() *cobra.Command {
 	cmd.Flags().StringVar(&promEndpoint, "overwrite-prometheus-endpoint", "", "Prometheus endpoint")
 	cmd.Flags().StringSliceVarP(&cOpt.Header, "prometheus-header", "H", nil, "custom headers of http request when collect metrics")
 	cmd.Flags().StringVarP(&cOpt.Dir, "output", "o", "", "output directory of collected data")
+	cmd.Flags().StringVar(&resumeDir, "resume", "", "resume an interrupted collection stored in the directory, the collectors and time range are read from it")
 	cmd.Flags().IntVarP(&cOpt.Limit, "limit", "l", -1, "Limits the used bandwidth, specified in Kbit/s")
 	cmd.Flags().IntVar(&cOpt.PerfDuration, "perf-duration", 30, "Duration of the collection of profile information in seconds")
+	cmd.Flags().IntVar(&cOpt.PerfCount, "perf-count", 1, "Number of profiling rounds, all instances are profiled at the same time in each round")
+	cmd.Flags().IntVar(&cOpt.PerfInterval, "perf-interval", 0, "Interval between the start of two profiling rounds in seconds, 0 means one right after another")
+	cmd.Flags().IntVar(&cOpt.PerfConcurrency, "perf-concurrency", 1, "Max number of profiles taken from one instance at the same time")
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 60, "Timeout in seconds when querying APIs.")
 	cmd.Flags().BoolVar(&cOpt.CompressScp, "compress-scp", true, "Compress when transfer config and logs.Only works with system ssh")
 	cmd.Flags().BoolVar(&cOpt.CompressMetrics, "compress-metrics", true, "Compress collected metrics data.")
 	cmd.Flags().StringSliceVar(&cOpt.StripLabels, "strip-labels", nil, "Comma-separated list of label names to strip from collected metrics.")
+	cmd.Flags().BoolVar(&cOpt.MetricsRemoteRead, "metrics-remote-read", false, "Dump metrics with the remote read API of Prometheus, fallback to the query API if it is not supported")
 	cmd.Flags().BoolVar(&cOpt.ExitOnError, "exit-on-error", false, "Stop collecting and exit if an error occurs.")
+	cmd.Flags().BoolVar(&cOpt.DryRun, "dry-run", false, "Print the plan of the collection as JSON without collecting or writing anything")
+	cmd.Flags().IntVar(&cOpt.Concurrency, "collector-concurrency", collector.DefaultCollectorConcurrency, "max number of collectors running at the same time, collectors run one by one by default")
+	cmd.Flags().IntVar(&cOpt.HostConcurrency, "host-concurrency", 2, "max number of collectors running against the same host, 0 means unlimited")
 	cmd.Flags().BoolVar(&cOpt.RawMonitor, "raw-monitor", false, "Collect raw prometheus data")
 	cmd.Flags().StringVar(&cOpt.ExplainSQLPath, "explain-sql", "", "File path for explain sql")
 	cmd.Flags().StringVar(&cOpt.CurrDB, "db", "", "default db for plan replayer collector")
+	cmd.Flags().StringVar(&cOpt.DBCredentialFile, "db-credential-file", "", "File of the user and password to connect to TiDB, in the format of MySQL option files, the DIAG_DB_USER and DIAG_DB_PASSWORD environment variables are used if not set")
 
 	return cmd
 }
@@ -54,10 +54,17 @@ func newCollectDMCmd() *cobra.Command {
 			if len(args) != 1 {
 				return cmd.Help()
 			}
+			if err := collector.ValidateCollectorConcurrency(cOpt.Concurrency, cOpt.HostConcurrency); err != nil {
+				return err
+			}
 			cOpt.DiagMode = collector.DiagModeCmd
 			cOpt.RawRequest = strings.Join(os.Args[1:], " ")
 
 			log.SetDisplayModeFromString(gOpt.DisplayMode)
+			if cOpt.DryRun {
+				// keep stdout for the plan
+				log.SetStdout(os.Stderr)
+			}
 			spec.Initialize("dm")
 			dmSpec := dmspec.GetSpecManager()
 			cm := collector.NewManager("dm", dmSpec, log)
@@ -158,6 +165,7 @@ func newCollectDMCmd() *cobra.Command {
 	cmd.Flags().StringSliceVar(&cOpt.MetricsFilter, "metricsfilter", nil, "prefix of metrics to collect")
 	cmd.Flags().StringSliceVar(&cOpt.MetricsExclude, "metricsexclude", []string{"node_interrupts_total"}, "prefix of metrics to exclude")
 	cmd.Flags().IntVar(&cOpt.MetricsLimit, "metricslimit", 10000, "metric size limit of single request, specified in series*hour per request")
+	cmd.Flags().BoolVar(&cOpt.MetricsRemoteRead, "metrics-remote-read", false, "Dump metrics with the remote read API of Prometheus, fallback to the query API if it is not supported")
 	cmd.Flags().StringVar(&metricsConf, "metricsconfig", "", "config file of metricsfilter")
 	cmd.Flags().StringSliceVar(&labels, "metricslabel", nil, "only collect metrics that match labels")
 	cmd.Flags().StringVar(&promEndpoint, "overwrite-prometheus-endpoint", "", "Prometheus endpoint")
@@ -167,6 +175,9 @@ func newCollectDMCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&cOpt.CompressScp, "compress-scp", true, "Compress when transfer config and logs.Only works with system ssh")
 	cmd.Flags().BoolVar(&cOpt.CompressMetrics, "compress-metrics", true, "Compress collected metrics data.")
 	cmd.Flags().BoolVar(&cOpt.ExitOnError, "exit-on-error", false, "Stop collecting and exit if an error occurs.")
+	cmd.Flags().BoolVar(&cOpt.DryRun, "dry-run", false, "Print the plan of the collection as JSON without collecting or writing anything")
+	cmd.Flags().IntVar(&cOpt.Concurrency, "collector-concurrency", collector.DefaultCollectorConcurrency, "max number of collectors running at the same time, collectors run one by one by default")
+	cmd.Flags().IntVar(&cOpt.HostConcurrency, "host-concurrency", 2, "max number of collectors running against the same host, 0 means unlimited")
 	cmd.Flags().BoolVar(&cOpt.RawMonitor, "raw-monitor", false, "Collect raw prometheus data")
 
 	return cmd
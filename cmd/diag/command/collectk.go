@@ -55,11 +55,18 @@ func newCollectkCmd() *cobra.Command {
 			if len(args) != 1 {
 				return cmd.Help()
 			}
+			if err := collector.ValidateCollectorConcurrency(cOpt.Concurrency, cOpt.HostConcurrency); err != nil {
+				return err
+			}
 			cOpt.DiagMode = collector.DiagModeCmd
 			cOpt.UsePortForward = !direct
 			cOpt.RawRequest = strings.Join(os.Args[1:], " ")
 
 			log.SetDisplayModeFromString(gOpt.DisplayMode)
+			if cOpt.DryRun {
+				// keep stdout for the plan
+				log.SetStdout(os.Stderr)
+			}
 			cm := collector.NewManager("tidb", nil, log)
 
 			if collectAll {
@@ -122,6 +129,7 @@ func newCollectkCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to get kubernetes dynamic client interface: %v", err)
 			}
+			cOpt.RestConfig = cfg
 
 			_, err = cm.CollectClusterInfo(&opt, &cOpt, &gOpt, kubeCli, dynCli, skipConfirm)
 			return err
@@ -145,15 +153,24 @@ func newCollectkCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&cOpt.Header, "prometheus-header", "H", nil, "custom headers of http request when collect metrics")
 	cmd.Flags().StringVarP(&cOpt.Dir, "output", "o", "", "output directory of collected data")
 	// cmd.Flags().IntVarP(&cOpt.Limit, "limit", "l", -1, "Limits the used bandwidth, specified in Kbit/s")
-	// cmd.Flags().IntVar(&cOpt.PerfDuration, "perf-duration", 30, "Duration of the collection of profile information in seconds")
+	cmd.Flags().IntVar(&cOpt.PerfDuration, "perf-duration", 30, "Duration of the collection of profile information in seconds")
+	cmd.Flags().IntVar(&cOpt.PerfCount, "perf-count", 1, "Number of profiling rounds, all instances are profiled at the same time in each round")
+	cmd.Flags().IntVar(&cOpt.PerfInterval, "perf-interval", 0, "Interval between the start of two profiling rounds in seconds, 0 means one right after another")
+	cmd.Flags().IntVar(&cOpt.PerfConcurrency, "perf-concurrency", 1, "Max number of profiles taken from one instance at the same time")
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 60, "Timeout in seconds when querying APIs.")
 	// cmd.Flags().BoolVar(&cOpt.CompressScp, "compress-scp", true, "Compress when transfer config and logs.Only works with system ssh")
 	cmd.Flags().BoolVar(&cOpt.CompressMetrics, "compress-metrics", true, "Compress collected metrics data.")
 	cmd.Flags().StringSliceVar(&cOpt.StripLabels, "strip-labels", nil, "Comma-separated list of label names to strip from collected metrics.")
+	cmd.Flags().BoolVar(&cOpt.MetricsRemoteRead, "metrics-remote-read", false, "Dump metrics with the remote read API of Prometheus, fallback to the query API if it is not supported")
 	cmd.Flags().BoolVar(&cOpt.ExitOnError, "exit-on-error", false, "Stop collecting and exit if an error occurs.")
+	cmd.Flags().BoolVar(&cOpt.DryRun, "dry-run", false, "Print the plan of the collection as JSON without collecting or writing anything")
+	cmd.Flags().IntVar(&cOpt.Concurrency, "collector-concurrency", collector.DefaultCollectorConcurrency, "max number of collectors running at the same time, collectors run one by one by default")
+	cmd.Flags().IntVar(&cOpt.HostConcurrency, "host-concurrency", 2, "max number of collectors running against the same host, 0 means unlimited")
 	// cmd.Flags().BoolVar(&cOpt.RawMonitor, "raw-monitor", false, "Collect raw prometheus data")
 	// cmd.Flags().StringVar(&cOpt.ExplainSQLPath, "explain-sql", "", "File path for explain sql")
 	// cmd.Flags().StringVar(&cOpt.CurrDB, "db", "", "default db for plan replayer collector")
+	cmd.Flags().StringVar(&cOpt.DBCredentialFile, "db-credential-file", "", "File of the user and password to connect to TiDB, in the format of MySQL option files")
+	cmd.Flags().StringVar(&cOpt.DBSecret, "db-secret", "", "Secret of the user and password to connect to TiDB, in the namespace of TidbCluster")
 
 	cmd.Flags().StringVar(&opt.Kubeconfig, "kubeconfig", clientcmd.RecommendedHomeFile, "path of kubeconfig")
 	cmd.Flags().StringVarP(&opt.Namespace, "namespace", "n", "", "Namespace of TidbCluster")
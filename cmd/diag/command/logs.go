@@ -0,0 +1,157 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/collector/log/search"
+	"github.com/pingcap/diag/pkg/utils"
+	"github.com/pingcap/diag/scraper"
+	"github.com/spf13/cobra"
+)
+
+type logEntry struct {
+	Time      time.Time `json:"time"`
+	Host      string    `json:"host"`
+	Component string    `json:"component"`
+	Port      string    `json:"port,omitempty"`
+	File      string    `json:"file"`
+	Level     string    `json:"level"`
+	Content   string    `json:"content"`
+}
+
+func newLogsCmd() *cobra.Command {
+	var (
+		begin   string
+		end     string
+		levels  []string
+		pattern string
+		limit   int
+		token   string
+	)
+	filter := &search.Filter{}
+	cmd := &cobra.Command{
+		Use:   "logs <collected-datadir> [flags]",
+		Short: "Search logs of a data set as a merged timeline.",
+		Long: `Search the logs of all hosts and components of a data set, and print
+the matched entries merged and ordered by time.
+
+At most --limit entries are printed, and a token is printed after them if
+there are more, pass it with --token and the same filters to get the next
+page, e.g.:
+  diag logs <collected-datadir> --level warn,error --component tikv --grep 'region \d+'
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if limit <= 0 {
+				return fmt.Errorf("the limit must be positive")
+			}
+			var err error
+			if begin != "" {
+				if filter.Begin, err = utils.ParseTime(begin); err != nil {
+					return err
+				}
+			}
+			filter.End = time.Now()
+			if end != "" {
+				if filter.End, err = utils.ParseTime(end); err != nil {
+					return err
+				}
+			}
+			if filter.Levels, err = scraper.ParseLevels(levels); err != nil {
+				return err
+			}
+			if pattern != "" {
+				if filter.Pattern, err = regexp.Compile(pattern); err != nil {
+					return err
+				}
+			}
+
+			iter, _, err := search.NewSearcher().SearchFilter(args[0], filter, token)
+			if err != nil {
+				return err
+			}
+			defer iter.Close()
+
+			entries := make([]logEntry, 0, limit)
+			more := false
+			for {
+				it, err := iter.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if len(entries) >= limit {
+					more = true
+					break
+				}
+				entries = append(entries, logEntry{
+					Time:      it.GetTime(),
+					Host:      it.GetHost(),
+					Component: it.GetComponent(),
+					Port:      it.GetPort(),
+					File:      it.GetFileName(),
+					Level:     it.GetLevel().String(),
+					Content:   string(it.GetContent()),
+				})
+				// the cursor is after the last printed entry
+				token = iter.Cursor().Token()
+			}
+			if !more {
+				token = ""
+			}
+
+			if strings.ToLower(gOpt.DisplayMode) == "json" {
+				data, err := json.MarshalIndent(map[string]interface{}{
+					"logs":  entries,
+					"token": token,
+				}, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			for _, e := range entries {
+				fmt.Printf("%s %s %s %s %s\n",
+					e.Time.Format("2006-01-02 15:04:05.000"), e.Host, e.Component, e.Level, e.Content)
+			}
+			if token != "" {
+				fmt.Printf("\nMore logs with: --token %s\n", token)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&begin, "from", "f", "", "only search logs after the time")
+	cmd.Flags().StringVarP(&end, "to", "t", "", "only search logs before the time, default to now")
+	cmd.Flags().StringSliceVar(&levels, "level", nil, "only search logs of the levels, e.g. warn,error")
+	cmd.Flags().StringSliceVar(&filter.Components, "component", nil, "only search logs of the components, e.g. tidb,tikv")
+	cmd.Flags().StringSliceVar(&filter.Hosts, "host", nil, "only search logs of the hosts, or pods for data sets collected from kubernetes")
+	cmd.Flags().StringVar(&pattern, "grep", "", "only search logs matching the regular expression")
+	cmd.Flags().IntVar(&limit, "limit", 100, "maximum number of logs to print")
+	cmd.Flags().StringVar(&token, "token", "", "continue a search from the token printed by the previous one")
+
+	return cmd
+}
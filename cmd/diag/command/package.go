@@ -77,6 +77,7 @@ func newPackageCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&pOpt.InputDir, "input", "i", "", "input directory of collected data")
 	cmd.Flags().StringVarP(&pOpt.OutputFile, "output", "o", "", "output file of packaged data")
 	cmd.Flags().BoolVar(&pOpt.Rebuild, "rebuild", true, "rebuild package immediately after upload")
+	cmd.Flags().BoolVar(&pOpt.AEADFormat, "aead-format", false, "package with chunked authenticated encryption, the package could only be read by consumers supporting the format")
 
 	return cmd
 }
@@ -0,0 +1,152 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/collector"
+	"github.com/pingcap/diag/pkg/promql"
+	"github.com/pingcap/diag/pkg/utils"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+)
+
+func newQueryCmd() *cobra.Command {
+	var (
+		begin  string
+		end    string
+		at     string
+		step   time.Duration
+		csvOut bool
+	)
+	cmd := &cobra.Command{
+		Use:   "query <collected-datadir> <expr> [flags]",
+		Short: "Query the dumped metrics with PromQL.",
+		Long: `Query the dumped metrics of a data set with PromQL, without
+rebuilding the monitoring system. Only a subset of PromQL is supported:
+  - selectors, e.g., tikv_engine_size_bytes{type=~"default|write"}
+  - arithmetic operators: +, -, *, /
+  - aggregations: sum, avg, min, max and count, with by or without
+  - functions: rate, irate, increase, delta and histogram_quantile
+
+The whole collected time range is queried by default, e.g.:
+  diag query <collected-datadir> 'histogram_quantile(0.99, sum(rate(tikv_grpc_msg_duration_seconds_bucket[1m])) by (le))' --at '2026-10-18 14:02'
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+			expr, err := promql.ParseExpr(args[1])
+			if err != nil {
+				return err
+			}
+			storage, err := collector.LoadMetricStorage(args[0], promql.MetricNames(expr))
+			if err != nil {
+				return err
+			}
+
+			start, stop, err := storage.TimeRange()
+			if err != nil {
+				return err
+			}
+			if begin != "" {
+				if start, err = utils.ParseTime(begin); err != nil {
+					return err
+				}
+			}
+			if end != "" {
+				if stop, err = utils.ParseTime(end); err != nil {
+					return err
+				}
+			}
+			if at != "" {
+				if start, err = utils.ParseTime(at); err != nil {
+					return err
+				}
+				stop = start
+			}
+
+			result, err := storage.Query(args[1], start, stop, step)
+			if err != nil {
+				return err
+			}
+			switch {
+			case strings.ToLower(gOpt.DisplayMode) == "json":
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			case csvOut:
+				w := csv.NewWriter(os.Stdout)
+				if err := w.WriteAll(queryResultTable(result, time.RFC3339)); err != nil {
+					return err
+				}
+			default:
+				if len(result) == 0 {
+					fmt.Println("No data.")
+					return nil
+				}
+				tui.PrintTable(queryResultTable(result, "2006-01-02 15:04:05"), true)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&begin, "from", "f", "", "start time of the query, default to the time of the first collected sample")
+	cmd.Flags().StringVarP(&end, "to", "t", "", "end time of the query, default to the time of the last collected sample")
+	cmd.Flags().StringVar(&at, "at", "", "evaluate the expression at a single time point")
+	cmd.Flags().DurationVar(&step, "step", time.Minute, "query resolution step")
+	cmd.Flags().BoolVar(&csvOut, "csv", false, "print the result in CSV")
+
+	return cmd
+}
+
+// queryResultTable converts the result to a table, the first column is the
+// time and each of the other columns is a series
+func queryResultTable(result model.Matrix, timeFormat string) [][]string {
+	header := []string{"Time"}
+	values := make(map[model.Time][]string)
+	for i, ss := range result {
+		header = append(header, ss.Metric.String())
+		for _, v := range ss.Values {
+			row, ok := values[v.Timestamp]
+			if !ok {
+				row = make([]string, len(result))
+				values[v.Timestamp] = row
+			}
+			row[i] = strconv.FormatFloat(float64(v.Value), 'g', -1, 64)
+		}
+	}
+
+	times := make([]model.Time, 0, len(values))
+	for t := range values {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	rows := [][]string{header}
+	for _, t := range times {
+		rows = append(rows, append([]string{t.Time().Local().Format(timeFormat)}, values[t]...))
+	}
+	return rows
+}
@@ -144,7 +144,11 @@ func init() {
 		newCollectDMCmd(),
 		newCollectkCmd(),
 		newPackageCmd(),
+		newUnpackCmd(),
+		newVerifyCmd(),
 		newRebuildCmd(),
+		newQueryCmd(),
+		newLogsCmd(),
 		newUploadCommand(),
 		newHistoryCommand(),
 		newCheckCmd(),
@@ -0,0 +1,99 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/pkg/packager"
+	"github.com/spf13/cobra"
+)
+
+func newUnpackCmd() *cobra.Command {
+	opt := &packager.UnpackOptions{}
+	cmd := &cobra.Command{
+		Use:   "unpack <package-file>",
+		Short: "Decrypt and extract a packaged data set",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			opt.InputFile = args[0]
+
+			dir, err := packager.UnpackPackage(opt)
+			if err != nil {
+				return err
+			}
+			log.Infof("package extracted to %s", dir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opt.OutputDir, "output", "o", "", "output directory of extracted data, default to the name of the package")
+	cmd.Flags().StringVarP(&opt.PrivateKey, "key", "k", "", "private key file to decrypt the package")
+
+	return cmd
+}
+
+func newVerifyCmd() *cobra.Command {
+	opt := &packager.UnpackOptions{}
+	cmd := &cobra.Command{
+		Use:   "verify <package-file>",
+		Short: "Verify integrity of a packaged data set without extracting it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			opt.InputFile = args[0]
+
+			info, verr := packager.VerifyPackage(opt)
+			if info != nil {
+				if strings.ToLower(gOpt.DisplayMode) == "json" {
+					data, err := json.MarshalIndent(info, "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(data))
+				} else {
+					printPackageInfo(info)
+				}
+			}
+			if verr != nil {
+				return verr
+			}
+			fmt.Fprintln(os.Stderr, color.GreenString("%s is verified", opt.InputFile))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opt.PrivateKey, "key", "k", "", "private key file to decrypt the package")
+
+	return cmd
+}
+
+func printPackageInfo(info *packager.PackageInfo) {
+	fmt.Printf("Package version: %d\n", info.Version>>6)
+	fmt.Printf("Format:          %s\n", info.Format)
+	fmt.Printf("Compression:     %s\n", info.Compress)
+	for _, key := range []string{"cluster_name", "cluster_id", "cluster_type", "begin_time", "end_time", "dir_size"} {
+		if v, ok := info.Meta[key]; ok {
+			fmt.Printf("  %-15s%v\n", key+":", v)
+		}
+	}
+	fmt.Printf("Files:           %d, %d bytes in total\n", len(info.Files), info.DataSize)
+}
@@ -84,6 +84,8 @@ func newUploadCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opt.Endpoint, "endpoint", "", "the clinic service Endpoint.")
 	cmd.Flags().StringVar(&opt.Issue, "issue", "", "related jira oncall Issue, example: ONCALL-1131")
 	cmd.Flags().BoolVar(&opt.Rebuild, "rebuild", true, "rebuild package immediately after upload")
+	cmd.Flags().BoolVar(&opt.Force, "force", false, "upload the package even if identical data was uploaded before")
+	cmd.Flags().BoolVar(&opt.AEADFormat, "aead-format", false, "package with chunked authenticated encryption if the data is not packaged yet")
 
 	cmd.Flags().MarkHidden("endpoint")
 
@@ -32,6 +32,8 @@ func newUtilCmd() *cobra.Command {
 
 	utilCmd.AddCommand(
 		newMetricDumpCmd(),
+		newMetricExportCmd(),
+		newLogExportCmd(),
 		newPlanReplayerCmd(),
 	)
 
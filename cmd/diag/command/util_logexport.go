@@ -0,0 +1,74 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/pingcap/diag/collector"
+	"github.com/pingcap/diag/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newLogExportCmd() *cobra.Command {
+	opt := collector.LogExportOptions{}
+	var begin, end string
+	cmd := &cobra.Command{
+		Use:   "logexport <collected-datadir> [flags]",
+		Short: "Convert collected logs to a time-sorted JSONL or Parquet event stream.",
+		Long: `Parse all logs of a data set with the parser matching each file, and
+write the entries merged and sorted by time to a single output file.
+
+Each entry has the time, host, component, file, level, message and the
+key=value fields parsed from the log, so it could be loaded into log
+analytics systems directly.
+
+With "--type parquet", the fields are stored as a JSON object string.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if opt.Output == "" {
+				return fmt.Errorf("the output path must be specified with --output")
+			}
+			var err error
+			if begin != "" {
+				if opt.Begin, err = utils.ParseTime(begin); err != nil {
+					return err
+				}
+			}
+			if end != "" {
+				if opt.End, err = utils.ParseTime(end); err != nil {
+					return err
+				}
+			}
+
+			count, err := collector.ExportLogs(args[0], &opt)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d log entries exported to %s\n", count, opt.Output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opt.Format, "type", collector.LogExportJSONL,
+		fmt.Sprintf("Format of exported logs, can be '%s' or '%s'.", collector.LogExportJSONL, collector.LogExportParquet))
+	cmd.Flags().StringVarP(&opt.Output, "output", "o", "", "Output file of exported logs.")
+	cmd.Flags().StringVarP(&begin, "from", "f", "", "Only export log entries after the time.")
+	cmd.Flags().StringVarP(&end, "to", "t", "", "Only export log entries before the time.")
+
+	return cmd
+}
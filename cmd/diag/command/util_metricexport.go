@@ -0,0 +1,65 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/pingcap/diag/collector"
+	"github.com/spf13/cobra"
+)
+
+func newMetricExportCmd() *cobra.Command {
+	opt := collector.MetricExportOptions{}
+	cmd := &cobra.Command{
+		Use:   "metricexport <collected-datadir> [flags]",
+		Short: "Convert dumped metrics to OpenMetrics text or Prometheus TSDB blocks.",
+		Long: `Convert the dumped metrics of a data set to OpenMetrics text or
+a Prometheus TSDB block for offline analysis without InfluxDB.
+
+With "--type tsdb", a block is created under the output directory,
+which could be used by a stock Prometheus directly:
+
+  prometheus --storage.tsdb.path=<output> --storage.tsdb.retention.time=100y
+
+The retention must be long enough to cover the time range of the
+dumped metrics, otherwise the block may be deleted by Prometheus.
+
+With "--type openmetrics", all metrics are written to the output file,
+which could also be imported with "promtool tsdb create-blocks-from openmetrics".
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if opt.Output == "" {
+				return fmt.Errorf("the output path must be specified with --output")
+			}
+
+			output, err := collector.ExportMetrics(args[0], &opt)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Metrics exported to %s\n", output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opt.Format, "type", collector.MetricExportTSDB,
+		fmt.Sprintf("Format of exported metrics, can be '%s' or '%s'.", collector.MetricExportTSDB, collector.MetricExportOpenMetrics))
+	cmd.Flags().StringVarP(&opt.Output, "output", "o", "", "Output file of OpenMetrics text, or the storage directory of TSDB blocks.")
+	cmd.Flags().StringSliceVar(&opt.StripLabels, "strip-labels", nil, "Comma-separated list of label names to strip from metrics before exporting.")
+
+	return cmd
+}
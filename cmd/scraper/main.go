@@ -69,6 +69,10 @@ func init() {
 	rootCmd.Flags().StringVar(&opt.PrometheusDataDir, "prometheus", "", "paths of prometheus datadir")
 	rootCmd.Flags().StringVarP(&opt.Start, "from", "f", "", "start time of range to scrap, only apply to logs")
 	rootCmd.Flags().StringVarP(&opt.End, "to", "t", "", "start time of range to scrap, only apply to logs")
+	rootCmd.Flags().StringVar(&opt.SliceDir, "slice-dir", "", "write entries of std and slow logs in the time range to gzipped slices in the dir")
+	rootCmd.Flags().BoolVar(&opt.EstimateSlices, "estimate", false, "estimate the sizes of slices by the time range without writing them, only apply with --slice-dir")
+	rootCmd.Flags().StringSliceVar(&opt.LogLevels, "level", nil, "levels of log entries to keep in slices, only apply with --slice-dir")
+	rootCmd.Flags().StringArrayVar(&opt.LogKeywords, "keyword", nil, "keep log entries containing any of the keywords in slices, only apply with --slice-dir")
 
 	// time range is required, no default values are assumed
 	cobra.MarkFlagRequired(rootCmd.Flags(), "from")
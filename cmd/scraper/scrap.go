@@ -30,10 +30,16 @@ func Scrap(opt *scraper.Option) (*scraper.Sample, error) {
 	}
 	if len(opt.LogPaths) > 0 {
 		s := &scraper.LogScraper{
-			Paths: opt.LogPaths,
-			Types: opt.LogTypes,
+			Paths:    opt.LogPaths,
+			Types:    opt.LogTypes,
+			SliceDir: opt.SliceDir,
+			Estimate: opt.EstimateSlices,
+			Keywords: opt.LogKeywords,
 		}
 		var err error
+		if s.Levels, err = scraper.ParseLevels(opt.LogLevels); err != nil {
+			return nil, err
+		}
 		if s.Start, err = utils.ParseTime(opt.Start); err != nil {
 			return nil, err
 		}
@@ -2,7 +2,7 @@ package collector
 
 import (
 	"context"
-	"database/sql"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,6 +25,7 @@ type BindCollectOptions struct {
 	dbpasswd  string
 	resultDir string
 	fileStats map[string][]CollectStat
+	tlsCfg    *tls.Config
 }
 
 type bindStruct struct {
@@ -65,13 +66,14 @@ func (c *BindCollectOptions) SetDir(dir string) {
 }
 
 // Prepare implements the Collector interface
-func (c *BindCollectOptions) Prepare(_ *Manager, _ *models.TiDBCluster) (map[string][]CollectStat, error) {
-	return nil, nil
+func (c *BindCollectOptions) Prepare(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+	return sqlStats(topo, "sql_bind", len(collectedBind), len(collectedBind),
+		int64(len(collectedBind))*50*1024), nil
 }
 
 // Collect implements the Collector interface
 func (c *BindCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error {
-	err := os.Mkdir(filepath.Join(c.resultDir, DirNameBind), 0755)
+	err := os.MkdirAll(filepath.Join(c.resultDir, DirNameBind), 0755)
 	if err != nil {
 		return err
 	}
@@ -87,27 +89,9 @@ func (c *BindCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error
 		Func(
 			"collect sql_bind",
 			func(ctx context.Context) error {
-				var db *sql.DB
-				for _, inst := range tidbInstants {
-					cdb, err := func() (*sql.DB, error) {
-						trydb, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/", c.dbuser, c.dbpasswd, inst.Host(), inst.MainPort()))
-						if err != nil {
-							return nil, err
-						}
-						err = trydb.Ping()
-						if err != nil {
-							defer trydb.Close()
-							return nil, err
-						}
-						return trydb, nil
-					}()
-					if err != nil {
-						continue
-					}
-					db = cdb
-				}
-				if db == nil {
-					return fmt.Errorf("cannot connect to any TiDB instance")
+				db, err := openTiDB(tidbInstants, c.dbuser, c.dbpasswd, c.tlsCfg)
+				if err != nil {
+					return err
 				}
 				defer db.Close()
 
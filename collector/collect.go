@@ -14,20 +14,25 @@
 package collector
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
+	json "github.com/json-iterator/go"
 	"github.com/pingcap/diag/pkg/models"
 	kubetls "github.com/pingcap/diag/pkg/tls"
 	"github.com/pingcap/diag/pkg/utils"
+	"github.com/pingcap/diag/scraper"
 	"github.com/pingcap/errors"
 	pingcapv1alpha1 "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
@@ -36,6 +41,7 @@ import (
 	"github.com/pingcap/tiup/pkg/tui"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog"
 )
 
@@ -52,6 +58,7 @@ const (
 	CollectTypeComponentMeta = "component_meta"
 	CollectTypeBind          = "sql_bind"
 	CollectTypePlanReplayer  = "plan_replayer"
+	CollectTypeSQLSnapshot   = "sql_snapshot"
 
 	CollectModeTiUP   = "tiup-cluster"  // collect from a tiup-cluster deployed cluster
 	CollectModeK8s    = "tidb-operator" // collect from a tidb-operator deployed cluster
@@ -77,10 +84,11 @@ type CollectTree struct {
 	Config         collectConfig
 	DB_Vars        bool
 	Perf           bool
-	Debug          bool
+	Debug          collectDebug
 	Component_Meta bool
 	SQL_Bind       bool
 	Plan_Replayer  bool
+	SQL_Snapshot   bool
 }
 
 // Collector is the configuration defining an collecting job
@@ -121,17 +129,32 @@ type CollectOptions struct {
 	MetricsLimit       int               // query limit of one request
 	MetricsMinInterval int               // query minimum interval of one request, default is 1min.
 	PerfDuration       int               // seconds: profile time(s), default is 30s.
+	PerfCount          int               // number of profiling rounds, default is 1
+	PerfInterval       int               // seconds between the start of two profiling rounds, 0 means one right after another
+	PerfConcurrency    int               // max number of profiles taken from one instance at the same time
+	LogSlice           bool              // fetch slices of logs in the time range instead of whole files
+	LogLevels          []string          // levels of log entries to collect, implies LogSlice
+	LogKeywords        []string          // keywords of log entries to collect, implies LogSlice
 	CompressScp        bool              // compress of files during collecting
 	CompressMetrics    bool              // compress of files during collecting
 	RawMonitor         bool              // collect raw data for metrics
 	StripLabels        []string          // label names to strip from collected metrics
+	MetricsRemoteRead  bool              // dump metrics with the remote read API of Prometheus
 	ExitOnError        bool              // break the process and exit when an error occur
 	ExtendedAttrs      map[string]string // extended attributes used for manual collecting mode
+	Resume             bool              // resume an interrupted collection stored in Dir
 	ExplainSQLPath     string            // File path for explain sql
 	ExplainSqls        []string          // explain sqls
 	CurrDB             string
 	Header             []string
-	UsePortForward     bool // use portforward when call api inside k8s cluster
+	UsePortForward     bool         // use portforward when call api inside k8s cluster
+	RestConfig         *rest.Config // config of the k8s API server, used to exec in pods
+	Concurrency        int          // max number of collectors running at the same time
+	HostConcurrency    int          // max number of collectors running against the same host, 0 means unlimited
+	SQLQueries         []SQLQuery   // queries added to the built-in catalog of the sql_snapshot collector
+	DBCredentialFile   string       // file of the user and password to connect to TiDB
+	DBSecret           string       // kubernetes secret of the user and password to connect to TiDB
+	DryRun             bool         // print the plan of the collection as JSON instead of collecting
 }
 
 // CollectStat is estimated size stats of data to be collected
@@ -157,11 +180,18 @@ func (m *Manager) CollectClusterInfo(
 	m.diagMode = cOpt.DiagMode
 	m.mode = cOpt.Mode
 
+	if cOpt.Resume {
+		if err := m.prepareResume(opt, cOpt); err != nil {
+			return "", err
+		}
+	}
+
 	var sensitiveTag bool
 	var cls *models.TiDBCluster
 	var tc *pingcapv1alpha1.TidbCluster
 	var tm *pingcapv1alpha1.TidbMonitor
 	var tlsCfg *tls.Config
+	var sqlTLSCfg *tls.Config // TLS config of the SQL port of TiDB
 	var err error
 	switch cOpt.Mode {
 	case CollectModeTiUP:
@@ -187,6 +217,12 @@ func (m *Manager) CollectClusterInfo(
 			}
 			klog.Infof("get tls config from secrets success")
 		}
+		if tc != nil && tc.Spec.TiDB != nil && tc.Spec.TiDB.IsTLSClientEnabled() && !tc.SkipTLSWhenConnectTiDB() {
+			sqlTLSCfg, err = kubetls.GetTiDBClientTLSConfig(kubeCli, opt.Namespace, opt.Cluster, time.Second*time.Duration(gOpt.APITimeout))
+			if err != nil {
+				return "", err
+			}
+		}
 	case CollectModeManual:
 		cls, err = buildTopoForManualCluster(cOpt)
 		if err != nil {
@@ -200,12 +236,26 @@ func (m *Manager) CollectClusterInfo(
 		if err != nil {
 			return "", err
 		}
+		// instances other than the input ones are found from PD
+		endpoints, ok := cls.Attributes[AttrKeyPDEndpoint].([]string)
+		if !ok {
+			return "", fmt.Errorf("no PD endpoint found in the topology")
+		}
+		if len(endpoints) > 0 && endpoints[0] != "" {
+			ctx := ctxt.New(context.Background(), gOpt.Concurrency, m.logger)
+			if err := discoverTopology(ctx, cls, endpoints, tlsCfg, time.Second*time.Duration(gOpt.APITimeout)); err != nil {
+				m.logger.Warnf("Failed to discover topology of the cluster from PD: %s, only the input instances are used", err)
+			}
+		}
 	default:
 		return "", fmt.Errorf("unknown collect mode '%s'", cOpt.Mode)
 	}
 	if cls == nil {
 		return "", fmt.Errorf("no valid cluster topology parsed")
 	}
+	if sqlTLSCfg == nil {
+		sqlTLSCfg = tlsCfg
+	}
 	if cls.Attributes == nil {
 		cls.Attributes = map[string]interface{}{}
 	}
@@ -244,7 +294,8 @@ func (m *Manager) CollectClusterInfo(
 		}
 		logprinter.Infof("%s", msg)
 
-		if len(cp.Collectors) > 0 {
+		// keep the collector list of the interrupted collection
+		if len(cp.Collectors) > 0 && !cOpt.Resume {
 			cOpt.Collectors, err = ParseCollectTree(cp.Collectors, nil)
 			if err != nil {
 				return "", err
@@ -255,6 +306,7 @@ func (m *Manager) CollectClusterInfo(
 		if len(cp.StripLabels) > 0 && len(cOpt.StripLabels) == 0 {
 			cOpt.StripLabels = cp.StripLabels
 		}
+		cOpt.SQLQueries = append(cOpt.SQLQueries, cp.SQLQueries...)
 	}
 
 	var explainSqls []string
@@ -319,6 +371,8 @@ func (m *Manager) CollectClusterInfo(
 				customHeader: cOpt.Header,
 				portForward:  cOpt.UsePortForward,
 				stripLabels:  cOpt.StripLabels,
+				remoteRead:   cOpt.MetricsRemoteRead,
+				dryRun:       cOpt.DryRun,
 			},
 		)
 	}
@@ -331,6 +385,7 @@ func (m *Manager) CollectClusterInfo(
 				fileStats:   make(map[string][]CollectStat),
 				limit:       cOpt.Limit,
 				compress:    cOpt.CompressScp,
+				dryRun:      cOpt.DryRun,
 			},
 		)
 	}
@@ -356,11 +411,16 @@ func (m *Manager) CollectClusterInfo(
 			BaseOptions: opt,
 			opt:         gOpt,
 			resultDir:   resultDir,
+			kubeCli:     kubeCli,
+			restCfg:     cOpt.RestConfig,
 		})
 	}
 
 	// collect log files
 	if canCollect(&cOpt.Collectors.Log) {
+		if _, err := scraper.ParseLevels(cOpt.LogLevels); err != nil {
+			return "", err
+		}
 		collectors = append(collectors,
 			&LogCollectOptions{
 				BaseOptions: opt,
@@ -371,6 +431,10 @@ func (m *Manager) CollectClusterInfo(
 				fileStats:   make(map[string][]CollectStat),
 				compress:    cOpt.CompressScp,
 				kubeCli:     kubeCli,
+				slice:       cOpt.LogSlice,
+				levels:      cOpt.LogLevels,
+				keywords:    cOpt.LogKeywords,
+				dryRun:      cOpt.DryRun,
 			})
 	}
 
@@ -386,14 +450,17 @@ func (m *Manager) CollectClusterInfo(
 				fileStats:   make(map[string][]CollectStat),
 				compress:    cOpt.CompressScp,
 				tlsCfg:      tlsCfg,
+				dryRun:      cOpt.DryRun,
 			})
 	}
 
 	var dbUser, dbPassword string
-	if needDBKey(cOpt.Collectors) {
-		fmt.Print("please enter database username:")
-		fmt.Scanln(&dbUser)
-		dbPassword = tui.PromptForPassword("please enter database password:")
+	if needDBKey(cOpt.Collectors) && !cOpt.DryRun {
+		var err error
+		dbUser, dbPassword, err = dbCredential(cOpt, kubeCli, opt.Namespace, time.Second*time.Duration(gOpt.APITimeout))
+		if err != nil {
+			return "", err
+		}
 	}
 
 	if canCollect(&cOpt.Collectors.DB_Vars) {
@@ -405,6 +472,7 @@ func (m *Manager) CollectClusterInfo(
 				dbpasswd:    dbPassword,
 				resultDir:   resultDir,
 				fileStats:   make(map[string][]CollectStat),
+				tlsCfg:      sqlTLSCfg,
 			})
 	}
 
@@ -417,39 +485,52 @@ func (m *Manager) CollectClusterInfo(
 				dbpasswd:    dbPassword,
 				resultDir:   resultDir,
 				fileStats:   make(map[string][]CollectStat),
+				tlsCfg:      sqlTLSCfg,
+			})
+	}
+
+	if canCollect(&cOpt.Collectors.SQL_Snapshot) {
+		queries, err := loadSQLQueries(cOpt.SQLQueries)
+		if err != nil {
+			return "", err
+		}
+		collectors = append(collectors,
+			&SQLSnapshotCollectOptions{
+				BaseOptions: opt,
+				opt:         gOpt,
+				dbuser:      dbUser,
+				dbpasswd:    dbPassword,
+				queries:     queries,
+				resultDir:   resultDir,
+				tlsCfg:      sqlTLSCfg,
 			})
 	}
 
-	// hide perf
 	if canCollect(&cOpt.Collectors.Perf) {
-		return "", fmt.Errorf("perf collection is disabled in diag, use TiDB Dashboard instead")
-	}
-
-	// 	if len(cls.TiKV) > 0 {
-	// 		// maybe it's better to use tiup/pkg/tidbver
-	// 		if !(semver.Compare(cls.Version, "v5.0.0") >= 0 || strings.Contains(cls.Version, "nightly")) {
-	// 			return "", errors.Errorf("cannot collect perf information of Tikv whose version is less than v5.0.0")
-	// 		}
-	// 	}
-
-	// 	if cOpt.PerfDuration < 1 {
-	// 		if m.mode == CollectModeK8s {
-	// 			cOpt.PerfDuration = 30
-	// 		} else {
-	// 			return "", errors.Errorf("perf-duration cannot be less than 1")
-	// 		}
-
-	// 	}
-	// 	collectors = append(collectors,
-	// 		&PerfCollectOptions{
-	// 			BaseOptions: opt,
-	// 			opt:         gOpt,
-	// 			duration:    cOpt.PerfDuration,
-	// 			resultDir:   resultDir,
-	// 			fileStats:   make(map[string][]CollectStat),
-	// 			tlsCfg:      tlsCfg,
-	// 		})
-	// }
+		if cOpt.PerfDuration < 1 {
+			if m.mode == CollectModeK8s {
+				cOpt.PerfDuration = 30
+			} else {
+				return "", errors.Errorf("perf-duration cannot be less than 1")
+			}
+		}
+		if cOpt.PerfCount > 1 && cOpt.PerfInterval > 0 && cOpt.PerfInterval < cOpt.PerfDuration {
+			// an instance could not run two cpu profiles at the same time
+			return "", errors.Errorf("perf-interval cannot be less than perf-duration")
+		}
+		collectors = append(collectors,
+			&PerfCollectOptions{
+				BaseOptions: opt,
+				opt:         gOpt,
+				duration:    cOpt.PerfDuration,
+				count:       cOpt.PerfCount,
+				interval:    cOpt.PerfInterval,
+				concurrency: cOpt.PerfConcurrency,
+				resultDir:   resultDir,
+				fileStats:   make(map[string][]CollectStat),
+				tlsCfg:      tlsCfg,
+			})
+	}
 
 	// todo: rename dir name to ops and move functions to log.go
 	if canCollect(&cOpt.Collectors.Log.Ops) {
@@ -484,6 +565,7 @@ func (m *Manager) CollectClusterInfo(
 		collectors = append(collectors,
 			&DebugCollectOptions{
 				BaseOptions: opt,
+				collector:   cOpt.Collectors.Debug,
 				opt:         gOpt,
 				resultDir:   resultDir,
 				fileStats:   make(map[string][]CollectStat),
@@ -501,6 +583,7 @@ func (m *Manager) CollectClusterInfo(
 				resultDir:      resultDir,
 				sqls:           explainSqls,
 				tlsCfg:         tlsCfg,
+				sqlTLSCfg:      sqlTLSCfg,
 				tables:         make(map[table]struct{}),
 				views:          make(map[table]struct{}),
 				tablesAndViews: make(map[table]struct{}),
@@ -512,6 +595,8 @@ func (m *Manager) CollectClusterInfo(
 	// run collectors
 	prepareErrs := make(map[string]error)
 	stats := make([]map[string][]CollectStat, 0)
+	jobs := make([]*collectJob, 0, len(collectors))
+	var metaJob *collectJob
 	for _, c := range collectors {
 		m.logger.Infof("Detecting %s...\n", c.Desc())
 		stat, err := c.Prepare(m, cls)
@@ -525,6 +610,36 @@ func (m *Manager) CollectClusterInfo(
 		}
 		defer c.Close()
 		stats = append(stats, stat)
+
+		// cluster metadata is always collected before any other data
+		if _, ok := c.(*MetaCollectOptions); ok {
+			metaJob = newCollectJob(c, stat)
+			jobs = append(jobs, metaJob)
+			continue
+		}
+		if metaJob != nil {
+			jobs = append(jobs, newCollectJob(c, stat, metaJob))
+		} else {
+			jobs = append(jobs, newCollectJob(c, stat))
+		}
+	}
+
+	if cOpt.DryRun {
+		plan := &CollectPlan{
+			Cluster: opt.Cluster,
+			Dir:     resultDir,
+			Begin:   opt.ScrapeBegin,
+			End:     opt.ScrapeEnd,
+		}
+		for i, c := range collectors {
+			plan.AddCollector(c.Desc(), stats[i], prepareErrs[c.Desc()], cOpt.Limit)
+		}
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		fmt.Println(string(data))
+		return "", nil
 	}
 
 	// confirm before really collect
@@ -542,6 +657,10 @@ func (m *Manager) CollectClusterInfo(
 	}
 
 	m.collectLock(resultDir)
+	m.progress, err = loadCollectProgress(resultDir)
+	if err != nil {
+		return "", err
+	}
 
 	logFile := initLogFile(filepath.Join(resultDir, "diag.log"), m.logger)
 	defer logFile.Close()
@@ -550,17 +669,30 @@ func (m *Manager) CollectClusterInfo(
 
 	// run collectors
 	collectErrs := make(map[string]error)
-	for _, c := range collectors {
+	errMu := sync.Mutex{}
+	scheduler := newCollectScheduler(cOpt.Concurrency, cOpt.HostConcurrency, cOpt.ExitOnError)
+	if err := scheduler.Run(jobs, func(c Collector) error {
+		if m.progress.CollectorDone(c.Desc()) {
+			m.logger.Infof("Skip collecting %s, it is already finished.\n", c.Desc())
+			return nil
+		}
 		fmt.Printf("Collecting %s...\n", c.Desc())
 		m.logger.Infof("Collecting %s...\n", c.Desc())
 		if err := c.Collect(m, cls); err != nil {
 			if cOpt.ExitOnError {
-				return "", err
+				return err
 			}
 			msg := fmt.Sprintf("Error collecting %s: %s, the data might be incomplete.", c.Desc(), err)
 			m.logger.Warnf("%s", color.YellowString(msg))
+			errMu.Lock()
 			collectErrs[c.Desc()] = err
+			errMu.Unlock()
+			return nil
 		}
+		m.progress.FinishCollector(c.Desc())
+		return nil
+	}); err != nil {
+		return "", err
 	}
 
 	if len(collectErrs) > 0 {
@@ -608,7 +740,7 @@ func (m *Manager) getOutputDir(dir, clusterName string) (string, error) {
 		if err != nil {
 			return dir, err
 		}
-		if len(readdir) == 0 {
+		if len(readdir) == 0 || m.resume {
 			return dir, nil
 		}
 		return dir, fmt.Errorf("%s is not an empty directory", dir)
@@ -686,7 +818,7 @@ func canCollect(w interface{}) bool {
 }
 
 func needDBKey(c CollectTree) bool {
-	return c.SQL_Bind || c.DB_Vars || c.Plan_Replayer
+	return c.SQL_Bind || c.DB_Vars || c.Plan_Replayer || c.SQL_Snapshot
 }
 
 func ParseCollectTree(include, exclude []string) (CollectTree, error) {
@@ -86,6 +86,9 @@ func (c *ComponentMetaCollectOptions) Prepare(m *Manager, topo *models.TiDBClust
 			c.fileStats[inst.Host()] = append(c.fileStats[inst.Host()], CollectStat{
 				Target: fmt.Sprintf("%s:%d %s component_meta", inst.Host(), inst.MainPort(), inst.Type()),
 				Size:   (10 * 1024),
+				Attributes: map[string]interface{}{
+					StatAttrAPICalls: 1,
+				},
 			})
 		default:
 			// pass
@@ -17,11 +17,13 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/joomcode/errorx"
+	json "github.com/json-iterator/go"
 	"github.com/pingcap/diag/pkg/models"
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
@@ -48,6 +50,7 @@ type ConfigCollectOptions struct {
 	fileStats  map[string][]CollectStat
 	compress   bool
 	tlsCfg     *tls.Config
+	dryRun     bool // list the config paths without running the scraper on hosts
 }
 
 // Desc implements the Collector interface
@@ -168,6 +171,9 @@ func (c *ConfigCollectOptions) prepareForTiUP(m *Manager, cls *models.TiDBCluste
 			hostPaths[inst.GetHost()].Insert(fmt.Sprintf("%s/conf/*", inst.DeployDir()))
 		}
 	}
+	if c.dryRun {
+		return planHostPaths(hostPaths, "config files"), nil
+	}
 
 	// build scraper tasks
 	for h, t := range hostTasks {
@@ -244,6 +250,7 @@ func (c *ConfigCollectOptions) Collect(m *Manager, cls *models.TiDBCluster) erro
 	comps = models.FilterComponent(comps, roleFilter)
 	instances := models.FilterInstance(comps, nodeFilter)
 	if c.Collectors.Runtime {
+		queried := make(map[models.ComponentType]bool)
 		for _, inst := range instances {
 			switch inst.Type() {
 			case models.ComponentTypeMonitor,
@@ -251,8 +258,11 @@ func (c *ConfigCollectOptions) Collect(m *Manager, cls *models.TiDBCluster) erro
 				continue
 			}
 
-			// query realtime configs for each instance if supported
-			if t3 := buildRealtimeConfigCollectingTasks(ctx, inst, c.resultDir, c.tlsCfg); t3 != nil {
+			// query realtime configs for each instance if supported, configs
+			// of the cluster are queried from the first instance only
+			clusterWide := !queried[inst.Type()]
+			queried[inst.Type()] = true
+			if t3 := buildRealtimeConfigCollectingTasks(ctx, inst, c.resultDir, c.tlsCfg, clusterWide); t3 != nil {
 				queryTasks = append(queryTasks, t3)
 			}
 		}
@@ -335,8 +345,25 @@ type rtConfig struct {
 	url      string
 }
 
-func buildRealtimeConfigCollectingTasks(ctx context.Context, inst models.Component, resultDir string, tlsCfg *tls.Config) *task.StepDisplay {
-	var configs []rtConfig
+// newRtConfigs creates configs of {file name, API path} pairs of an address
+func newRtConfigs(addr string, apis [][2]string) []rtConfig {
+	configs := make([]rtConfig, 0, len(apis))
+	for _, api := range apis {
+		configs = append(configs, rtConfig{api[0], addr + api[1]})
+	}
+	return configs
+}
+
+// buildRealtimeConfigCollectingTasks queries configs of the instance, with
+// clusterWide, configs shared by all instances of the component, such as
+// changefeeds of TiCDC, are queried as well
+func buildRealtimeConfigCollectingTasks(ctx context.Context, inst models.Component, resultDir string, tlsCfg *tls.Config, clusterWide bool) *task.StepDisplay {
+	var (
+		configs []rtConfig
+		// APIs may be missing in some versions or disabled, errors of them
+		// are ignored
+		optionals []rtConfig
+	)
 	scheme := "http"
 	if tlsCfg != nil {
 		scheme = "https"
@@ -347,12 +374,43 @@ func buildRealtimeConfigCollectingTasks(ctx context.Context, inst models.Compone
 		configs = append(configs, rtConfig{"config.json", inst.ConfigURL()})
 		configs = append(configs, rtConfig{"store.json", fmt.Sprintf("%s/pd/api/v1/stores", inst.StatusURL())})
 		configs = append(configs, rtConfig{"placement-rule.json", fmt.Sprintf("%s/pd/api/v1/config/placement-rule", inst.StatusURL())})
+		optionals = newRtConfigs(inst.StatusURL(), [][2]string{
+			{"schedule-config.json", "/pd/api/v1/config/schedule"},
+			{"replication-config.json", "/pd/api/v1/config/replicate"},
+			{"replication-mode.json", "/pd/api/v1/replication_mode/status"},
+			// the list of running schedulers is collected by debug.pd
+			{"scheduler-config.json", "/pd/api/v1/scheduler-config"},
+			{"region-label-rule.json", "/pd/api/v1/config/region-label/rules"},
+		})
 	case models.ComponentTypeTiKV:
 		configs = append(configs, rtConfig{"config.json", fmt.Sprintf("%s?full=true", inst.ConfigURL())})
 	case models.ComponentTypeTiDB:
 		configs = append(configs, rtConfig{"config.json", inst.ConfigURL()})
 	case models.ComponentTypeTiFlash:
 		configs = append(configs, rtConfig{"config.json", inst.ConfigURL()})
+	case models.ComponentTypeTiCDC:
+		if !clusterWide {
+			return nil
+		}
+		// configs of each changefeed are queried after the list
+		optionals = newRtConfigs(inst.StatusURL(), [][2]string{
+			{"changefeeds.json", "/api/v2/changefeeds"},
+		})
+	case models.ComponentTypeDMMaster:
+		if !clusterWide {
+			return nil
+		}
+		// the OpenAPI of DM is served on the main port
+		addr := fmt.Sprintf("%s:%d", inst.Host(), inst.MainPort())
+		if m, ok := inst.(*models.DMMasterSpec); ok && m.Domain() != "" {
+			addr = fmt.Sprintf("%s:%d", m.Domain(), inst.MainPort())
+		}
+		optionals = newRtConfigs(addr, [][2]string{
+			{"sources.json", "/api/v1/sources?with_status=false"},
+			{"tasks.json", "/api/v1/tasks?with_status=false"},
+		})
+	case models.ComponentTypeDMWorker:
+		optionals = append(optionals, rtConfig{"config.json", inst.ConfigURL()})
 	default:
 		// not supported yet, just ignore
 		return nil
@@ -367,6 +425,7 @@ func buildRealtimeConfigCollectingTasks(ctx context.Context, inst models.Compone
 	if pod, ok := inst.Attributes()["pod"].(string); ok {
 		host = pod
 	}
+	confDir := filepath.Join(resultDir, host, instDir, "conf")
 
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 	t := task.NewBuilder(logger).
@@ -376,12 +435,21 @@ func buildRealtimeConfigCollectingTasks(ctx context.Context, inst models.Compone
 				c := utils.NewHTTPClient(time.Second*3, tlsCfg)
 				for _, config := range configs {
 					url := fmt.Sprintf("%s://%s", scheme, config.url)
-					err := c.Download(ctx, url, filepath.Join(resultDir, host, instDir, "conf", config.filename))
+					err := c.Download(ctx, url, filepath.Join(confDir, config.filename))
 					if err != nil {
 						logger.Warnf("fail querying config %s: %s, continue", url, err)
 						return err
 					}
 				}
+				for _, config := range optionals {
+					url := fmt.Sprintf("%s://%s", scheme, config.url)
+					if err := saveOptionalConfig(ctx, c, url, filepath.Join(confDir, config.filename)); err != nil {
+						logger.Warnf("fail querying config %s: %s, continue", url, err)
+					}
+				}
+				if inst.Type() == models.ComponentTypeTiCDC {
+					collectChangefeedConfigs(ctx, c, fmt.Sprintf("%s://%s", scheme, inst.StatusURL()), confDir, logger)
+				}
 				return nil
 			},
 		).
@@ -394,3 +462,60 @@ func buildRealtimeConfigCollectingTasks(ctx context.Context, inst models.Compone
 
 	return t
 }
+
+// saveOptionalConfig saves the response of an optional API, nothing is saved
+// if the API is not available
+func saveOptionalConfig(ctx context.Context, c *utils.HTTPClient, url, fp string) error {
+	data, err := c.Get(ctx, url)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fp, data, 0644)
+}
+
+// changefeedIDs parses the changefeed list returned by the v2 API of TiCDC
+func changefeedIDs(data []byte) ([][2]string, error) {
+	var list struct {
+		Items []struct {
+			ID        string `json:"id"`
+			Namespace string `json:"namespace"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	ids := make([][2]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		ns := item.Namespace
+		if ns == "" {
+			ns = "default"
+		}
+		ids = append(ids, [2]string{ns, item.ID})
+	}
+	return ids, nil
+}
+
+// collectChangefeedConfigs queries the config of each changefeed in the
+// collected list, they are saved as changefeed-{namespace}-{id}.json
+func collectChangefeedConfigs(ctx context.Context, c *utils.HTTPClient, baseURL, confDir string, logger *logprinter.Logger) {
+	data, err := os.ReadFile(filepath.Join(confDir, "changefeeds.json"))
+	if err != nil {
+		// the list is not collected
+		return
+	}
+	ids, err := changefeedIDs(data)
+	if err != nil {
+		logger.Warnf("fail parsing changefeeds: %s, continue", err)
+		return
+	}
+	for _, id := range ids {
+		url := fmt.Sprintf("%s/api/v2/changefeeds/%s?namespace=%s", baseURL, id[1], id[0])
+		fp := filepath.Join(confDir, fmt.Sprintf("changefeed-%s-%s.json", id[0], id[1]))
+		if err := saveOptionalConfig(ctx, c, url, fp); err != nil {
+			logger.Warnf("fail querying config %s: %s, continue", url, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/diag/pkg/models"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealtimeConfigTiCDC(t *testing.T) {
+	assert := require.New(t)
+
+	cdc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/changefeeds":
+			_, _ = w.Write([]byte(`{"total":2,"items":[{"id":"cf1","namespace":"default"},{"id":"cf2"}]}`))
+		case "/api/v2/changefeeds/cf1":
+			fmt.Fprintf(w, `{"id":"cf1","namespace":"%s"}`, r.URL.Query().Get("namespace"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer cdc.Close()
+
+	host, port, _ := strings.Cut(strings.TrimPrefix(cdc.URL, "http://"), ":")
+	inst := &models.TiCDCSpec{ComponentSpec: models.ComponentSpec{
+		Host:       host,
+		Attributes: models.AttributeMap{"deploy_dir": "cdc"},
+	}}
+	_, _ = fmt.Sscan(port, &inst.ComponentSpec.Port)
+
+	dir := t.TempDir()
+	ctx := ctxt.New(context.Background(), 1, logprinter.NewLogger(""))
+	// changefeeds are only queried from one capture
+	assert.Nil(buildRealtimeConfigCollectingTasks(ctx, inst, dir, nil, false))
+	step := buildRealtimeConfigCollectingTasks(ctx, inst, dir, nil, true)
+	assert.NotNil(step)
+	// the missing changefeed is ignored
+	assert.Nil(step.Execute(ctx))
+
+	confDir := filepath.Join(dir, host, "cdc", "conf")
+	data, err := os.ReadFile(filepath.Join(confDir, "changefeed-default-cf1.json"))
+	assert.Nil(err)
+	assert.Equal(`{"id":"cf1","namespace":"default"}`, string(data))
+	_, err = os.Stat(filepath.Join(confDir, "changefeed-default-cf2.json"))
+	assert.True(os.IsNotExist(err))
+
+	// not supported components
+	assert.Nil(buildRealtimeConfigCollectingTasks(ctx, &models.PumpSpec{}, dir, nil, true))
+}
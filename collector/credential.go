@@ -0,0 +1,125 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/tui"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// environment variables of the TiDB credential
+const (
+	EnvNameDBUser     = "DIAG_DB_USER"
+	EnvNameDBPassword = "DIAG_DB_PASSWORD"
+)
+
+// readCredentialFile reads user and password from a file in the format of
+// MySQL option files, only the [client] section or keys without a section
+// are read, e.g.:
+//
+//	[client]
+//	user = root
+//	password = "secret"
+func readCredentialFile(fp string) (user, passwd string, err error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	section := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != "" && section != "client" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		switch strings.TrimSpace(key) {
+		case "user":
+			user = value
+		case "password":
+			passwd = value
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", "", err
+	}
+	if user == "" {
+		return "", "", fmt.Errorf("no user found in credential file %s", fp)
+	}
+	return user, passwd, nil
+}
+
+// credentialFromSecret reads user and password from a kubernetes secret,
+// the keys are "user" and "password", or the secret of TidbInitializer whose
+// key is "root" and the value is the password
+func credentialFromSecret(kubeCli kubernetes.Interface, namespace, name string, timeout time.Duration) (user, passwd string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	secret, err := kubeCli.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("unable to load credential from secret %s/%s: %v", namespace, name, err)
+	}
+	if u, ok := secret.Data["user"]; ok {
+		return string(u), string(secret.Data["password"]), nil
+	}
+	if p, ok := secret.Data["root"]; ok {
+		return "root", string(p), nil
+	}
+	return "", "", fmt.Errorf("no user found in secret %s/%s", namespace, name)
+}
+
+// dbCredential returns the user and password to connect to TiDB, they are
+// read from the credential file, the kubernetes secret or the environment
+// variables, and prompted if none is set
+func dbCredential(cOpt *CollectOptions, kubeCli *kubernetes.Clientset, namespace string, timeout time.Duration) (user, passwd string, err error) {
+	switch {
+	case cOpt.DBCredentialFile != "":
+		return readCredentialFile(cOpt.DBCredentialFile)
+	case cOpt.DBSecret != "":
+		if kubeCli == nil {
+			return "", "", fmt.Errorf("secret %s could only be used for tidb-operator deployed clusters", cOpt.DBSecret)
+		}
+		return credentialFromSecret(kubeCli, namespace, cOpt.DBSecret, timeout)
+	case os.Getenv(EnvNameDBUser) != "":
+		return os.Getenv(EnvNameDBUser), os.Getenv(EnvNameDBPassword), nil
+	}
+	fmt.Print("please enter database username:")
+	fmt.Scanln(&user)
+	passwd = tui.PromptForPassword("please enter database password:")
+	return user, passwd, nil
+}
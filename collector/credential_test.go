@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCredentialFile(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	fp := filepath.Join(dir, "my.cnf")
+	assert.Nil(os.WriteFile(fp, []byte(`
+# comment
+[mysqld]
+user = mysql
+
+[client]
+user = "diag"
+password = 'p@ss=word'
+`), 0600))
+	user, passwd, err := readCredentialFile(fp)
+	assert.Nil(err)
+	assert.Equal("diag", user)
+	assert.Equal("p@ss=word", passwd)
+
+	fp = filepath.Join(dir, "plain")
+	assert.Nil(os.WriteFile(fp, []byte("user=root\n"), 0600))
+	user, passwd, err = readCredentialFile(fp)
+	assert.Nil(err)
+	assert.Equal("root", user)
+	assert.Equal("", passwd)
+
+	fp = filepath.Join(dir, "nouser")
+	assert.Nil(os.WriteFile(fp, []byte("[mysqld]\nuser = mysql\n"), 0600))
+	_, _, err = readCredentialFile(fp)
+	assert.NotNil(err)
+}
+
+func TestDBCredentialFromEnv(t *testing.T) {
+	assert := require.New(t)
+	t.Setenv(EnvNameDBUser, "diag")
+	t.Setenv(EnvNameDBPassword, "secret")
+
+	user, passwd, err := dbCredential(&CollectOptions{}, nil, "", time.Second)
+	assert.Nil(err)
+	assert.Equal("diag", user)
+	assert.Equal("secret", passwd)
+
+	_, _, err = dbCredential(&CollectOptions{DBSecret: "diag"}, nil, "", time.Second)
+	assert.NotNil(err)
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/joomcode/errorx"
+	json "github.com/json-iterator/go"
 	"github.com/pingcap/diag/pkg/models"
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
@@ -31,13 +32,24 @@ import (
 	"github.com/pingcap/tiup/pkg/utils"
 )
 
+type collectDebug struct {
+	PD      bool
+	TiDB    bool
+	TiKV    bool
+	TiFlash bool
+	TiCDC   bool
+}
+
 // DebugCollectOptions are options used collecting debug info
 type DebugCollectOptions struct {
 	*BaseOptions
+	collector collectDebug
 	opt       *operator.Options // global operations from cli
 	resultDir string
 	fileStats map[string][]CollectStat
 	tlsCfg    *tls.Config
+	storeIDs  map[string]uint64 // store ids of TiKV and TiFlash by status address
+	pdAddr    string            // status address of the PD the store ids are queried from
 }
 
 // Desc implements the Collector interface
@@ -65,34 +77,61 @@ func (c *DebugCollectOptions) SetDir(dir string) {
 	c.resultDir = dir
 }
 
-// Prepare implements the Collector interface
-func (c *DebugCollectOptions) Prepare(m *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
-	switch m.mode {
-	case CollectModeTiUP:
-		return c.prepareForTiUP(m, topo)
+// debugEnabled checks if the debug info of the component type is collected
+func (c *DebugCollectOptions) debugEnabled(typ models.ComponentType) bool {
+	switch typ {
+	case models.ComponentTypePD:
+		return c.collector.PD
+	case models.ComponentTypeTiDB:
+		return c.collector.TiDB
+	case models.ComponentTypeTiKV:
+		return c.collector.TiKV
+	case models.ComponentTypeTiFlash:
+		return c.collector.TiFlash
+	case models.ComponentTypeTiCDC:
+		return c.collector.TiCDC
 	}
-	return nil, nil
+	return false
 }
 
-// // prepareForTiUP implements preparation for tiup-cluster deployed clusters
-func (c *DebugCollectOptions) prepareForTiUP(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+// instances returns the instances to collect debug info from
+func (c *DebugCollectOptions) instances(topo *models.TiDBCluster) []models.Component {
 	// filter nodes or roles
 	roleFilter := set.NewStringSet(c.opt.Roles...)
 	nodeFilter := set.NewStringSet(c.opt.Nodes...)
 	comps := topo.Components()
 	comps = models.FilterComponent(comps, roleFilter)
-	instances := models.FilterInstance(comps, nodeFilter)
+	instances := make([]models.Component, 0)
+	for _, inst := range models.FilterInstance(comps, nodeFilter) {
+		if c.debugEnabled(inst.Type()) {
+			instances = append(instances, inst)
+		}
+	}
+	return instances
+}
 
-	for _, inst := range instances {
+// Prepare implements the Collector interface, the estimation is the same
+// for all deploy modes as debug info is always queried from the status API
+func (c *DebugCollectOptions) Prepare(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+	for _, inst := range c.instances(topo) {
+		var size int64
 		switch inst.Type() {
 		case models.ComponentTypeTiCDC:
-			stat := CollectStat{
-				Target: fmt.Sprintf("%s:%d %s debug", inst.Host(), inst.MainPort(), inst.Type()),
-				Size:   1024 * 5 * 5,
-			}
-
-			c.fileStats[inst.Host()] = append(c.fileStats[inst.Host()], stat)
+			size = 1024 * 5 * 5
+		case models.ComponentTypePD:
+			size = 1024 * 50 * 12
+		case models.ComponentTypeTiDB:
+			size = 1024 * 1024
+		case models.ComponentTypeTiKV, models.ComponentTypeTiFlash:
+			// mostly the metadata of regions in the store
+			size = 1024 * 1024 * 10
+		}
+		stat := CollectStat{
+			Target: fmt.Sprintf("%s:%d %s debug", inst.Host(), inst.MainPort(), inst.Type()),
+			Size:   size,
 		}
+
+		c.fileStats[inst.Host()] = append(c.fileStats[inst.Host()], stat)
 	}
 
 	return c.fileStats, nil
@@ -106,17 +145,18 @@ func (c *DebugCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) erro
 		m.logger,
 	)
 
-	collecteDebugTasks := []*task.StepDisplay{}
+	if (c.collector.TiKV && len(topo.TiKV) > 0) || (c.collector.TiFlash && len(topo.TiFlash) > 0) {
+		storeIDs, err := c.getStoreIDs(ctx, topo)
+		if err != nil {
+			m.logger.Warnf("failed to get stores from PD, region info of stores will not be collected: %s", err)
+		}
+		c.storeIDs = storeIDs
+	}
 
-	// filter nodes or roles
-	roleFilter := set.NewStringSet(c.opt.Roles...)
-	nodeFilter := set.NewStringSet(c.opt.Nodes...)
-	comps := topo.Components()
-	comps = models.FilterComponent(comps, roleFilter)
-	instances := models.FilterInstance(comps, nodeFilter)
+	collecteDebugTasks := []*task.StepDisplay{}
 
 	// build tsaks
-	for _, inst := range instances {
+	for _, inst := range c.instances(topo) {
 		if t := buildDebugCollectingTasks(ctx, inst, c); len(t) != 0 {
 			collecteDebugTasks = append(collecteDebugTasks, t...)
 		}
@@ -136,11 +176,66 @@ func (c *DebugCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) erro
 	return nil
 }
 
+func (c *DebugCollectOptions) scheme() string {
+	if c.tlsCfg != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// getStoreIDs queries stores from the first available PD, the ids are
+// mapped by status address
+func (c *DebugCollectOptions) getStoreIDs(ctx context.Context, topo *models.TiDBCluster) (map[string]uint64, error) {
+	if len(topo.PD) < 1 {
+		return nil, fmt.Errorf("no PD found in topology")
+	}
+	client := utils.NewHTTPClient(time.Second*15, c.tlsCfg)
+	var lastErr error
+	for _, pd := range topo.PD {
+		data, err := client.Get(ctx, fmt.Sprintf("%s://%s/pd/api/v1/stores", c.scheme(), pd.StatusURL()))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var stores struct {
+			Stores []struct {
+				Store struct {
+					ID            uint64 `json:"id"`
+					StatusAddress string `json:"status_address"`
+				} `json:"store"`
+			} `json:"stores"`
+		}
+		if err := json.Unmarshal(data, &stores); err != nil {
+			return nil, err
+		}
+		ids := make(map[string]uint64)
+		for _, s := range stores.Stores {
+			ids[s.Store.StatusAddress] = s.Store.ID
+		}
+		c.pdAddr = pd.StatusURL()
+		return ids, nil
+	}
+	return nil, lastErr
+}
+
 type debugConfig struct {
 	filepath string
 	url      string
 }
 
+// newDebugConfigs creates configs of {file name, API path} pairs of a
+// status address
+func newDebugConfigs(dir, statusURL string, apis [][2]string) []debugConfig {
+	configs := make([]debugConfig, 0, len(apis))
+	for _, api := range apis {
+		configs = append(configs, debugConfig{
+			filepath: filepath.Join(dir, api[0]),
+			url:      statusURL + api[1],
+		})
+	}
+	return configs
+}
+
 // buildDebugCollectingTasks build collect debug information tasks
 func buildDebugCollectingTasks(ctx context.Context, inst models.Component, c *DebugCollectOptions) []*task.StepDisplay {
 	var (
@@ -158,69 +253,113 @@ func buildDebugCollectingTasks(ctx context.Context, inst models.Component, c *De
 		host = pod
 	}
 
+	debugDir := filepath.Join(c.resultDir, host, instDir, CollectTypeDebug)
 	switch inst.Type() {
 	case models.ComponentTypeTiCDC:
 		// /debug/info
 		debugConfigs = append(debugConfigs,
 			debugConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypeDebug, "info.txt"),
+				filepath: filepath.Join(debugDir, "info.txt"),
 				url:      fmt.Sprintf("%s/debug/info", inst.StatusURL()),
 			})
 
 		// /status
 		debugConfigs = append(debugConfigs,
 			debugConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypeDebug, "status.txt"),
+				filepath: filepath.Join(debugDir, "status.txt"),
 				url:      fmt.Sprintf("%s/status", inst.StatusURL()),
 			})
 
 		// changefeeds
 		debugConfigs = append(debugConfigs,
 			debugConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypeDebug, "changefeeds.txt"),
+				filepath: filepath.Join(debugDir, "changefeeds.txt"),
 				url:      fmt.Sprintf("%s/api/v1/changefeeds", inst.StatusURL()),
 			})
 
 		// captures
 		debugConfigs = append(debugConfigs,
 			debugConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypeDebug, "captures.txt"),
+				filepath: filepath.Join(debugDir, "captures.txt"),
 				url:      fmt.Sprintf("%s/api/v1/captures", inst.StatusURL()),
 			})
 
 		// processors
 		debugConfigs = append(debugConfigs,
 			debugConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypeDebug, "processors.txt"),
+				filepath: filepath.Join(debugDir, "processors.txt"),
 				url:      fmt.Sprintf("%s/api/v1/processors", inst.StatusURL()),
 			})
 
+	case models.ComponentTypePD:
+		debugConfigs = append(debugConfigs, newDebugConfigs(debugDir, inst.StatusURL(), [][2]string{
+			{"members.json", "/pd/api/v1/members"},
+			{"health.json", "/pd/api/v1/health"},
+			// stores and the scheduler config are collected by config.runtime
+			{"region_stats.json", "/pd/api/v1/stats/region"},
+			{"regions_miss_peer.json", "/pd/api/v1/regions/check/miss-peer"},
+			{"regions_pending_peer.json", "/pd/api/v1/regions/check/pending-peer"},
+			{"regions_down_peer.json", "/pd/api/v1/regions/check/down-peer"},
+			{"hot_read_regions.json", "/pd/api/v1/hotspot/regions/read"},
+			{"hot_write_regions.json", "/pd/api/v1/hotspot/regions/write"},
+			{"schedulers.json", "/pd/api/v1/schedulers"},
+			{"operators.json", "/pd/api/v1/operators"},
+		})...)
+
+	case models.ComponentTypeTiDB:
+		debugConfigs = append(debugConfigs, newDebugConfigs(debugDir, inst.StatusURL(), [][2]string{
+			{"status.json", "/status"},
+			{"info.json", "/info"},
+			{"schema_versions.json", "/info/all"}, // schema versions of all TiDB servers
+			{"ddl_history.json", "/ddl/history"},
+			{"settings.json", "/settings"},
+		})...)
+
+	case models.ComponentTypeTiKV, models.ComponentTypeTiFlash:
+		debugConfigs = append(debugConfigs, newDebugConfigs(debugDir, inst.StatusURL(), [][2]string{
+			{"status.json", "/status"},
+		})...)
+		if inst.Type() == models.ComponentTypeTiKV {
+			debugConfigs = append(debugConfigs, newDebugConfigs(debugDir, inst.StatusURL(), [][2]string{
+				{"fail_points.txt", "/debug/fail_point"},
+			})...)
+		}
+
+		// metadata of regions in the store, queried from PD
+		if id, ok := c.storeIDs[inst.StatusURL()]; ok {
+			debugConfigs = append(debugConfigs,
+				debugConfig{
+					filepath: filepath.Join(debugDir, "regions.json"),
+					url:      fmt.Sprintf("%s/pd/api/v1/regions/store/%d", c.pdAddr, id),
+				})
+		}
 	default:
 		// not supported yet, just ignore
 		return nil
 	}
 
-	scheme := "http"
-	if c.tlsCfg != nil {
-		scheme = "https"
-	}
-
+	scheme := c.scheme()
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 
 	t := task.NewBuilder(logger).
 		Func(
 			fmt.Sprintf("querying %s:%d", host, inst.MainPort()),
 			func(ctx context.Context) error {
+				// APIs may be missing in some versions, so go on querying
+				// others on errors
+				var firstErr error
 				c := utils.NewHTTPClient(time.Second*15, c.tlsCfg)
 				for _, config := range debugConfigs {
 					url := fmt.Sprintf("%s://%s", scheme, config.url)
 					err := c.Download(ctx, url, config.filepath)
 					if err != nil {
 						logger.Warnf("fail querying debug info %s: %s, continue", url, err)
-						return err
+						if firstErr == nil {
+							firstErr = err
+						}
 					}
 				}
-				return nil
+				return firstErr
 			},
 		).
 		BuildAsStep(fmt.Sprintf(
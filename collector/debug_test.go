@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/diag/pkg/models"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDebugCollectors(t *testing.T) {
+	assert := require.New(t)
+
+	tree, err := ParseCollectTree([]string{"debug.pd", "debug.TiDB"}, nil)
+	assert.Nil(err)
+	assert.Equal(collectDebug{PD: true, TiDB: true}, tree.Debug)
+	assert.Equal([]string{"debug.pd", "debug.tidb"}, tree.List())
+
+	tree, err = ParseCollectTree([]string{"debug"}, []string{"debug.ticdc"})
+	assert.Nil(err)
+	assert.Equal(collectDebug{PD: true, TiDB: true, TiKV: true, TiFlash: true}, tree.Debug)
+
+	_, err = ParseCollectTree([]string{"debug.tso"}, nil)
+	assert.NotNil(err)
+}
+
+func TestDebugCollect(t *testing.T) {
+	assert := require.New(t)
+
+	tikv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tikv " + r.URL.Path))
+	}))
+	defer tikv.Close()
+	tikvAddr := strings.TrimPrefix(tikv.URL, "http://")
+	pd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pd/api/v1/stores" {
+			fmt.Fprintf(w, `{"count":1,"stores":[{"store":{"id":4,"status_address":"%s"}}]}`, tikvAddr)
+			return
+		}
+		_, _ = w.Write([]byte("pd " + r.URL.Path))
+	}))
+	defer pd.Close()
+
+	spec := func(url, dir string) models.ComponentSpec {
+		host, port, _ := strings.Cut(strings.TrimPrefix(url, "http://"), ":")
+		cs := models.ComponentSpec{Host: host, Attributes: models.AttributeMap{"deploy_dir": dir}}
+		_, _ = fmt.Sscan(port, &cs.StatusPort)
+		return cs
+	}
+	topo := &models.TiDBCluster{
+		PD:   []*models.PDSpec{{ComponentSpec: spec(pd.URL, "pd")}},
+		TiKV: []*models.TiKVSpec{{ComponentSpec: spec(tikv.URL, "tikv")}},
+	}
+	m := &Manager{logger: logprinter.NewLogger("")}
+
+	dir := t.TempDir()
+	c := &DebugCollectOptions{
+		BaseOptions: &BaseOptions{},
+		collector:   collectDebug{PD: true, TiKV: true},
+		opt:         &operator.Options{Concurrency: 2},
+		resultDir:   dir,
+		fileStats:   make(map[string][]CollectStat),
+	}
+	stats, err := c.Prepare(m, topo)
+	assert.Nil(err)
+	assert.Len(stats["127.0.0.1"], 2)
+
+	assert.Nil(c.Collect(m, topo))
+	read := func(p ...string) string {
+		data, err := os.ReadFile(filepath.Join(append([]string{dir, "127.0.0.1"}, p...)...))
+		assert.Nil(err, p)
+		return string(data)
+	}
+	assert.Equal("pd /pd/api/v1/hotspot/regions/write", read("pd", CollectTypeDebug, "hot_write_regions.json"))
+	assert.Equal("tikv /status", read("tikv", CollectTypeDebug, "status.json"))
+	assert.Equal("tikv /debug/fail_point", read("tikv", CollectTypeDebug, "fail_points.txt"))
+	assert.Equal("pd /pd/api/v1/regions/store/4", read("tikv", CollectTypeDebug, "regions.json"))
+
+	// only enabled components
+	c.collector = collectDebug{TiDB: true}
+	assert.Len(c.instances(topo), 0)
+}
@@ -0,0 +1,317 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/pkg/models"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcd keys registered by TiDB servers and TiCDC captures
+const (
+	tidbTopologyKeyBase = "/topology/tidb/"
+	ticdcKeyBase        = "/tidb/cdc/"
+	ticdcCaptureKey     = "/capture/"
+)
+
+// discoverTopology adds instances of the cluster found from PD to the topo:
+// PD members from the members API, TiKV and TiFlash from the stores API, and
+// TiDB servers and TiCDC captures from the keys they register in etcd, the
+// instances already in the topo are kept.
+func discoverTopology(ctx context.Context, cls *models.TiDBCluster, endpoints []string, tlsCfg *tls.Config, timeout time.Duration) error {
+	logger, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+	if !ok {
+		return fmt.Errorf("no logger found in the context")
+	}
+	pdAPI := api.NewPDClient(ctx, endpoints, timeout, tlsCfg)
+	members, err := pdAPI.GetMembers()
+	if err != nil {
+		return fmt.Errorf("failed to get members of PD: %s", err)
+	}
+	stores, err := pdAPI.GetStores()
+	if err != nil {
+		return fmt.Errorf("failed to get stores from PD: %s", err)
+	}
+	cls.PD = mergeInstances(cls.PD, pdSpecsFromMembers(members.Members))
+	tikv, tiflash := storeSpecs(stores)
+	cls.TiKV = mergeInstances(cls.TiKV, tikv)
+	cls.TiFlash = mergeInstances(cls.TiFlash, tiflash)
+
+	if cls.Version == "" || cls.Version == "unknown" {
+		for _, pd := range cls.PD {
+			if v, ok := pd.Attributes()["version"].(string); ok && v != "" {
+				cls.Version = v
+				break
+			}
+		}
+	}
+
+	etcdCli, err := cls.GetEtcdClient(tlsCfg)
+	if err != nil {
+		return err
+	}
+	defer etcdCli.Close()
+
+	etcdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if resp, err := etcdCli.Get(etcdCtx, tidbTopologyKeyBase, clientv3.WithPrefix()); err != nil {
+		logger.Warnf("failed to get TiDB servers from etcd: %s", err)
+	} else {
+		cls.TiDB = mergeInstances(cls.TiDB, tidbSpecsFromEtcd(resp.Kvs))
+	}
+	if resp, err := etcdCli.Get(etcdCtx, ticdcKeyBase, clientv3.WithPrefix()); err != nil {
+		logger.Warnf("failed to get TiCDC captures from etcd: %s", err)
+	} else {
+		cls.TiCDC = mergeInstances(cls.TiCDC, ticdcSpecsFromEtcd(resp.Kvs))
+	}
+	return nil
+}
+
+// normalizeVersion adds the "v" prefix used by tiup to a version
+func normalizeVersion(v string) string {
+	if v == "" || strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
+
+// splitAddr splits an address of host:port
+func splitAddr(addr string) (string, int, error) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port of address %s", addr)
+	}
+	return host, port, nil
+}
+
+// pdSpecsFromMembers converts members of PD to specs, the client URL is used
+// as both the main and status address
+func pdSpecsFromMembers(members []*pdpb.Member) []*models.PDSpec {
+	specs := make([]*models.PDSpec, 0, len(members))
+	for _, m := range members {
+		if len(m.ClientUrls) < 1 {
+			continue
+		}
+		u, err := url.Parse(m.ClientUrls[0])
+		if err != nil {
+			continue
+		}
+		host, port, err := splitAddr(u.Host)
+		if err != nil {
+			continue
+		}
+		specs = append(specs, &models.PDSpec{
+			ComponentSpec: models.ComponentSpec{
+				Host:       host,
+				Port:       port,
+				StatusPort: port,
+				Attributes: map[string]interface{}{
+					"name":       m.Name,
+					"id":         m.MemberId,
+					"client_url": m.ClientUrls[0],
+					"version":    normalizeVersion(m.BinaryVersion),
+				},
+			},
+		})
+	}
+	return specs
+}
+
+// storeSpecs converts stores of PD to TiKV and TiFlash specs, tombstone
+// stores are ignored
+func storeSpecs(stores *api.StoresInfo) ([]*models.TiKVSpec, []*models.TiFlashSpec) {
+	var (
+		tikv    []*models.TiKVSpec
+		tiflash []*models.TiFlashSpec
+	)
+	for _, s := range stores.Stores {
+		if s.Store == nil || s.Store.Store == nil || s.Store.State == metapb.StoreState_Tombstone {
+			continue
+		}
+		host, port, err := splitAddr(s.Store.Address)
+		if err != nil {
+			continue
+		}
+		_, statusPort, err := splitAddr(s.Store.StatusAddress)
+		if err != nil {
+			continue
+		}
+		cs := models.ComponentSpec{
+			Host:       host,
+			Port:       port,
+			StatusPort: statusPort,
+			Attributes: map[string]interface{}{
+				"id":      s.Store.Id,
+				"state":   s.Store.StateName,
+				"version": normalizeVersion(s.Store.Version),
+			},
+		}
+		isTiFlash := false
+		for _, l := range s.Store.Labels {
+			if l.Key == "engine" && strings.HasPrefix(l.Value, "tiflash") {
+				isTiFlash = true
+				break
+			}
+		}
+		if isTiFlash {
+			tiflash = append(tiflash, &models.TiFlashSpec{ComponentSpec: cs})
+		} else {
+			tikv = append(tikv, &models.TiKVSpec{ComponentSpec: cs})
+		}
+	}
+	return tikv, tiflash
+}
+
+// tidbTopologyInfo is the value of the info key registered by TiDB servers
+type tidbTopologyInfo struct {
+	Version    string `json:"version"`
+	GitHash    string `json:"git_hash"`
+	StatusPort int    `json:"status_port"`
+	DeployPath string `json:"deploy_path"`
+}
+
+// tidbSpecsFromEtcd converts the keys registered by TiDB servers to specs,
+// only servers alive, whose ttl keys exist, are returned
+func tidbSpecsFromEtcd(kvs []*mvccpb.KeyValue) []*models.TiDBSpec {
+	infos := make(map[string]tidbTopologyInfo)
+	alive := make(map[string]bool)
+	for _, kv := range kvs {
+		key := strings.TrimPrefix(string(kv.Key), tidbTopologyKeyBase)
+		addr, name, found := strings.Cut(key, "/")
+		if !found {
+			continue
+		}
+		switch name {
+		case "info":
+			var info tidbTopologyInfo
+			if err := json.Unmarshal(kv.Value, &info); err != nil {
+				continue
+			}
+			infos[addr] = info
+		case "ttl":
+			alive[addr] = true
+		}
+	}
+
+	addrs := make([]string, 0, len(infos))
+	for addr := range infos {
+		if alive[addr] {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+
+	specs := make([]*models.TiDBSpec, 0, len(addrs))
+	for _, addr := range addrs {
+		host, port, err := splitAddr(addr)
+		if err != nil {
+			continue
+		}
+		info := infos[addr]
+		// the version is like 8.0.11-TiDB-v7.5.0
+		version := info.Version
+		if i := strings.Index(version, "-TiDB-"); i >= 0 {
+			version = version[i+len("-TiDB-"):]
+		}
+		specs = append(specs, &models.TiDBSpec{
+			ComponentSpec: models.ComponentSpec{
+				Host:       host,
+				Port:       port,
+				StatusPort: info.StatusPort,
+				Attributes: map[string]interface{}{
+					"version":     normalizeVersion(version),
+					"git_hash":    info.GitHash,
+					"deploy_path": info.DeployPath,
+				},
+			},
+		})
+	}
+	return specs
+}
+
+// ticdcCaptureInfo is the value of the key registered by TiCDC captures
+type ticdcCaptureInfo struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	Version string `json:"version"`
+}
+
+// ticdcSpecsFromEtcd converts the keys registered by TiCDC captures to
+// specs, the keys are /tidb/cdc/capture/<id> before v6.2 and
+// /tidb/cdc/<cluster>/__cdc_meta__/capture/<id> since then
+func ticdcSpecsFromEtcd(kvs []*mvccpb.KeyValue) []*models.TiCDCSpec {
+	specs := make([]*models.TiCDCSpec, 0)
+	for _, kv := range kvs {
+		if !strings.Contains(string(kv.Key), ticdcCaptureKey) {
+			continue
+		}
+		var info ticdcCaptureInfo
+		if err := json.Unmarshal(kv.Value, &info); err != nil {
+			continue
+		}
+		host, port, err := splitAddr(info.Address)
+		if err != nil {
+			continue
+		}
+		specs = append(specs, &models.TiCDCSpec{
+			ComponentSpec: models.ComponentSpec{
+				Host:       host,
+				Port:       port,
+				StatusPort: port,
+				Attributes: map[string]interface{}{
+					"id":      info.ID,
+					"version": normalizeVersion(info.Version),
+				},
+			},
+		})
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].ID() < specs[j].ID()
+	})
+	return specs
+}
+
+// mergeInstances appends the discovered instances not in the list
+func mergeInstances[T models.Component](list, discovered []T) []T {
+	ids := make(map[string]struct{}, len(list))
+	for _, inst := range list {
+		ids[inst.ID()] = struct{}{}
+	}
+	for _, inst := range discovered {
+		if _, found := ids[inst.ID()]; !found {
+			list = append(list, inst)
+		}
+	}
+	return list
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/pingcap/diag/pkg/models"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestDiscoverPDAndStores(t *testing.T) {
+	assert := require.New(t)
+
+	pds := pdSpecsFromMembers([]*pdpb.Member{
+		{Name: "pd-1", ClientUrls: []string{"http://10.0.0.1:2379"}, BinaryVersion: "7.5.0"},
+		{Name: "pd-2"}, // no client url
+	})
+	assert.Len(pds, 1)
+	assert.Equal("10.0.0.1:2379", pds[0].ID())
+	assert.Equal(2379, pds[0].StatusPort())
+	assert.Equal("v7.5.0", pds[0].Attributes()["version"])
+
+	store := func(id uint64, addr, status string, state metapb.StoreState, labels ...*metapb.StoreLabel) *api.StoreInfo {
+		return &api.StoreInfo{Store: &api.MetaStore{Store: &metapb.Store{
+			Id: id, Address: addr, StatusAddress: status, State: state, Version: "7.5.0", Labels: labels,
+		}}}
+	}
+	tikv, tiflash := storeSpecs(&api.StoresInfo{Stores: []*api.StoreInfo{
+		store(1, "10.0.0.2:20160", "10.0.0.2:20180", metapb.StoreState_Up),
+		store(2, "10.0.0.3:20160", "10.0.0.3:20180", metapb.StoreState_Tombstone),
+		store(3, "10.0.0.4:3930", "10.0.0.4:20292", metapb.StoreState_Up, &metapb.StoreLabel{Key: "engine", Value: "tiflash"}),
+	}})
+	assert.Len(tikv, 1)
+	assert.Equal("10.0.0.2:20160", tikv[0].ID())
+	assert.Equal(20180, tikv[0].StatusPort())
+	assert.Len(tiflash, 1)
+	assert.Equal("10.0.0.4:3930", tiflash[0].ID())
+	assert.Equal(20292, tiflash[0].StatusPort())
+}
+
+func TestDiscoverFromEtcd(t *testing.T) {
+	assert := require.New(t)
+
+	tidb := tidbSpecsFromEtcd([]*mvccpb.KeyValue{
+		{Key: []byte("/topology/tidb/10.0.0.5:4000/info"), Value: []byte(`{"version":"8.0.11-TiDB-v7.5.0","status_port":10080}`)},
+		{Key: []byte("/topology/tidb/10.0.0.5:4000/ttl"), Value: []byte("1700000000000000000")},
+		// not alive
+		{Key: []byte("/topology/tidb/10.0.0.6:4000/info"), Value: []byte(`{"version":"8.0.11-TiDB-v7.5.0","status_port":10080}`)},
+	})
+	assert.Len(tidb, 1)
+	assert.Equal("10.0.0.5:4000", tidb[0].ID())
+	assert.Equal(10080, tidb[0].StatusPort())
+	assert.Equal("v7.5.0", tidb[0].Attributes()["version"])
+
+	cdc := ticdcSpecsFromEtcd([]*mvccpb.KeyValue{
+		{Key: []byte("/tidb/cdc/default/__cdc_meta__/capture/b"), Value: []byte(`{"id":"b","address":"10.0.0.8:8300","version":"v7.5.0"}`)},
+		{Key: []byte("/tidb/cdc/capture/a"), Value: []byte(`{"id":"a","address":"10.0.0.7:8300","version":"v6.1.0"}`)},
+		{Key: []byte("/tidb/cdc/default/default/changefeed/info/cf"), Value: []byte(`{}`)},
+	})
+	assert.Len(cdc, 2)
+	assert.Equal("10.0.0.7:8300", cdc[0].ID())
+	assert.Equal(8300, cdc[0].StatusPort())
+	assert.Equal("10.0.0.8:8300", cdc[1].ID())
+
+	// input instances are kept
+	merged := mergeInstances([]*models.TiDBSpec{{ComponentSpec: models.ComponentSpec{Host: "10.0.0.5", Port: 4000}}}, tidb)
+	assert.Len(merged, 1)
+	assert.Equal(0, merged[0].StatusPort())
+}
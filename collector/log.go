@@ -14,6 +14,7 @@
 package collector
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -41,6 +42,9 @@ import (
 const (
 	// componentDiagCollector is the component name of diagnostic collector
 	componentDiagCollector = "diag"
+	// attrKeyLogSlice is the attribute of a log file to be sliced on the
+	// remote host before fetching, holding the path of the slice
+	attrKeyLogSlice = "slice"
 )
 
 type collectLog struct {
@@ -61,6 +65,10 @@ type LogCollectOptions struct {
 	fileStats map[string][]CollectStat
 	compress  bool
 	kubeCli   *kubernetes.Clientset
+	slice     bool     // fetch slices of logs in the time range instead of whole files
+	levels    []string // levels of log entries to keep in slices
+	keywords  []string // keywords of log entries to keep in slices
+	dryRun    bool     // list the log paths without running the scraper on hosts
 }
 
 // Desc implements the Collector interface
@@ -202,8 +210,14 @@ func (c *LogCollectOptions) Prepare(m *Manager, cls *models.TiDBCluster) (map[st
 				hostPaths[inst.GetHost()] = set.NewStringSet()
 			}
 			hostPaths[inst.GetHost()].Insert(fmt.Sprintf("%s/*", inst.LogDir()))
+			if c.dryRun && c.collector.Rocksdb && inst.ComponentName() == spec.ComponentTiKV {
+				hostPaths[inst.GetHost()].Insert(fmt.Sprintf("%s/*", inst.DataDir()))
+			}
 		}
 	}
+	if c.dryRun {
+		return planHostPaths(hostPaths, "log files"), nil
+	}
 
 	var scraperLogType []string
 	if c.collector.Std {
@@ -222,11 +236,12 @@ func (c *LogCollectOptions) Prepare(m *Manager, cls *models.TiDBCluster) (map[st
 		t = t.
 			Shell(
 				host,
-				fmt.Sprintf("%s --log '%s' -f '%s' -t '%s' --logtype %s",
+				fmt.Sprintf("%s --log '%s' -f '%s' -t '%s' --logtype %s%s",
 					filepath.Join(task.CheckToolsPathDir, "bin", "scraper"),
 					strings.Join(hostPaths[host].Slice(), ","),
 					c.ScrapeBegin, c.ScrapeEnd,
 					strings.Join(scraperLogType, ","),
+					c.sliceArgs(true),
 				),
 				"",
 				false,
@@ -319,7 +334,17 @@ func (c *LogCollectOptions) Collect(m *Manager, cls *models.TiDBCluster) error {
 			if err != nil {
 				return err
 			}
-			for _, f := range c.fileStats[inst.GetHost()] {
+			host := inst.GetHost()
+			slices := make([]string, 0)
+			for _, f := range c.fileStats[host] {
+				target := fmt.Sprintf("%s:%s", host, f.Target)
+				if m.progress.TargetDone(progressKeyLog, target) {
+					continue
+				}
+				if _, ok := f.Attributes[attrKeyLogSlice]; ok {
+					slices = append(slices, f.Target)
+					continue
+				}
 				// build checking tasks
 				t2 = t2.
 					// check for listening ports
@@ -330,6 +355,35 @@ func (c *LogCollectOptions) Collect(m *Manager, cls *models.TiDBCluster) error {
 						true,
 						c.limit,
 						c.compress,
+					).
+					Func(
+						inst.GetHost(),
+						func(_ context.Context) error {
+							m.progress.FinishTarget(progressKeyLog, target)
+							return nil
+						},
+					)
+			}
+			if len(slices) > 0 {
+				// logs are sliced on the host right before fetching
+				t2 = t2.
+					Shell(
+						host,
+						fmt.Sprintf("%s --log %s -f '%s' -t '%s' --logtype %s,%s%s",
+							filepath.Join(task.CheckToolsPathDir, "bin", "scraper"),
+							shellQuote(strings.Join(slices, ",")),
+							c.ScrapeBegin, c.ScrapeEnd,
+							scraper.LogTypeStd, scraper.LogTypeSlow,
+							c.sliceArgs(false),
+						),
+						"",
+						false,
+					).
+					Func(
+						host,
+						func(ctx context.Context) error {
+							return c.fetchSlices(ctx, m, host, slices)
+						},
 					)
 			}
 			collectTasks = append(
@@ -416,6 +470,10 @@ func (c *LogCollectOptions) collectK8s(m *Manager, cls *models.TiDBCluster) erro
 
 	for podName, fileStats := range c.fileStats {
 		for _, fs := range fileStats {
+			target := fmt.Sprintf("%s:%s", podName, fs.Target)
+			if m.progress.TargetDone(progressKeyLog, target) {
+				continue
+			}
 			opt := corev1.PodLogOptions{
 				Container: fs.Attributes["containerName"].(string),
 				SinceTime: &metav1.Time{Time: beginTime},
@@ -434,7 +492,7 @@ func (c *LogCollectOptions) collectK8s(m *Manager, cls *models.TiDBCluster) erro
 			if err != nil {
 				return err
 			}
-			f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY, 0644)
+			f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 			if err != nil {
 				return err
 			}
@@ -444,13 +502,16 @@ func (c *LogCollectOptions) collectK8s(m *Manager, cls *models.TiDBCluster) erro
 			if err != nil {
 				return err
 			}
+			m.progress.FinishTarget(progressKeyLog, target)
 		}
 	}
 
 	return nil
 }
 
-func parseScraperSamples(ctx context.Context, host string) (map[string][]CollectStat, error) {
+// readScraperSample parses the output of the scraper on the host, it is
+// nil if no files are matched
+func readScraperSample(ctx context.Context, host string) (*scraper.Sample, error) {
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 	stdout, stderr, _ := ctxt.GetInner(ctx).GetOutputs(host)
 	if len(stderr) > 0 {
@@ -466,6 +527,14 @@ func parseScraperSamples(ctx context.Context, host string) (map[string][]Collect
 		// save output directly on parsing errors
 		return nil, fmt.Errorf("error parsing scraped stats: %s", stdout)
 	}
+	return &s, nil
+}
+
+func parseScraperSamples(ctx context.Context, host string) (map[string][]CollectStat, error) {
+	s, err := readScraperSample(ctx, host)
+	if s == nil || err != nil {
+		return nil, err
+	}
 
 	stats := make(map[string][]CollectStat)
 	if _, found := stats[host]; !found {
@@ -479,10 +548,14 @@ func parseScraperSamples(ctx context.Context, host string) (map[string][]Collect
 		})
 	}
 	for k, v := range s.Log {
-		stats[host] = append(stats[host], CollectStat{
+		stat := CollectStat{
 			Target: k,
 			Size:   v,
-		})
+		}
+		if slice, ok := s.LogSlices[k]; ok {
+			stat.Attributes = map[string]interface{}{attrKeyLogSlice: slice}
+		}
+		stats[host] = append(stats[host], stat)
 	}
 	for k, v := range s.TSDB {
 		stats[host] = append(stats[host], CollectStat{
@@ -493,3 +566,99 @@ func parseScraperSamples(ctx context.Context, host string) (map[string][]Collect
 
 	return stats, nil
 }
+
+// fetchSlices downloads the slices written by the scraper on the host, a log
+// is fetched as a whole if it failed to be sliced, and skipped if it is not
+// in the sample, only the fetched logs are marked as done
+func (c *LogCollectOptions) fetchSlices(ctx context.Context, m *Manager, host string, logs []string) error {
+	s, err := readScraperSample(ctx, host)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		s = &scraper.Sample{}
+	}
+	e, ok := ctxt.GetInner(ctx).GetExecutor(host)
+	if !ok {
+		return task.ErrNoExecutor
+	}
+	for _, fname := range logs {
+		fp := filepath.Join(c.resultDir, host, fname)
+		if slice, ok := s.LogSlices[fname]; ok {
+			// slices are plain text, so the suffix of rotated logs is dropped
+			dst := strings.TrimSuffix(fp, ".gz")
+			if err := e.Transfer(ctx, slice, dst+".gz", true, c.limit, false); err != nil {
+				return perrs.Annotate(err, "failed to transfer file")
+			}
+			if err := decompressSlice(dst+".gz", dst); err != nil {
+				return err
+			}
+		} else if _, ok := s.Log[fname]; ok {
+			if err := e.Transfer(ctx, fname, fp, true, c.limit, c.compress); err != nil {
+				return perrs.Annotate(err, "failed to transfer file")
+			}
+		} else {
+			m.logger.Warnf("Log %s is not found in the scraped files of %s, skipped", fname, host)
+			continue
+		}
+		m.progress.FinishTarget(progressKeyLog, fmt.Sprintf("%s:%s", host, fname))
+	}
+	return nil
+}
+
+// sliceArgs returns the scraper arguments to slice logs, the slices are
+// written to the tools dir so they are removed in the cleanup, with estimate
+// only the sizes of slices are returned
+func (c *LogCollectOptions) sliceArgs(estimate bool) string {
+	if !c.slice && len(c.levels) == 0 && len(c.keywords) == 0 {
+		return ""
+	}
+	args := fmt.Sprintf(" --slice-dir %s", shellQuote(filepath.Join(task.CheckToolsPathDir, "slices")))
+	if estimate {
+		args += " --estimate"
+	}
+	if len(c.levels) > 0 {
+		args += fmt.Sprintf(" --level %s", shellQuote(strings.Join(c.levels, ",")))
+	}
+	for _, kw := range c.keywords {
+		args += fmt.Sprintf(" --keyword %s", shellQuote(kw))
+	}
+	return args
+}
+
+// shellQuote quotes a string as a single argument of sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// decompressSlice extracts the fetched slice of a log file to the path of
+// the log, so the slice could be read as the original file
+func decompressSlice(src, dst string) error {
+	if err := gunzipFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func gunzipFile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
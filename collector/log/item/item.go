@@ -17,12 +17,31 @@ const (
 	LevelDEBUG
 )
 
+// String returns the name of the level
+func (l LevelType) String() string {
+	switch l {
+	case LevelFATAL:
+		return "FATAL"
+	case LevelERROR:
+		return "ERROR"
+	case LevelWARN:
+		return "WARN"
+	case LevelINFO:
+		return "INFO"
+	case LevelDEBUG:
+		return "DEBUG"
+	default:
+		return ""
+	}
+}
+
 const (
 	TypeInvalid ItemType = iota
 	TypeTiDB
 	TypeTiKV
 	TypePD
 	TypeTiDBSlowQuery
+	TypeOther // logs of other components, e.g. tiflash, ticdc
 )
 
 // Item represent a log entity
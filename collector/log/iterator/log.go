@@ -33,12 +33,23 @@ type LogIterator struct {
 	parsers   []parser.Parser
 	current   item.Item
 	nextError error
+	anyType   bool // logs of unknown components are typed as TypeOther
 }
 
 // Generate a new iterator from a specific file and a time range.
 // The iterator should only return logs in the [begin, end] time
 // range.
 func New(fw *parser.FileWrapper, begin, end time.Time) (IteratorWithPeek, error) {
+	return newIterator(fw, begin, end, false)
+}
+
+// NewAny is like New, but also accepts logs of components other than tidb,
+// tikv and pd, they are returned as TypeOther.
+func NewAny(fw *parser.FileWrapper, begin, end time.Time) (IteratorWithPeek, error) {
+	return newIterator(fw, begin, end, true)
+}
+
+func newIterator(fw *parser.FileWrapper, begin, end time.Time, anyType bool) (IteratorWithPeek, error) {
 	component, port, err := fw.ParseFolderName()
 	if err != nil {
 		return nil, err
@@ -58,6 +69,7 @@ func New(fw *parser.FileWrapper, begin, end time.Time) (IteratorWithPeek, error)
 		begin:     begin,
 		end:       end,
 		parsers:   parser.List(),
+		anyType:   anyType,
 	}
 
 	if iter.itemType() == item.TypeInvalid {
@@ -272,6 +284,9 @@ func (iter *LogIterator) itemType() item.ItemType {
 	case "pd":
 		return item.TypePD
 	default:
+		if iter.anyType && iter.component != "" {
+			return item.TypeOther
+		}
 		return item.TypeInvalid
 	}
 }
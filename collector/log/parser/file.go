@@ -2,8 +2,11 @@ package parser
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -14,6 +17,10 @@ type FileWrapper struct {
 	Host     string
 	Folder   string
 	Filename string
+
+	// Component and Port are set when they are not from the folder name
+	Component string
+	Port      string
 }
 
 // Open the file fw represent.
@@ -24,6 +31,9 @@ func (fw *FileWrapper) Open() (*os.File, error) {
 
 // ParseFolderName returns the component name and port it listening on.
 func (fw *FileWrapper) ParseFolderName() (comp string, port string, err error) {
+	if fw.Component != "" {
+		return fw.Component, fw.Port, nil
+	}
 	s := strings.Split(fw.Folder, "-")
 	if len(s) < 2 {
 		return "", "", fmt.Errorf("unexpect folder name: %s", s)
@@ -81,3 +91,65 @@ func ResolveDir(src string) ([]*FileWrapper, error) {
 	}
 	return wrappers, nil
 }
+
+// deployDirRE matches deploy dirs of instances, e.g. tidb-4000, dm-master-8261
+var deployDirRE = regexp.MustCompile(`^([a-z][a-z-]*?)-([0-9]+)$`)
+
+// ResolveDataDir lists log files in a data set collected by diag, the host
+// is the first level dir and the component and port are from the deploy dir
+// of the instance, e.g. {host}/{deploy_path}/tidb-4000/log/tidb.log; for
+// logs collected from kubernetes, which are logs/{pod}/{component}.log, the
+// host is the pod name. Monitoring data and compressed files are ignored.
+func ResolveDataDir(src string) ([]*FileWrapper, error) {
+	var wrappers []*FileWrapper
+	err := filepath.WalkDir(src, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, fp)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == "monitor" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if !strings.Contains(name, ".log") || strings.HasSuffix(name, ".gz") {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 2 {
+			return nil
+		}
+
+		fw := NewFileWrapper(src, parts[0], path.Join(parts[1:len(parts)-1]...), name)
+		top := 0 // index of the host dir
+		if parts[0] == "logs" && len(parts) > 2 {
+			top = 1
+			fw.Root = path.Join(src, "logs")
+			fw.Host = parts[1]
+			fw.Folder = path.Join(parts[2 : len(parts)-1]...)
+		}
+		for i := len(parts) - 2; i > top && fw.Component == ""; i-- {
+			if m := deployDirRE.FindStringSubmatch(parts[i]); m != nil {
+				fw.Component, fw.Port = m[1], m[2]
+			}
+		}
+		if fw.Component == "" {
+			comp := name[:strings.Index(name, ".log")]
+			if i := strings.Index(comp, "_"); i > 0 {
+				comp = comp[:i]
+			}
+			fw.Component = comp
+		}
+		wrappers = append(wrappers, fw)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wrappers, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/pingcap/diag/collector/log/parser"
+)
+
+// Filter selects logs of a search, empty fields match everything
+type Filter struct {
+	Begin      time.Time
+	End        time.Time
+	Levels     []item.LevelType
+	Text       string         // the content contains the text
+	Pattern    *regexp.Regexp // the content matches the pattern
+	Components []string
+	Hosts      []string
+}
+
+// matchFile checks the host and component of a log file
+func (f *Filter) matchFile(fw *parser.FileWrapper) bool {
+	if len(f.Hosts) > 0 && !contains(f.Hosts, fw.Host) {
+		return false
+	}
+	if len(f.Components) == 0 {
+		return true
+	}
+	comp, _, err := fw.ParseFolderName()
+	return err == nil && contains(f.Components, comp)
+}
+
+// match checks the level and content of a log item
+func (f *Filter) match(it item.Item) bool {
+	if len(f.Levels) > 0 {
+		found := false
+		for _, l := range f.Levels {
+			if it.GetLevel() == l {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !bytes.Contains(it.GetContent(), []byte(f.Text)) {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.Match(it.GetContent())
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Cursor is the position of a search, it is the time of the last returned
+// log and the number of returned logs of that time, so a search could be
+// continued from it without keeping the iterator in memory.
+type Cursor struct {
+	Time time.Time
+	Skip int
+}
+
+// Token encodes the cursor as a paging token
+func (c Cursor) Token() string {
+	return fmt.Sprintf("%d.%d", c.Time.UnixNano(), c.Skip)
+}
+
+// ParseCursor decodes a paging token returned by Cursor.Token
+func ParseCursor(token string) (*Cursor, error) {
+	fields := strings.Split(token, ".")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid token: %s", token)
+	}
+	ts, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %s", token)
+	}
+	skip, err := strconv.Atoi(fields[1])
+	if err != nil || skip < 0 {
+		return nil, fmt.Errorf("invalid token: %s", token)
+	}
+	return &Cursor{Time: time.Unix(0, ts), Skip: skip}, nil
+}
@@ -1,7 +1,6 @@
 package search
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"sync"
@@ -15,6 +14,9 @@ import (
 
 type Searcher interface {
 	Search(dir string, begin, end time.Time, level, text, token string) (iterator.Iterator, string, error)
+	// SearchFilter searches logs in a data set collected by diag, the token
+	// could also be a cursor token to continue a search which is not in memory
+	SearchFilter(dir string, filter *Filter, token string) (*IterWithAccessTime, string, error)
 }
 
 type searcher struct {
@@ -25,18 +27,33 @@ type searcher struct {
 type IterWithAccessTime struct {
 	iter   *Sequence
 	access time.Time
-	search []byte
-	level  string
+	filter *Filter
+	cursor Cursor
+	skip   int // logs of the cursor time to skip
 	l      sync.Mutex
 }
 
 func NewIter(iter *Sequence, search, level string) *IterWithAccessTime {
-	return &IterWithAccessTime{
+	filter := &Filter{Text: search}
+	if level != "" {
+		filter.Levels = []item.LevelType{parser.ParseLogLevel([]byte(level))}
+	}
+	return NewFilterIter(iter, filter, nil)
+}
+
+// NewFilterIter creates an iterator returning logs matching the filter, logs
+// before the cursor are skipped if it is not nil
+func NewFilterIter(iter *Sequence, filter *Filter, cursor *Cursor) *IterWithAccessTime {
+	i := &IterWithAccessTime{
 		iter:   iter,
 		access: time.Now(),
-		search: []byte(search),
-		level:  level,
+		filter: filter,
 	}
+	if cursor != nil {
+		i.cursor = *cursor
+		i.skip = cursor.Skip
+	}
+	return i
 }
 
 func (i *IterWithAccessTime) Next() (item.Item, error) {
@@ -53,16 +70,31 @@ func (i *IterWithAccessTime) Next() (item.Item, error) {
 		if err != nil {
 			return nil, err
 		}
-		if !bytes.Contains(item.GetContent(), i.search) {
+		if !i.filter.match(item) {
 			continue
 		}
-		if i.level != "" && item.GetLevel() != parser.ParseLogLevel([]byte(i.level)) {
+		ts := item.GetTime()
+		if i.skip > 0 && ts.Equal(i.cursor.Time) {
+			i.skip--
 			continue
 		}
+		i.skip = 0
+		if ts.Equal(i.cursor.Time) {
+			i.cursor.Skip++
+		} else {
+			i.cursor = Cursor{Time: ts, Skip: 1}
+		}
 		return item, nil
 	}
 }
 
+// Cursor returns the position after the last returned log
+func (i *IterWithAccessTime) Cursor() Cursor {
+	i.l.Lock()
+	defer i.l.Unlock()
+	return i.cursor
+}
+
 func (i *IterWithAccessTime) Close() error {
 	i.l.Lock()
 	defer i.l.Unlock()
@@ -140,3 +172,38 @@ func (s *searcher) Search(dir string, begin, end time.Time, level, text, token s
 	}
 	return iter, token, nil
 }
+
+func (s *searcher) SearchFilter(dir string, filter *Filter, token string) (*IterWithAccessTime, string, error) {
+	var cursor *Cursor
+	if token != "" {
+		if iter := s.GetIter(token); iter != nil {
+			return iter, token, nil
+		}
+		var err error
+		if cursor, err = ParseCursor(token); err != nil {
+			return nil, token, fmt.Errorf("not found")
+		}
+	}
+
+	files, err := parser.ResolveDataDir(dir)
+	if err != nil {
+		return nil, token, err
+	}
+	selected := make([]*parser.FileWrapper, 0, len(files))
+	for _, fw := range files {
+		if filter.matchFile(fw) {
+			selected = append(selected, fw)
+		}
+	}
+	begin := filter.Begin
+	if cursor != nil && cursor.Time.After(begin) {
+		// the iterator returns logs after the begin time, and the logs of
+		// the cursor time are needed to skip the returned ones
+		begin = cursor.Time.Add(-time.Nanosecond)
+	}
+
+	token = uuid.New().String()
+	iter := NewFilterIter(newSequence(selected, begin, filter.End, true), filter, cursor)
+	go s.Gc(token, iter)
+	return iter, token, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogBegin = time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+
+// writeTestLog writes a log with an entry per second, every 3rd entry is a
+// warning
+func writeTestLog(t *testing.T, fp, comp string, entries int) {
+	var buf bytes.Buffer
+	for i := 0; i < entries; i++ {
+		ts := testLogBegin.Add(time.Duration(i) * time.Second).Format("2006/01/02 15:04:05.000 -07:00")
+		level := "INFO"
+		if i%3 == 0 {
+			level = "WARN"
+		}
+		fmt.Fprintf(&buf, "[%s] [%s] [%s.go:1] [\"request\"] [id=%d]\n", ts, level, comp, i)
+	}
+	require.Nil(t, os.MkdirAll(filepath.Dir(fp), 0755))
+	require.Nil(t, os.WriteFile(fp, buf.Bytes(), 0644))
+}
+
+func collect(t *testing.T, iter *IterWithAccessTime, limit int) []item.Item {
+	var items []item.Item
+	for len(items) < limit {
+		it, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		items = append(items, it)
+	}
+	return items
+}
+
+func TestSearchFilter(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	writeTestLog(t, filepath.Join(dir, "10.0.0.1", "tidb-deploy", "tidb-4000", "log", "tidb.log"), "tidb", 10)
+	writeTestLog(t, filepath.Join(dir, "10.0.0.2", "tikv-deploy", "tikv-20160", "log", "tikv.log"), "tikv", 10)
+	writeTestLog(t, filepath.Join(dir, "logs", "basic-pd-0", "pd.log"), "pd", 10)
+	writeTestLog(t, filepath.Join(dir, "monitor", "alertmanager", "alert.log"), "alert", 10)
+
+	s := NewSearcher()
+	filter := &Filter{End: testLogBegin.Add(time.Hour)}
+	iter, token, err := s.SearchFilter(dir, filter, "")
+	assert.Nil(err)
+	assert.NotEmpty(token)
+	all := collect(t, iter, 100)
+	assert.Len(all, 30)
+	assert.Equal("10.0.0.1", all[0].GetHost())
+	assert.Equal("tidb", all[0].GetComponent())
+	assert.Equal("4000", all[0].GetPort())
+	assert.Equal("tikv", all[1].GetComponent())
+	assert.Equal("basic-pd-0", all[2].GetHost())
+	assert.Equal("pd", all[2].GetComponent())
+	for i := 1; i < len(all); i++ {
+		assert.False(all[i].GetTime().Before(all[i-1].GetTime()))
+	}
+
+	// pages continued from cursor tokens are the same as a single search
+	var paged []item.Item
+	token = ""
+	for {
+		iter, _, err := s.SearchFilter(dir, filter, token)
+		assert.Nil(err)
+		page := collect(t, iter, 4)
+		paged = append(paged, page...)
+		token = iter.Cursor().Token()
+		iter.Close()
+		if len(page) < 4 {
+			break
+		}
+	}
+	assert.Equal(all, paged)
+
+	_, _, err = s.SearchFilter(dir, filter, "unknown")
+	assert.NotNil(err)
+
+	// filters of files and items
+	filter.Hosts = []string{"10.0.0.1", "10.0.0.2"}
+	filter.Components = []string{"tikv"}
+	filter.Levels = []item.LevelType{item.LevelWARN}
+	filter.Pattern = regexp.MustCompile(`id=[36]\]`)
+	iter, _, err = s.SearchFilter(dir, filter, "")
+	assert.Nil(err)
+	items := collect(t, iter, 100)
+	assert.Len(items, 2)
+	assert.Equal("10.0.0.2", items[0].GetHost())
+	assert.Contains(string(items[1].GetContent()), "id=6")
+}
+
+func TestSearchOtherComponents(t *testing.T) {
+	assert := require.New(t)
+
+	// logs of other components are only returned by SearchFilter
+	dir := t.TempDir()
+	writeTestLog(t, filepath.Join(dir, "10.0.0.1", "tidb-4000", "tidb.log"), "tidb", 10)
+	writeTestLog(t, filepath.Join(dir, "10.0.0.1", "tiflash-9000", "tiflash.log"), "tiflash", 10)
+	s := NewSearcher()
+	iter, _, err := s.Search(dir, testLogBegin.Add(-time.Second), testLogBegin.Add(time.Hour), "", "", "")
+	assert.Nil(err)
+	items := collect(t, iter.(*IterWithAccessTime), 100)
+	assert.Len(items, 10)
+	for _, it := range items {
+		assert.Equal(item.TypeTiDB, it.(*item.LogItem).Type)
+	}
+
+	dir = t.TempDir()
+	writeTestLog(t, filepath.Join(dir, "10.0.0.1", "tiflash-deploy", "tiflash-9000", "log", "tiflash.log"), "tiflash", 10)
+	iter2, _, err := s.SearchFilter(dir, &Filter{End: testLogBegin.Add(time.Hour)}, "")
+	assert.Nil(err)
+	items = collect(t, iter2, 100)
+	assert.Len(items, 10)
+	assert.Equal("tiflash", items[0].GetComponent())
+	assert.Equal(item.TypeOther, items[0].(*item.LogItem).Type)
+}
+
+func TestCursor(t *testing.T) {
+	assert := require.New(t)
+
+	c := Cursor{Time: testLogBegin, Skip: 2}
+	parsed, err := ParseCursor(c.Token())
+	assert.Nil(err)
+	assert.True(parsed.Time.Equal(c.Time))
+	assert.Equal(2, parsed.Skip)
+
+	for _, token := range []string{"", "1.2.3", "a.1", "1.-1"} {
+		_, err := ParseCursor(token)
+		assert.NotNil(err)
+	}
+}
@@ -80,33 +80,42 @@ func (s *Sequence) Remove(index int) {
 // analyze each log in each file by merge sort (from old to new by timestamp),
 // return the constructed LogIter object and provide the Next function for external call.
 func NewSequence(src string, begin, end time.Time) (*Sequence, error) {
-	sequence := &Sequence{}
 	files, err := parser.ResolveDir(src)
 	if err != nil {
 		return nil, err
 	}
+	return newSequence(files, begin, end, false), nil
+}
 
-	iters := make(chan iterator.IteratorWithPeek, len(files))
+// newSequence opens iterators of the files, the iterators keep the order of
+// files, so logs of the same time are always returned in the same order,
+// logs of unknown components are included if anyType is true
+func newSequence(files []*parser.FileWrapper, begin, end time.Time, anyType bool) *Sequence {
+	newIter := iterator.New
+	if anyType {
+		newIter = iterator.NewAny
+	}
+	sequence := &Sequence{}
+	iters := make([]iterator.IteratorWithPeek, len(files))
 	wg := sync.WaitGroup{}
 	wg.Add(len(files))
 
-	for _, fw := range files {
-		go func(fw *parser.FileWrapper) {
-			if iter, err := iterator.New(fw, begin, end); err != nil {
+	for idx, fw := range files {
+		go func(idx int, fw *parser.FileWrapper) {
+			if iter, err := newIter(fw, begin, end); err != nil {
 				if err != io.EOF {
 					log.Warnf("create log iterator err: %s", err)
 				}
 			} else {
-				iters <- iter
+				iters[idx] = iter
 			}
 			wg.Done()
-		}(fw)
+		}(idx, fw)
 	}
 	wg.Wait()
-	close(iters)
 
-	for iter := range iters {
+	for _, iter := range iters {
 		sequence.Add(iter)
 	}
-	return sequence, nil
+	return sequence
 }
@@ -0,0 +1,610 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/pingcap/diag/collector/log/parser"
+	"github.com/pingcap/diag/pkg/parquet"
+)
+
+// formats of exported logs
+const (
+	LogExportJSONL   = "jsonl"
+	LogExportParquet = "parquet"
+)
+
+// LogExportOptions are options of converting collected logs
+type LogExportOptions struct {
+	Format string    // jsonl or parquet
+	Output string    // output file
+	Begin  time.Time // only export entries after it, zero means no limit
+	End    time.Time // only export entries before it, zero means no limit
+}
+
+// LogEvent is a parsed entry of a collected log
+type LogEvent struct {
+	Time      time.Time         `json:"time"`
+	Host      string            `json:"host"`
+	Component string            `json:"component"`
+	File      string            `json:"file"` // path relative to the data dir
+	Level     string            `json:"level,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+type logEventWriter interface {
+	write(ev *LogEvent) error
+	close() error
+}
+
+// ExportLogs parses all logs in the data dir with the parser matching each
+// file, and writes the entries merged and sorted by time to the output,
+// the number of exported entries is returned
+func ExportLogs(dataDir string, opt *LogExportOptions) (int, error) {
+	files, err := listLogFiles(dataDir)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := os.Create(opt.Output)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	var w logEventWriter
+	switch opt.Format {
+	case LogExportJSONL:
+		w = newJSONLEventWriter(out)
+	case LogExportParquet:
+		w, err = newParquetEventWriter(out)
+	default:
+		err = fmt.Errorf("unknown format '%s', valid formats are: %s, %s",
+			opt.Format, LogExportJSONL, LogExportParquet)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	readers := make(logReaderHeap, 0, len(files))
+	defer func() {
+		for _, r := range readers {
+			r.close()
+		}
+	}()
+	for _, lf := range files {
+		r, err := openLogReader(dataDir, lf, opt.Begin, opt.End)
+		if err != nil {
+			return 0, err
+		}
+		if r.current == nil {
+			r.close()
+			continue
+		}
+		readers = append(readers, r)
+	}
+	heap.Init(&readers)
+
+	count := 0
+	for readers.Len() > 0 {
+		r := readers[0]
+		if err := w.write(r.current); err != nil {
+			return count, err
+		}
+		count++
+		if err := r.next(); err != nil {
+			return count, err
+		}
+		if r.current == nil {
+			r.close()
+			heap.Pop(&readers)
+		} else {
+			heap.Fix(&readers, 0)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return count, err
+	}
+	return count, out.Close()
+}
+
+// logFile is a log file found in the data dir
+type logFile struct {
+	path      string // relative to the data dir
+	host      string
+	component string
+	parser    parser.Parser
+}
+
+// deployDirRE matches the deploy dir of instances, e.g. tidb-4000
+var deployDirRE = regexp.MustCompile(`^([a-z][a-z-]*?)-[0-9]+$`)
+
+// listLogFiles finds log files in the data dir, a file is taken as a log if
+// any of the parsers could parse its head, the host is the first level dir,
+// or the pod name for logs collected from kubernetes
+func listLogFiles(dataDir string) ([]*logFile, error) {
+	files := make([]*logFile, 0)
+	err := filepath.WalkDir(dataDir, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dataDir, fp)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == subdirMonitor {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.Contains(d.Name(), ".log") {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 2 {
+			return nil
+		}
+		p, err := detectLogParser(fp)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return nil
+		}
+
+		lf := &logFile{
+			path:   rel,
+			host:   parts[0],
+			parser: p,
+		}
+		top := 0 // index of the host dir
+		if parts[0] == "logs" && len(parts) > 2 {
+			top = 1
+			lf.host = parts[1]
+		}
+		for i := len(parts) - 2; i > top && lf.component == ""; i-- {
+			if m := deployDirRE.FindStringSubmatch(parts[i]); m != nil {
+				lf.component = m[1]
+			}
+		}
+		if lf.component == "" {
+			name := d.Name()
+			name = name[:strings.Index(name, ".log")]
+			if i := strings.Index(name, "_"); i > 0 {
+				name = name[:i]
+			}
+			lf.component = name
+		}
+		files = append(files, lf)
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, err
+}
+
+// openLogFile opens a log file, gzipped files are decompressed
+func openLogFile(fp string) (io.ReadCloser, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(fp, ".gz") {
+		return f, nil
+	}
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, nil
+}
+
+// detectLogParser returns the parser of the first parsable line in the head
+// of the file, or nil if it is not a known log
+func detectLogParser(fp string) (parser.Parser, error) {
+	f, err := openLogFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parsers := append([]parser.Parser{&parser.SlowQueryParser{}}, parser.ListStd()...)
+	r := bufio.NewReader(f)
+	for i := 0; i < 64; i++ {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		for _, p := range parsers {
+			if t, _ := p.ParseHead(line); t != nil {
+				return p, nil
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return nil, nil
+}
+
+// logReader reads entries of a log file one by one
+type logReader struct {
+	file    *logFile
+	f       io.ReadCloser
+	r       *bufio.Reader
+	begin   time.Time
+	end     time.Time
+	current *LogEvent
+
+	head    []byte // the head line of the next entry
+	headT   time.Time
+	headLvl item.LevelType
+	eof     bool
+}
+
+func openLogReader(dataDir string, lf *logFile, begin, end time.Time) (*logReader, error) {
+	f, err := openLogFile(filepath.Join(dataDir, lf.path))
+	if err != nil {
+		return nil, err
+	}
+	r := &logReader{
+		file:  lf,
+		f:     f,
+		r:     bufio.NewReaderSize(f, 64*1024),
+		begin: begin,
+		end:   end,
+	}
+	if err := r.next(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *logReader) close() {
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+	}
+}
+
+// next reads the next entry in the time range to current, current is nil
+// if there is no more entries
+func (r *logReader) next() error {
+	for {
+		ev, err := r.readEntry()
+		if err != nil || ev == nil {
+			r.current = nil
+			return err
+		}
+		if !r.end.IsZero() && ev.Time.After(r.end) {
+			r.current = nil
+			return nil
+		}
+		if r.begin.IsZero() || !ev.Time.Before(r.begin) {
+			r.current = ev
+			return nil
+		}
+	}
+}
+
+// readEntry reads the lines of an entry, lines before the first head are
+// dropped
+func (r *logReader) readEntry() (*LogEvent, error) {
+	lines := make([][]byte, 0, 1)
+	for !r.eof {
+		line, err := r.r.ReadBytes('\n')
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 && r.eof {
+			break
+		}
+		if t, level := r.file.parser.ParseHead(line); t != nil {
+			prev, prevT, prevLvl := r.head, r.headT, r.headLvl
+			r.head, r.headT, r.headLvl = line, *t, level
+			if prev != nil {
+				return r.buildEvent(append([][]byte{prev}, lines...), prevT, prevLvl), nil
+			}
+			lines = lines[:0]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if r.head == nil {
+		return nil, nil
+	}
+	head := r.head
+	r.head = nil
+	return r.buildEvent(append([][]byte{head}, lines...), r.headT, r.headLvl), nil
+}
+
+func (r *logReader) buildEvent(lines [][]byte, t time.Time, level item.LevelType) *LogEvent {
+	ev := &LogEvent{
+		Time:      t,
+		Host:      r.file.host,
+		Component: r.file.component,
+		File:      r.file.path,
+		Level:     level.String(),
+	}
+	ev.Message, ev.Fields = parseLogEntry(r.file.parser, lines)
+	return ev
+}
+
+// parseLogEntry extracts the message and key-value fields of an entry,
+// following lines are appended to the message
+func parseLogEntry(p parser.Parser, lines [][]byte) (string, map[string]string) {
+	var (
+		msg    string
+		fields map[string]string
+	)
+	head := lines[0]
+	switch p.(type) {
+	case *parser.SlowQueryParser:
+		return parseSlowQueryEntry(lines)
+	case *parser.UnifiedLogParser:
+		msg, fields = parseUnifiedEntry(head)
+	case *parser.UnifiedJSONLogParser:
+		msg, fields = parseJSONEntry(head, []string{"message", "msg"}, "level", "time")
+	case *parser.AuditJSONLogParser:
+		msg, fields = parseJSONEntry(head, []string{"SQL_TEXT"}, "TIME")
+	case *parser.PrometheusLogParser:
+		msg, fields = parseLogfmtEntry(head)
+	default:
+		msg = string(head)
+	}
+	for _, line := range lines[1:] {
+		msg += "\n" + string(line)
+	}
+	return msg, fields
+}
+
+// parseUnifiedEntry parses entries of the unified log format:
+// [time] [level] [source] [message] [key=value]...
+func parseUnifiedEntry(head []byte) (string, map[string]string) {
+	groups := splitBracketGroups(string(head))
+	if len(groups) < 4 {
+		return string(head), nil
+	}
+	fields := map[string]string{"source": groups[2]}
+	for _, g := range groups[4:] {
+		k, v, ok := strings.Cut(g, "=")
+		if !ok {
+			continue
+		}
+		fields[unquoteLogValue(k)] = unquoteLogValue(v)
+	}
+	return unquoteLogValue(groups[3]), fields
+}
+
+// splitBracketGroups returns contents of the top level brackets, brackets
+// in quoted strings are ignored
+func splitBracketGroups(s string) []string {
+	groups := make([]string, 0)
+	start, quoted := -1, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' && start < 0:
+			start = i + 1
+		case c == ']' && start >= 0:
+			groups = append(groups, s[start:i])
+			start = -1
+		}
+	}
+	return groups
+}
+
+func unquoteLogValue(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+// parseJSONEntry parses entries in JSON, the first present key of msgKeys
+// is the message, and the ignored keys are dropped
+func parseJSONEntry(head []byte, msgKeys []string, ignored ...string) (string, map[string]string) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(head, &entry); err != nil {
+		return string(head), nil
+	}
+	for _, k := range ignored {
+		delete(entry, k)
+	}
+	var msg string
+	for _, k := range msgKeys {
+		if v, ok := entry[k]; ok {
+			msg = fmt.Sprint(v)
+			delete(entry, k)
+			break
+		}
+	}
+	fields := make(map[string]string, len(entry))
+	for k, v := range entry {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+			continue
+		}
+		data, _ := json.Marshal(v)
+		fields[k] = string(data)
+	}
+	return msg, fields
+}
+
+var logfmtRE = regexp.MustCompile(`([^\s=]+)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// parseLogfmtEntry parses entries of key=value pairs, e.g. logs of Prometheus
+func parseLogfmtEntry(head []byte) (string, map[string]string) {
+	fields := make(map[string]string)
+	var msg string
+	for _, m := range logfmtRE.FindAllStringSubmatch(string(head), -1) {
+		switch v := unquoteLogValue(m[2]); m[1] {
+		case "level", "ts":
+		case "msg":
+			msg = v
+		default:
+			fields[m[1]] = v
+		}
+	}
+	return msg, fields
+}
+
+var slowQueryFieldRE = regexp.MustCompile(`([A-Za-z_]+): (\S*)`)
+
+// parseSlowQueryEntry parses a slow query, the `# Key: value` lines are
+// fields and the rest is the statement
+func parseSlowQueryEntry(lines [][]byte) (string, map[string]string) {
+	fields := make(map[string]string)
+	stmt := make([]string, 0, 1)
+	for _, line := range lines {
+		if !bytes.HasPrefix(line, []byte("# ")) {
+			stmt = append(stmt, string(line))
+			continue
+		}
+		for _, m := range slowQueryFieldRE.FindAllStringSubmatch(string(line[2:]), -1) {
+			if m[1] != "Time" {
+				fields[m[1]] = m[2]
+			}
+		}
+	}
+	return strings.Join(stmt, "\n"), fields
+}
+
+// logReaderHeap orders readers by the time of their current entries
+type logReaderHeap []*logReader
+
+func (h logReaderHeap) Len() int { return len(h) }
+func (h logReaderHeap) Less(i, j int) bool {
+	ti, tj := h[i].current.Time, h[j].current.Time
+	if ti.Equal(tj) {
+		return h[i].file.path < h[j].file.path
+	}
+	return ti.Before(tj)
+}
+func (h logReaderHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *logReaderHeap) Push(x interface{}) { *h = append(*h, x.(*logReader)) }
+func (h *logReaderHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+type jsonlEventWriter struct {
+	w *bufio.Writer
+}
+
+func newJSONLEventWriter(out io.Writer) *jsonlEventWriter {
+	return &jsonlEventWriter{w: bufio.NewWriter(out)}
+}
+
+func (w *jsonlEventWriter) write(ev *LogEvent) error {
+	// keys of fields are sorted for stable outputs
+	data, err := json.ConfigCompatibleWithStandardLibrary.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+func (w *jsonlEventWriter) close() error {
+	return w.w.Flush()
+}
+
+type parquetEventWriter struct {
+	w   *bufio.Writer
+	pqw *parquet.Writer
+}
+
+var logEventColumns = []parquet.Column{
+	{Name: "time", Type: parquet.ColumnTimestampMillis},
+	{Name: "host", Type: parquet.ColumnString},
+	{Name: "component", Type: parquet.ColumnString},
+	{Name: "file", Type: parquet.ColumnString},
+	{Name: "level", Type: parquet.ColumnString},
+	{Name: "message", Type: parquet.ColumnString},
+	{Name: "fields", Type: parquet.ColumnString}, // JSON object
+}
+
+func newParquetEventWriter(out io.Writer) (*parquetEventWriter, error) {
+	w := bufio.NewWriter(out)
+	pqw, err := parquet.NewWriter(w, logEventColumns, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetEventWriter{w: w, pqw: pqw}, nil
+}
+
+func (w *parquetEventWriter) write(ev *LogEvent) error {
+	fields := "{}"
+	if len(ev.Fields) > 0 {
+		data, err := json.ConfigCompatibleWithStandardLibrary.Marshal(ev.Fields)
+		if err != nil {
+			return err
+		}
+		fields = string(data)
+	}
+	return w.pqw.Write([]interface{}{
+		ev.Time.UnixMilli(),
+		ev.Host,
+		ev.Component,
+		ev.File,
+		ev.Level,
+		ev.Message,
+		fields,
+	})
+}
+
+func (w *parquetEventWriter) close() error {
+	if err := w.pqw.Close(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
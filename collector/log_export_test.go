@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, fp, content string) {
+	require.Nil(t, os.MkdirAll(filepath.Dir(fp), 0755))
+	require.Nil(t, os.WriteFile(fp, []byte(content), 0644))
+}
+
+func TestExportLogs(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "10.0.0.1", "tidb-deploy", "tidb-4000", "log", "tidb.log"),
+		`[2026/01/02 03:04:05.000 +00:00] [INFO] [server.go:1] ["new connection"] [conn=1] [remoteAddr="10.0.0.9:1234"]
+[2026/01/02 03:04:07.000 +00:00] [WARN] [session.go:2] ["[txn] retry"] [conn=1]
+goroutine 1 [running]:
+`)
+	writeTestFile(t, filepath.Join(dir, "10.0.0.1", "tidb-deploy", "tidb-4000", "log", "tidb_slow_query.log"),
+		`# Time: 2026-01-02T03:04:06.5+00:00
+# Query_time: 1.5
+# DB: test Succ: true
+select * from t;
+`)
+	writeTestFile(t, filepath.Join(dir, "logs", "basic-tikv-0", "tikv.log"),
+		`{"level":"ERROR","time":"2026/01/02 03:04:06.000 +00:00","caller":"raft.rs:1","message":"raft error","region_id":4}
+`)
+	writeTestFile(t, filepath.Join(dir, "10.0.0.1", "dmesg.log"), "not a log\n")
+	writeTestFile(t, filepath.Join(dir, "monitor", "metrics", "up.log"), "[2026/01/02 03:04:06.000 +00:00] [INFO] [a.go:1] [x]\n")
+
+	output := filepath.Join(t.TempDir(), "logs.jsonl")
+	count, err := ExportLogs(dir, &LogExportOptions{Format: LogExportJSONL, Output: output})
+	assert.Nil(err)
+	assert.Equal(4, count)
+
+	f, err := os.Open(output)
+	assert.Nil(err)
+	defer f.Close()
+	events := make([]*LogEvent, 0)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		ev := &LogEvent{}
+		assert.Nil(json.Unmarshal(s.Bytes(), ev))
+		events = append(events, ev)
+	}
+	assert.Len(events, 4)
+
+	assert.Equal("new connection", events[0].Message)
+	assert.Equal(map[string]string{"source": "server.go:1", "conn": "1", "remoteAddr": "10.0.0.9:1234"}, events[0].Fields)
+	assert.Equal("tidb", events[0].Component)
+	assert.Equal("10.0.0.1", events[0].Host)
+
+	// entries at the same time are ordered by file
+	assert.Equal("basic-tikv-0", events[1].Host)
+	assert.Equal("tikv", events[1].Component)
+	assert.Equal("raft error", events[1].Message)
+	assert.Equal("ERROR", events[1].Level)
+	assert.Equal("4", events[1].Fields["region_id"])
+
+	assert.Equal("select * from t;", events[2].Message)
+	assert.Equal(map[string]string{"Query_time": "1.5", "DB": "test", "Succ": "true"}, events[2].Fields)
+
+	assert.Equal("[txn] retry\ngoroutine 1 [running]:", events[3].Message)
+	assert.Equal("WARN", events[3].Level)
+
+	// time range
+	count, err = ExportLogs(dir, &LogExportOptions{
+		Format: LogExportParquet,
+		Output: filepath.Join(t.TempDir(), "logs.parquet"),
+		Begin:  time.Date(2026, 1, 2, 3, 4, 6, 0, time.UTC),
+		End:    time.Date(2026, 1, 2, 3, 4, 6, 600000000, time.UTC),
+	})
+	assert.Nil(err)
+	assert.Equal(2, count)
+
+	_, err = ExportLogs(dir, &LogExportOptions{Format: "csv", Output: output})
+	assert.NotNil(err)
+}
@@ -36,6 +36,8 @@ type Manager struct {
 	mode        string // tiup-cluster or tidb-operator
 	diagMode    string // cmd or server
 	logger      *logprinter.Logger
+	resume      bool             // resume an interrupted collection
+	progress    *collectProgress // finished parts of the collection
 }
 
 // NewManager create a Manager.
@@ -106,5 +108,7 @@ func (m *Manager) collectLock(resultDir string) {
 
 // collectUnlock when the acquisition ends, remove the file lock
 func (m *Manager) collectUnlock(resultDir string) {
+	m.progress.Close()
+	os.Remove(filepath.Join(resultDir, CollectProgressName))
 	os.Remove(filepath.Join(resultDir, CollectLockName))
 }
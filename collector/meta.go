@@ -41,6 +41,7 @@ const (
 	FileNameK8sClusterMonitor = "tidbmonitor.json" // tidb-operator crd
 	DirNameSchema             = "db_vars"
 	DirNameBind               = "sql_bind"
+	DirNameSQLSnapshot        = "sql_snapshot"
 )
 
 // MetaCollectOptions is the options collecting cluster meta
@@ -96,8 +97,26 @@ func (c *MetaCollectOptions) SetDir(dir string) {
 }
 
 // Prepare implements the Collector interface
-func (c *MetaCollectOptions) Prepare(_ *Manager, _ *models.TiDBCluster) (map[string][]CollectStat, error) {
-	return nil, nil
+func (c *MetaCollectOptions) Prepare(m *Manager, _ *models.TiDBCluster) (map[string][]CollectStat, error) {
+	files := 1 // cluster.json
+	switch m.mode {
+	case CollectModeTiUP:
+		files++
+	case CollectModeK8s:
+		files++
+		if c.tm != nil {
+			files++
+		}
+	}
+	return map[string][]CollectStat{
+		"localhost": {{
+			Target: "metadata of the cluster",
+			Size:   int64(files) * 20 * 1024,
+			Attributes: map[string]interface{}{
+				StatAttrFiles: files,
+			},
+		}},
+	}, nil
 }
 
 // Collect implements the Collector interface
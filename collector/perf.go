@@ -20,6 +20,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/diag/pkg/models"
 	perrs "github.com/pingcap/errors"
@@ -29,16 +30,20 @@ import (
 	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/sync/errgroup"
 )
 
 // PerfCollectOptions are options used collecting pref info
 type PerfCollectOptions struct {
 	*BaseOptions
-	opt       *operator.Options // global operations from cli
-	duration  int               //seconds: profile time(s), default is 30s.
-	resultDir string
-	fileStats map[string][]CollectStat
-	tlsCfg    *tls.Config
+	opt         *operator.Options // global operations from cli
+	duration    int               //seconds: profile time(s), default is 30s.
+	count       int               // number of profiling rounds, default is 1
+	interval    int               // seconds between the start of two rounds, 0 means one right after another
+	concurrency int               // max number of profiles taken from one instance at the same time
+	resultDir   string
+	fileStats   map[string][]CollectStat
+	tlsCfg      *tls.Config
 }
 
 // Desc implements the Collector interface
@@ -66,30 +71,61 @@ func (c *PerfCollectOptions) SetDir(dir string) {
 	c.resultDir = dir
 }
 
-// Prepare implements the Collector interface
-func (c *PerfCollectOptions) Prepare(m *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
-	switch m.mode {
-	case CollectModeTiUP:
-		return c.prepareForTiUP(m, topo)
-	}
-	return nil, nil
-}
-
-// // prepareForTiUP implements preparation for tiup-cluster deployed clusters
-func (c *PerfCollectOptions) prepareForTiUP(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+// instances returns the instances to profile, TiKV and TiFlash are skipped
+// if they do not support CPU profiling in the status API
+func (c *PerfCollectOptions) instances(topo *models.TiDBCluster) []models.Component {
 	// filter nodes or roles
 	roleFilter := set.NewStringSet(c.opt.Roles...)
 	nodeFilter := set.NewStringSet(c.opt.Nodes...)
 	comps := topo.Components()
 	comps = models.FilterComponent(comps, roleFilter)
-	instances := models.FilterInstance(comps, nodeFilter)
+	instances := make([]models.Component, 0)
+	for _, inst := range models.FilterInstance(comps, nodeFilter) {
+		switch inst.Type() {
+		case models.ComponentTypeTiKV, models.ComponentTypeTiFlash:
+			if !supportRustProfile(topo.Version) {
+				continue
+			}
+		}
+		instances = append(instances, inst)
+	}
+	return instances
+}
+
+// supportRustProfile checks if TiKV and TiFlash of the version could be
+// profiled from the status API, which is added in v5.0.0
+func supportRustProfile(version string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		// nightly and other unknown versions
+		return true
+	}
+	return !v.LessThan(semver.MustParse("v5.0.0"))
+}
 
-	for _, inst := range instances {
+// Prepare implements the Collector interface, the estimation is the same
+// for all deploy modes as profiles are always queried from the status API
+func (c *PerfCollectOptions) Prepare(m *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+	if c.count < 1 {
+		c.count = 1
+	}
+	if (len(topo.TiKV) > 0 || len(topo.TiFlash) > 0) && !supportRustProfile(topo.Version) {
+		m.logger.Warnf("cannot collect perf information of TiKV and TiFlash whose version is less than v5.0.0, skip them")
+	}
+	// all instances are profiled at the same time in each round
+	duration := c.duration * c.count
+	if c.count > 1 && c.interval > 0 {
+		duration = c.interval*(c.count-1) + c.duration
+	}
+	for _, inst := range c.instances(topo) {
+		var fsize int64
+		var profiles int
 		switch inst.Type() {
 		case models.ComponentTypeTiDB, models.ComponentTypePD, models.ComponentTypeTiCDC:
-
+			// cpu, heap, goroutine and mutex
+			profiles = 4
 			// cpu profile
-			fsize := (6 * 1024) * int64(c.duration)
+			fsize = (6 * 1024) * int64(c.duration)
 
 			// mem Heap
 			fsize = fsize + 500*1024
@@ -103,61 +139,86 @@ func (c *PerfCollectOptions) prepareForTiUP(_ *Manager, topo *models.TiDBCluster
 			if inst.Type() == models.ComponentTypePD {
 				fsize = fsize + 800*1024
 			}
-
-			stat := CollectStat{
-				Target: fmt.Sprintf("%s:%d %s perf", inst.Host(), inst.MainPort(), inst.Type()),
-				Size:   fsize,
-			}
-
-			c.fileStats[inst.Host()] = append(c.fileStats[inst.Host()], stat)
-
 		case models.ComponentTypeTiKV, models.ComponentTypeTiFlash:
 			// cpu profile
-			c.fileStats[inst.Host()] = append(c.fileStats[inst.Host()], CollectStat{
-				Target: fmt.Sprintf("%s:%d %s perf", inst.Host(), inst.MainPort(), inst.Type()),
-				Size:   (18 * 1024) * int64(c.duration),
-			})
+			profiles = 1
+			fsize = (18 * 1024) * int64(c.duration)
+		default:
+			continue
+		}
+
+		target := fmt.Sprintf("%s:%d %s perf", inst.Host(), inst.MainPort(), inst.Type())
+		if c.count > 1 {
+			target = fmt.Sprintf("%s, %d rounds", target, c.count)
 		}
+		c.fileStats[inst.Host()] = append(c.fileStats[inst.Host()], CollectStat{
+			Target: target,
+			Size:   fsize * int64(c.count),
+			Attributes: map[string]interface{}{
+				StatAttrFiles:    profiles * c.count,
+				StatAttrAPICalls: profiles * c.count,
+				StatAttrDuration: duration,
+			},
+		})
 	}
 
 	return c.fileStats, nil
 }
 
-// Collect implements the Collector interface
+// Collect implements the Collector interface, profiles of all instances are
+// taken at the same time in each round, so that they could be correlated.
+// When more than one round is set, profiles of a round are saved in a sub
+// dir named by the index and start time of the round.
 func (c *PerfCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error {
+	instances := c.instances(topo)
+	if len(instances) == 0 {
+		return nil
+	}
 	ctx := ctxt.New(
 		context.Background(),
-		c.opt.Concurrency,
+		len(instances),
 		m.logger,
 	)
 
-	collectePerfTasks := []*task.StepDisplay{}
+	var firstErr error
+	begin := time.Now()
+	for round := 0; round < c.count; round++ {
+		if round > 0 && c.interval > 0 {
+			time.Sleep(time.Until(begin.Add(time.Duration(round*c.interval) * time.Second)))
+		}
+		title := "+ Query profile info"
+		roundDir := ""
+		if c.count > 1 {
+			title = fmt.Sprintf("+ Query profile info (round %d/%d)", round+1, c.count)
+			roundDir = fmt.Sprintf("%03d-%s", round+1, time.Now().Format("20060102-150405"))
+		}
 
-	// filter nodes or roles
-	roleFilter := set.NewStringSet(c.opt.Roles...)
-	nodeFilter := set.NewStringSet(c.opt.Nodes...)
-	comps := topo.Components()
-	comps = models.FilterComponent(comps, roleFilter)
-	instances := models.FilterInstance(comps, nodeFilter)
+		collectePerfTasks := []*task.StepDisplay{}
+		for _, inst := range instances {
+			if t := buildPerfCollectingTasks(ctx, inst, c, roundDir); len(t) != 0 {
+				collectePerfTasks = append(collectePerfTasks, t...)
+			}
+		}
+
+		t := task.NewBuilder(m.logger).
+			ParallelStep(title, false, collectePerfTasks...).Build()
 
-	// build tsaks
-	for _, inst := range instances {
-		if t := buildPerfCollectingTasks(ctx, inst, c); len(t) != 0 {
-			collectePerfTasks = append(collectePerfTasks, t...)
+		if err := t.Execute(ctx); err != nil {
+			// go on with the remaining rounds
+			m.logger.Warnf("failed to collect profile info of round %d: %s", round+1, err)
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
 	}
 
-	t := task.NewBuilder(m.logger).
-		ParallelStep("+ Query profile info", false, collectePerfTasks...).Build()
-
-	if err := t.Execute(ctx); err != nil {
-		if errorx.Cast(err) != nil {
+	if firstErr != nil {
+		if errorx.Cast(firstErr) != nil {
 			// FIXME: Map possible task errors and give suggestions.
-			return err
+			return firstErr
 		}
-		return perrs.Trace(err)
+		return perrs.Trace(firstErr)
 	}
-
 	return nil
 }
 
@@ -169,7 +230,7 @@ type perfConfig struct {
 }
 
 // buildPerfCollectingTasks build collect profile information tasks
-func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *PerfCollectOptions) []*task.StepDisplay {
+func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *PerfCollectOptions, roundDir string) []*task.StepDisplay {
 	var (
 		perfInfoTasks []*task.StepDisplay
 		perfConfigs   []perfConfig
@@ -190,7 +251,7 @@ func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *Per
 		// cpu profile
 		perfConfigs = append(perfConfigs,
 			perfConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, "cpu_profile.proto"),
+				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, roundDir, "cpu_profile.proto"),
 				url:      fmt.Sprintf("%s/debug/pprof/profile?seconds=%d", inst.StatusURL(), c.duration),
 				timeout:  time.Second * time.Duration(c.duration+3),
 			})
@@ -198,7 +259,7 @@ func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *Per
 		// mem Heap
 		perfConfigs = append(perfConfigs,
 			perfConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, "mem_heap.proto"),
+				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, roundDir, "mem_heap.proto"),
 				url:      fmt.Sprintf("%s/debug/pprof/heap", inst.StatusURL()),
 				timeout:  time.Second * time.Duration(c.duration),
 			})
@@ -206,7 +267,7 @@ func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *Per
 		// Goroutine
 		perfConfigs = append(perfConfigs,
 			perfConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, "goroutine.txt"),
+				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, roundDir, "goroutine.txt"),
 				url:      fmt.Sprintf("%s/debug/pprof/goroutine?debug=1", inst.StatusURL()),
 				timeout:  time.Second * time.Duration(c.duration),
 			})
@@ -214,7 +275,7 @@ func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *Per
 		// mutex
 		perfConfigs = append(perfConfigs,
 			perfConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, "mutex.txt"),
+				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, roundDir, "mutex.txt"),
 				url:      fmt.Sprintf("%s/debug/pprof/mutex?debug=1", inst.StatusURL()),
 				timeout:  time.Second * time.Duration(c.duration),
 			})
@@ -223,7 +284,7 @@ func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *Per
 		// cpu profile
 		perfConfigs = append(perfConfigs,
 			perfConfig{
-				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, "cpu_profile.proto"),
+				filepath: filepath.Join(c.resultDir, host, instDir, CollectTypePerf, roundDir, "cpu_profile.proto"),
 				url:      fmt.Sprintf("%s/debug/pprof/profile?seconds=%d", inst.StatusURL(), c.duration),
 				timeout:  time.Second * time.Duration(c.duration+3),
 				header:   map[string]string{"Content-Type": "application/protobuf"},
@@ -244,21 +305,33 @@ func buildPerfCollectingTasks(ctx context.Context, inst models.Component, c *Per
 		Func(
 			fmt.Sprintf("querying %s:%d", host, inst.MainPort()),
 			func(ctx context.Context) error {
+				// the cpu profile is the first one, others are taken during
+				// it if the concurrency allows
+				errg, ctx := errgroup.WithContext(ctx)
+				if c.concurrency > 0 {
+					errg.SetLimit(c.concurrency)
+				} else {
+					errg.SetLimit(1)
+				}
 				for _, config := range perfConfigs {
-					c := utils.NewHTTPClient(config.timeout, c.tlsCfg)
-					if config.header != nil {
-						for k, v := range config.header {
-							c.SetRequestHeader(k, v)
+					config := config
+					errg.Go(func() error {
+						c := utils.NewHTTPClient(config.timeout, c.tlsCfg)
+						if config.header != nil {
+							for k, v := range config.header {
+								c.SetRequestHeader(k, v)
+							}
+						}
+						url := fmt.Sprintf("%s://%s", scheme, config.url)
+						err := c.Download(ctx, url, config.filepath)
+						if err != nil {
+							logger.Warnf("fail querying perf info %s: %s, continue", url, err)
+							return err
 						}
-					}
-					url := fmt.Sprintf("%s://%s", scheme, config.url)
-					err := c.Download(ctx, url, config.filepath)
-					if err != nil {
-						logger.Warnf("fail querying perf info %s: %s, continue", url, err)
-						return err
-					}
+						return nil
+					})
 				}
-				return nil
+				return errg.Wait()
 			},
 		).
 		BuildAsStep(fmt.Sprintf(
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/diag/pkg/models"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProfileServer serves the pprof API, the cpu profile takes 100ms
+func newTestProfileServer(t *testing.T) (*httptest.Server, int, func() int) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if r.URL.Path == "/debug/pprof/profile" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	t.Cleanup(srv.Close)
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.Nil(t, err)
+	p, err := strconv.Atoi(port)
+	require.Nil(t, err)
+	return srv, p, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return maxRunning
+	}
+}
+
+func TestPerfCollect(t *testing.T) {
+	assert := require.New(t)
+
+	_, tidbPort, tidbMax := newTestProfileServer(t)
+	_, tikvPort, _ := newTestProfileServer(t)
+	topo := &models.TiDBCluster{
+		Version: "v6.5.0",
+		TiDB:    []*models.TiDBSpec{{ComponentSpec: models.ComponentSpec{Host: "127.0.0.1", StatusPort: tidbPort}}},
+		TiKV:    []*models.TiKVSpec{{ComponentSpec: models.ComponentSpec{Host: "localhost", StatusPort: tikvPort}}},
+	}
+	m := &Manager{logger: logprinter.NewLogger("")}
+
+	// profiles of each round are saved in its own dir
+	dir := t.TempDir()
+	c := &PerfCollectOptions{
+		BaseOptions: &BaseOptions{},
+		opt:         &operator.Options{},
+		duration:    1,
+		count:       2,
+		concurrency: 1,
+		resultDir:   dir,
+		fileStats:   make(map[string][]CollectStat),
+	}
+	stats, err := c.Prepare(m, topo)
+	assert.Nil(err)
+	assert.Len(stats, 2)
+	assert.Nil(c.Collect(m, topo))
+	assert.Equal(1, tidbMax())
+
+	rounds, err := os.ReadDir(filepath.Join(dir, "127.0.0.1", CollectTypePerf))
+	assert.Nil(err)
+	assert.Len(rounds, 2)
+	for _, name := range []string{"cpu_profile.proto", "mem_heap.proto", "goroutine.txt", "mutex.txt"} {
+		_, err := os.Stat(filepath.Join(dir, "127.0.0.1", CollectTypePerf, rounds[1].Name(), name))
+		assert.Nil(err, name)
+	}
+	kvRounds, err := os.ReadDir(filepath.Join(dir, "localhost", CollectTypePerf))
+	assert.Nil(err)
+	assert.Len(kvRounds, 2)
+	// the same round dir for all instances
+	assert.Equal(rounds[0].Name(), kvRounds[0].Name())
+
+	// a single round is saved in the perf dir directly, other profiles are
+	// taken during the cpu profile
+	dir = t.TempDir()
+	c.count, c.concurrency, c.resultDir = 1, 4, dir
+	assert.Nil(c.Collect(m, topo))
+	assert.Greater(tidbMax(), 1)
+	_, err = os.Stat(filepath.Join(dir, "127.0.0.1", CollectTypePerf, "cpu_profile.proto"))
+	assert.Nil(err)
+
+	// old TiKV is skipped
+	topo.Version = "v4.0.16"
+	assert.Len(c.instances(topo), 1)
+	assert.True(supportRustProfile("nightly"))
+}
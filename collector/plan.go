@@ -0,0 +1,159 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// keys of CollectStat.Attributes describing the estimation in detail, a stat
+// without the files attribute is a single file
+const (
+	StatAttrFiles    = "files"     // number of files to write
+	StatAttrSeries   = "series"    // number of metric series
+	StatAttrAPICalls = "api_calls" // number of API requests or SQL queries
+	StatAttrDuration = "duration"  // estimated seconds, not including the transfer time
+)
+
+// HostPlan is the estimation of the data collected from one host
+type HostPlan struct {
+	Host     string   `json:"host"`
+	Targets  []string `json:"targets"`
+	Files    int64    `json:"files"`
+	Size     int64    `json:"size"`
+	Series   int64    `json:"series,omitempty"`
+	APICalls int64    `json:"api_calls,omitempty"`
+	Duration int64    `json:"duration"` // seconds
+}
+
+// CollectorPlan is the estimation of the data collected by one collector
+type CollectorPlan struct {
+	Collector string     `json:"collector"`
+	Hosts     []HostPlan `json:"hosts"`
+	Duration  int64      `json:"duration"` // seconds
+	Error     string     `json:"error,omitempty"`
+}
+
+// CollectPlan is the result of a dry run, it lists what would be collected
+// without collecting anything
+type CollectPlan struct {
+	Cluster    string          `json:"cluster"`
+	Dir        string          `json:"dir"`
+	Begin      string          `json:"begin"`
+	End        string          `json:"end"`
+	Collectors []CollectorPlan `json:"collectors"`
+	Files      int64           `json:"files"`
+	Size       int64           `json:"size"`
+	Series     int64           `json:"series,omitempty"`
+	APICalls   int64           `json:"api_calls,omitempty"`
+	Duration   int64           `json:"duration"` // seconds
+}
+
+// AddCollector adds the stats returned by Prepare of a collector to the
+// plan, speedLimit is the bandwidth limit in Kbit/s used to estimate the
+// transfer time of files, which is not estimated if it is not positive.
+// Stats of a host and hosts of a collector are assumed to be collected at
+// the same time, while collectors run one by one, so the duration of a host
+// is the longest of its stats plus the transfer time, and the duration of a
+// collector is the longest of its hosts.
+func (p *CollectPlan) AddCollector(desc string, stats map[string][]CollectStat, prepareErr error, speedLimit int) {
+	cp := CollectorPlan{
+		Collector: desc,
+		Hosts:     make([]HostPlan, 0, len(stats)),
+	}
+	if prepareErr != nil {
+		cp.Error = prepareErr.Error()
+	}
+
+	hosts := make([]string, 0, len(stats))
+	for host := range stats {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		items := stats[host]
+		if len(items) < 1 {
+			continue
+		}
+		hp := HostPlan{
+			Host:    host,
+			Targets: make([]string, 0, len(items)),
+		}
+		for _, s := range items {
+			hp.Targets = append(hp.Targets, s.Target)
+			if files, ok := statAttrInt(s, StatAttrFiles); ok {
+				hp.Files += files
+			} else {
+				hp.Files++
+			}
+			hp.Size += s.Size
+			series, _ := statAttrInt(s, StatAttrSeries)
+			hp.Series += series
+			calls, _ := statAttrInt(s, StatAttrAPICalls)
+			hp.APICalls += calls
+			duration, _ := statAttrInt(s, StatAttrDuration)
+			hp.Duration = max(hp.Duration, duration)
+		}
+		if speedLimit > 0 {
+			hp.Duration += hp.Size * 8 / 1024 / int64(speedLimit)
+		}
+
+		p.Files += hp.Files
+		p.Size += hp.Size
+		p.Series += hp.Series
+		p.APICalls += hp.APICalls
+		cp.Duration = max(cp.Duration, hp.Duration)
+		cp.Hosts = append(cp.Hosts, hp)
+	}
+	p.Duration += cp.Duration
+	p.Collectors = append(p.Collectors, cp)
+}
+
+// statAttrInt reads a numeric attribute of the stat
+func statAttrInt(s CollectStat, key string) (int64, bool) {
+	switch v := s.Attributes[key].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// planHostPaths lists the paths to scrape on each host without estimating
+// their sizes, it is used in dry runs instead of copying the scraper to the
+// hosts and running it there
+func planHostPaths(hostPaths map[string]set.StringSet, kind string) map[string][]CollectStat {
+	stats := make(map[string][]CollectStat, len(hostPaths))
+	for host, paths := range hostPaths {
+		list := paths.Slice()
+		sort.Strings(list)
+		for _, p := range list {
+			stats[host] = append(stats[host], CollectStat{
+				Target: fmt.Sprintf("%s in %s, size not estimated", kind, p),
+				Attributes: map[string]interface{}{
+					StatAttrFiles: 0,
+				},
+			})
+		}
+	}
+	return stats
+}
@@ -41,6 +41,7 @@ type PlanReplayerCollectorOptions struct {
 	tablesAndViews map[table]struct{}
 	resultDir      string
 	tlsCfg         *tls.Config
+	sqlTLSCfg      *tls.Config // TLS config of the SQL port of TiDB
 	currDB         string
 }
 
@@ -70,8 +71,11 @@ func (c *PlanReplayerCollectorOptions) SetDir(dir string) {
 }
 
 // Prepare implements the Collector interface
-func (c *PlanReplayerCollectorOptions) Prepare(_ *Manager, _ *models.TiDBCluster) (map[string][]CollectStat, error) {
-	return nil, nil
+func (c *PlanReplayerCollectorOptions) Prepare(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+	// explain of each sql and the variables, queries of the schema and stats
+	// of tables are not counted as tables are unknown before parsing the sqls
+	return sqlStats(topo, fmt.Sprintf("plan_replayer of %d sqls", len(c.sqls)),
+		1, len(c.sqls)+1, int64(len(c.sqls))*1024*1024), nil
 }
 
 // Collect implements the Collector interface
@@ -430,17 +434,7 @@ func (c *PlanReplayerCollectorOptions) collectSQLExplain(zw *zip.Writer, db *sql
 }
 
 func (c *PlanReplayerCollectorOptions) getDB(inst *models.TiDBSpec) (*sql.DB, error) {
-	trydb, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		c.dbuser, c.dbpasswd, inst.Host(), inst.MainPort(), c.currDB))
-	if err != nil {
-		return nil, err
-	}
-	err = trydb.Ping()
-	if err != nil {
-		defer trydb.Close()
-		return nil, err
-	}
-	return trydb, nil
+	return openTiDBInstance(inst, c.dbuser, c.dbpasswd, c.currDB, c.sqlTLSCfg)
 }
 
 func (c *PlanReplayerCollectorOptions) getDBFromTopo(tidbInstants []*models.TiDBSpec) (*models.TiDBSpec, *sql.DB) {
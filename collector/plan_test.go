@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectPlan(t *testing.T) {
+	assert := require.New(t)
+
+	plan := &CollectPlan{Cluster: "test"}
+	plan.AddCollector("logs", map[string][]CollectStat{
+		"host2": {{Target: "tikv.log", Size: 1024 * 1024}},
+		"host1": {
+			{Target: "tidb.log", Size: 1024 * 1024},
+			{Target: "tidb_slow_query.log", Size: 1024 * 1024},
+		},
+		"host3": nil,
+	}, nil, 1024)
+	plan.AddCollector("perf", map[string][]CollectStat{
+		"host1": {
+			{Target: "tidb perf", Size: 100, Attributes: map[string]interface{}{StatAttrFiles: 4, StatAttrAPICalls: 4, StatAttrDuration: 30}},
+			{Target: "tikv perf", Size: 100, Attributes: map[string]interface{}{StatAttrFiles: 1, StatAttrAPICalls: 1, StatAttrDuration: 30}},
+		},
+	}, errors.New("tikv is not supported"), 0)
+	plan.AddCollector("metrics", nil, nil, 0)
+
+	assert.Len(plan.Collectors, 3)
+	logs := plan.Collectors[0]
+	assert.Len(logs.Hosts, 2)
+	assert.Equal("host1", logs.Hosts[0].Host)
+	assert.Equal([]string{"tidb.log", "tidb_slow_query.log"}, logs.Hosts[0].Targets)
+	assert.Equal(int64(2), logs.Hosts[0].Files)
+	// 16 seconds transferring 2MB at 1024Kbit/s
+	assert.Equal(int64(16), logs.Hosts[0].Duration)
+	assert.Equal(int64(16), logs.Duration)
+
+	perf := plan.Collectors[1]
+	assert.Equal("tikv is not supported", perf.Error)
+	assert.Equal(int64(5), perf.Hosts[0].Files)
+	assert.Equal(int64(5), perf.Hosts[0].APICalls)
+	// profiles are taken at the same time
+	assert.Equal(int64(30), perf.Duration)
+
+	assert.Empty(plan.Collectors[2].Hosts)
+	assert.Equal(int64(8), plan.Files)
+	assert.Equal(int64(3*1024*1024+200), plan.Size)
+	assert.Equal(int64(5), plan.APICalls)
+	assert.Equal(int64(46), plan.Duration)
+}
+
+func TestPlanHostPaths(t *testing.T) {
+	assert := require.New(t)
+
+	stats := planHostPaths(map[string]set.StringSet{
+		"host1": set.NewStringSet("/tikv/log/*", "/tidb/log/*"),
+	}, "log files")
+	assert.Len(stats["host1"], 2)
+	assert.Equal("log files in /tidb/log/*, size not estimated", stats["host1"][0].Target)
+
+	plan := &CollectPlan{}
+	plan.AddCollector("logs", stats, nil, 0)
+	assert.Equal([]string{
+		"log files in /tidb/log/*, size not estimated",
+		"log files in /tikv/log/*, size not estimated",
+	}, plan.Collectors[0].Hosts[0].Targets)
+	assert.Zero(plan.Files)
+	assert.Zero(plan.Size)
+}
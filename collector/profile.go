@@ -20,9 +20,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pingcap/diag/pkg/utils"
 	"github.com/pingcap/diag/pkg/utils/toml"
-
-	tiuplocaldata "github.com/pingcap/tiup/pkg/localdata"
 )
 
 // ProfileDirectoryName is the sub-path name storing profile config files
@@ -30,14 +29,15 @@ const ProfileDirectoryName = "profiles"
 
 // CollectProfile is a pre-defined configuration of collecting jobs
 type CollectProfile struct {
-	Name          string   `toml:"name"` // name of the profile
-	Version       string   `toml:"version"`
-	Maintainers   []string `toml:"maintainers,omitempty"`
-	Description   string   `toml:"description,omitempty"`
-	Collectors    []string `toml:"collectors,omitempty"`
-	Roles         []string `toml:"roles,omitempty"`
-	MetricFilters []string `toml:"metric_filters,omitempty"`
-	StripLabels   []string `toml:"strip_labels,omitempty"`
+	Name          string     `toml:"name"` // name of the profile
+	Version       string     `toml:"version"`
+	Maintainers   []string   `toml:"maintainers,omitempty"`
+	Description   string     `toml:"description,omitempty"`
+	Collectors    []string   `toml:"collectors,omitempty"`
+	Roles         []string   `toml:"roles,omitempty"`
+	MetricFilters []string   `toml:"metric_filters,omitempty"`
+	StripLabels   []string   `toml:"strip_labels,omitempty"`
+	SQLQueries    []SQLQuery `toml:"sql_queries,omitempty"` // extra queries of the sql_snapshot collector
 }
 
 // readProfile tries to load a CollectProfile from file
@@ -65,18 +65,9 @@ func readProfile(name string) (*CollectProfile, error) {
 // readProfileFromDataDir tries to load a user defined profile file
 func readProfileFromDataDir(name string) (*CollectProfile, error) {
 	// try ~/.tiup/storage/diag/profiles/<name>.toml
-	tiupData := os.Getenv(tiuplocaldata.EnvNameComponentDataDir)
-	if tiupData == "" {
-		tiupHome := os.Getenv(tiuplocaldata.EnvNameHome)
-		if tiupHome != "" {
-			tiupData = filepath.Join(tiupHome, tiuplocaldata.StorageParentDir, "diag")
-		} else {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return nil, err
-			}
-			tiupData = filepath.Join(homeDir, ".tiup", tiuplocaldata.StorageParentDir, "diag")
-		}
+	tiupData, err := utils.DiagDataDir()
+	if err != nil {
+		return nil, err
 	}
 	fp := filepath.Join(
 		tiupData,
@@ -0,0 +1,212 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	json "github.com/json-iterator/go"
+	perrs "github.com/pingcap/errors"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+)
+
+// CollectProgressName is the file recording finished parts of a collection,
+// it is kept together with the lock file so that an interrupted collection
+// could be resumed
+const CollectProgressName = ".collect.progress"
+
+// keys of collectors that record progress of each target
+const (
+	progressKeyMetric = "metric"
+	progressKeyLog    = "log"
+)
+
+// progressRecord is one line of the progress file, an empty target means
+// the whole collector is finished, a removed record cancels the previous
+// record of the target
+type progressRecord struct {
+	Collector string `json:"collector"`
+	Target    string `json:"target,omitempty"`
+	Removed   bool   `json:"removed,omitempty"`
+}
+
+// collectProgress tracks finished collectors and targets (metric blocks,
+// log files, etc.) of a collection, records are appended to the progress
+// file as soon as they are done
+type collectProgress struct {
+	sync.Mutex
+	file       *os.File
+	collectors map[string]struct{}
+	targets    map[string]map[string]struct{}
+}
+
+// loadCollectProgress reads the progress file in the result dir if it exists,
+// and opens it for appending new records
+func loadCollectProgress(resultDir string) (*collectProgress, error) {
+	p := &collectProgress{
+		collectors: make(map[string]struct{}),
+		targets:    make(map[string]map[string]struct{}),
+	}
+
+	fp := filepath.Join(resultDir, CollectProgressName)
+	data, err := os.ReadFile(fp)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		var r progressRecord
+		// ignore broken lines, e.g., the last one written when interrupted
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			continue
+		}
+		p.add(r)
+	}
+
+	f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// terminate the broken line so that new records are not appended to it
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		f.Write([]byte{'\n'})
+	}
+	p.file = f
+	return p, nil
+}
+
+func (p *collectProgress) add(r progressRecord) {
+	if r.Removed {
+		delete(p.targets[r.Collector], r.Target)
+		return
+	}
+	if r.Target == "" {
+		p.collectors[r.Collector] = struct{}{}
+		return
+	}
+	if _, ok := p.targets[r.Collector]; !ok {
+		p.targets[r.Collector] = make(map[string]struct{})
+	}
+	p.targets[r.Collector][r.Target] = struct{}{}
+}
+
+func (p *collectProgress) record(r progressRecord) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.add(r)
+	if p.file == nil {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	p.file.Write(append(data, '\n'))
+}
+
+// CollectorDone checks if a collector is already finished, it is safe to
+// call on a nil progress
+func (p *collectProgress) CollectorDone(collector string) bool {
+	if p == nil {
+		return false
+	}
+	p.Lock()
+	defer p.Unlock()
+	_, ok := p.collectors[collector]
+	return ok
+}
+
+// TargetDone checks if a target of a collector is already finished
+func (p *collectProgress) TargetDone(collector, target string) bool {
+	if p == nil {
+		return false
+	}
+	p.Lock()
+	defer p.Unlock()
+	_, ok := p.targets[collector][target]
+	return ok
+}
+
+// FinishCollector marks a collector as finished
+func (p *collectProgress) FinishCollector(collector string) {
+	if p == nil {
+		return
+	}
+	p.record(progressRecord{Collector: collector})
+}
+
+// FinishTarget marks a target of a collector as finished
+func (p *collectProgress) FinishTarget(collector, target string) {
+	if p == nil {
+		return
+	}
+	p.record(progressRecord{Collector: collector, Target: target})
+}
+
+// UnfinishTarget marks a finished target of a collector as not finished,
+// e.g., its data is removed to be collected in another way
+func (p *collectProgress) UnfinishTarget(collector, target string) {
+	if p == nil || !p.TargetDone(collector, target) {
+		return
+	}
+	p.record(progressRecord{Collector: collector, Target: target, Removed: true})
+}
+
+// Close closes the progress file
+func (p *collectProgress) Close() {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	if p.file != nil {
+		p.file.Close()
+		p.file = nil
+	}
+}
+
+// prepareResume restores arguments of an interrupted collection from the
+// metadata saved in its result dir
+func (m *Manager) prepareResume(opt *BaseOptions, cOpt *CollectOptions) error {
+	if cOpt.Dir == "" {
+		return fmt.Errorf("the directory of the interrupted collection is not specified")
+	}
+	if tiuputils.IsNotExist(filepath.Join(cOpt.Dir, CollectLockName)) {
+		return fmt.Errorf("%s is not an interrupted collection, lock file %s not found", cOpt.Dir, CollectLockName)
+	}
+	info, err := GetClusterInfoFromFile(cOpt.Dir)
+	if err != nil {
+		return perrs.Annotate(err, "failed to read metadata of the interrupted collection")
+	}
+	if opt.Cluster != "" && info.ClusterName != opt.Cluster {
+		return fmt.Errorf("%s is collected from cluster %s, not %s", cOpt.Dir, info.ClusterName, opt.Cluster)
+	}
+
+	cOpt.Collectors, err = ParseCollectTree(info.Collectors, nil)
+	if err != nil {
+		return err
+	}
+	opt.Cluster = info.ClusterName
+	opt.ScrapeBegin = info.BeginTime
+	opt.ScrapeEnd = info.EndTime
+	m.session = info.Session
+	m.resume = true
+	return nil
+}
@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectProgress(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	p, err := loadCollectProgress(dir)
+	assert.Nil(err)
+	assert.False(p.CollectorDone("logs of components"))
+	p.FinishCollector("logs of components")
+	p.FinishTarget(progressKeyMetric, "up-1.json")
+	p.Close()
+
+	// append a broken record as if the process was killed while writing
+	f, err := os.OpenFile(filepath.Join(dir, CollectProgressName), os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(err)
+	_, err = f.WriteString(`{"collector":"metr`)
+	assert.Nil(err)
+	f.Close()
+
+	p, err = loadCollectProgress(dir)
+	assert.Nil(err)
+	assert.True(p.CollectorDone("logs of components"))
+	assert.False(p.CollectorDone(progressKeyMetric))
+	assert.True(p.TargetDone(progressKeyMetric, "up-1.json"))
+	assert.False(p.TargetDone(progressKeyMetric, "up-2.json"))
+	p.FinishTarget(progressKeyMetric, "up-2.json")
+	p.Close()
+
+	p, err = loadCollectProgress(dir)
+	assert.Nil(err)
+	assert.True(p.TargetDone(progressKeyMetric, "up-2.json"))
+	p.UnfinishTarget(progressKeyMetric, "up-1.json")
+	assert.False(p.TargetDone(progressKeyMetric, "up-1.json"))
+	p.Close()
+
+	p, err = loadCollectProgress(dir)
+	assert.Nil(err)
+	defer p.Close()
+	assert.False(p.TargetDone(progressKeyMetric, "up-1.json"))
+	assert.True(p.TargetDone(progressKeyMetric, "up-2.json"))
+
+	// nil progress is used when the collection is not tracked
+	var np *collectProgress
+	assert.False(np.TargetDone(progressKeyLog, "a"))
+	np.FinishTarget(progressKeyLog, "a")
+}
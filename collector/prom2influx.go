@@ -135,10 +135,19 @@ func LoadMetrics(ctx context.Context, dataDir string, opt *RebuildOptions) error
 }
 
 func (opt *RebuildOptions) LoadMetrics(client influx.Client) error {
-	f, err := os.Open(opt.File)
+	data, err := readMetricDump(opt.File)
 	if err != nil {
 		return err
 	}
+	return writeBatchPoints(client, *data, opt)
+}
+
+// readMetricDump reads a dumped metric file, which may be compressed
+func readMetricDump(file string) (*promDump, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
 
 	var input []byte
@@ -158,17 +167,15 @@ func (opt *RebuildOptions) LoadMetrics(client influx.Client) error {
 		input, readErr = io.ReadAll(f)
 	}
 	if readErr != nil {
-		return err
+		return nil, readErr
 	}
 
 	// decode JSON
 	var data promDump
 	if err = json.Unmarshal(input, &data); err != nil {
-		//fmt.Println(string(input))
-		return err
+		return nil, err
 	}
-
-	return writeBatchPoints(client, data, opt)
+	return &data, nil
 }
 
 type promResult struct {
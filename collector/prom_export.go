@@ -0,0 +1,327 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/tiup/pkg/tui/progress"
+	"github.com/prometheus/common/model"
+)
+
+// formats of exported metrics
+const (
+	MetricExportOpenMetrics = "openmetrics"
+	MetricExportTSDB        = "tsdb"
+)
+
+// MetricExportOptions are options of converting dumped metrics
+type MetricExportOptions struct {
+	Format      string   // openmetrics or tsdb
+	Output      string   // output file of openmetrics, or the storage dir of tsdb blocks
+	StripLabels []string // label names to strip from metrics
+}
+
+type metricExporter interface {
+	write(name string, series []*model.SampleStream) error
+	close() (string, error)
+	abort()
+}
+
+// ExportMetrics converts the dumped metric JSON files to OpenMetrics text or
+// a TSDB block that could be loaded by Prometheus directly
+func ExportMetrics(dataDir string, opt *MetricExportOptions) (string, error) {
+	promDir, metrics, err := listMetricDumps(dataDir)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var exporter metricExporter
+	switch opt.Format {
+	case MetricExportOpenMetrics:
+		exporter, err = newOpenMetricsExporter(opt.Output)
+	case MetricExportTSDB:
+		if err = os.MkdirAll(opt.Output, 0755); err == nil {
+			exporter, err = newTSDBBlockWriter(opt.Output)
+		}
+	default:
+		return "", fmt.Errorf("unknown format '%s', valid formats are: %s, %s",
+			opt.Format, MetricExportOpenMetrics, MetricExportTSDB)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	mb := progress.NewMultiBar("Exporting metrics")
+	bar := mb.AddBar(promDir)
+	mb.StartRenderLoop()
+	defer mb.StopRenderLoop()
+
+	for i, name := range names {
+		bar.UpdateDisplay(&progress.DisplayProps{
+			Prefix: fmt.Sprintf(" - Exporting metrics from %s", promDir),
+			Suffix: fmt.Sprintf("%d/%d: %s", i+1, len(names), name),
+		})
+		series, err := loadMetricSeries(name, metrics[name], opt.StripLabels)
+		if err == nil {
+			err = exporter.write(name, series)
+		}
+		if err != nil {
+			exporter.abort()
+			bar.UpdateDisplay(&progress.DisplayProps{
+				Prefix: fmt.Sprintf(" - Export metrics from %s", promDir),
+				Suffix: err.Error(),
+				Mode:   progress.ModeError,
+			})
+			return "", err
+		}
+	}
+
+	output, err := exporter.close()
+	if err != nil {
+		return "", err
+	}
+	bar.UpdateDisplay(&progress.DisplayProps{
+		Prefix: fmt.Sprintf(" - Export metrics from %s", promDir),
+		Mode:   progress.ModeDone,
+	})
+	return output, nil
+}
+
+// listMetricDumps finds dumped metric files of the first Prometheus instance
+// in the data set and groups them by metric names, as a metric may be dumped
+// to multiple files
+func listMetricDumps(dataDir string) (string, map[string][]string, error) {
+	promsDir := filepath.Join(dataDir, subdirMonitor, subdirMetrics)
+	proms, err := os.ReadDir(promsDir)
+	if err != nil {
+		return "", nil, err
+	}
+	var promDir string
+	for _, p := range proms {
+		if !p.IsDir() {
+			continue
+		}
+		if promDir != "" {
+			fmt.Println(color.YellowString("Multiple folders were found under %s, only pick %s", promsDir, promDir))
+			break
+		}
+		promDir = p.Name()
+	}
+	if promDir == "" {
+		return "", nil, fmt.Errorf("cannot find metrics on %s", promsDir)
+	}
+
+	files, err := os.ReadDir(filepath.Join(promsDir, promDir))
+	if err != nil {
+		return "", nil, err
+	}
+	metrics := make(map[string][]string)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		name := strings.SplitN(f.Name(), "-", 2)[0]
+		metrics[name] = append(metrics[name], filepath.Join(promsDir, promDir, f.Name()))
+	}
+	if len(metrics) == 0 {
+		return "", nil, fmt.Errorf("cannot find metrics on %s", filepath.Join(promsDir, promDir))
+	}
+	return promDir, metrics, nil
+}
+
+// loadMetricSeries reads all files of a metric and merges samples of the
+// same series, the result is sorted by labels and samples are sorted by time
+func loadMetricSeries(name string, files []string, stripLabels []string) ([]*model.SampleStream, error) {
+	merged := make(map[string]*model.SampleStream)
+	for _, file := range files {
+		data, err := readMetricDump(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", file, err)
+		}
+		for _, s := range data.Data.Result {
+			m := s.Metric.Clone()
+			for _, label := range stripLabels {
+				delete(m, model.LabelName(label))
+			}
+			for k, v := range m {
+				if v == "" {
+					delete(m, k)
+				}
+			}
+			m[model.MetricNameLabel] = model.LabelValue(name)
+			key := m.String()
+			if ms, ok := merged[key]; ok {
+				ms.Values = append(ms.Values, s.Values...)
+			} else {
+				merged[key] = &model.SampleStream{Metric: m, Values: s.Values}
+			}
+		}
+	}
+
+	series := make([]*model.SampleStream, 0, len(merged))
+	for _, s := range merged {
+		sort.SliceStable(s.Values, func(i, j int) bool {
+			return s.Values[i].Timestamp < s.Values[j].Timestamp
+		})
+		// files may overlap at the boundary
+		values := s.Values[:0]
+		for i, v := range s.Values {
+			if i > 0 && v.Timestamp == s.Values[i-1].Timestamp {
+				continue
+			}
+			values = append(values, v)
+		}
+		s.Values = values
+		series = append(series, s)
+	}
+	labels := make(map[*model.SampleStream][]tsdbLabel, len(series))
+	for _, s := range series {
+		labels[s] = sortedLabels(s.Metric)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return compareTSDBLabels(labels[series[i]], labels[series[j]]) < 0
+	})
+	return series, nil
+}
+
+func sortedLabels(m model.Metric) []tsdbLabel {
+	labels := make([]tsdbLabel, 0, len(m))
+	for k, v := range m {
+		labels = append(labels, tsdbLabel{name: string(k), value: string(v)})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels
+}
+
+func (w *tsdbBlockWriter) write(name string, series []*model.SampleStream) error {
+	for _, s := range series {
+		if err := w.addSeries(sortedLabels(s.Metric), s.Values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openMetricsExporter writes metrics in the OpenMetrics text format, all
+// metrics are typed as unknown as the type is not dumped
+type openMetricsExporter struct {
+	path string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+func newOpenMetricsExporter(path string) (*openMetricsExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &openMetricsExporter{path: path, f: f, w: bufio.NewWriterSize(f, 1<<20)}, nil
+}
+
+func (e *openMetricsExporter) write(name string, series []*model.SampleStream) error {
+	if _, err := fmt.Fprintf(e.w, "# TYPE %s unknown\n", name); err != nil {
+		return err
+	}
+	for _, s := range series {
+		if err := writeOpenMetricsSeries(e.w, name, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *openMetricsExporter) close() (string, error) {
+	_, err := io.WriteString(e.w, "# EOF\n")
+	if err == nil {
+		err = e.w.Flush()
+	}
+	if cerr := e.f.Close(); err == nil {
+		err = cerr
+	}
+	return e.path, err
+}
+
+func (e *openMetricsExporter) abort() {
+	e.f.Close()
+	os.Remove(e.path)
+}
+
+func writeOpenMetricsSeries(w io.Writer, name string, s *model.SampleStream) error {
+	var b strings.Builder
+	b.WriteString(name)
+	labels := sortedLabels(s.Metric)
+	sep := "{"
+	for _, l := range labels {
+		if l.name == model.MetricNameLabel {
+			continue
+		}
+		b.WriteString(sep)
+		b.WriteString(l.name)
+		b.WriteString(`="`)
+		b.WriteString(escapeOpenMetricsLabel(l.value))
+		b.WriteString(`"`)
+		sep = ","
+	}
+	if sep == "," {
+		b.WriteString("}")
+	}
+	prefix := b.String()
+
+	for _, v := range s.Values {
+		// timestamps are in seconds
+		ts := strconv.FormatFloat(float64(v.Timestamp)/1000, 'f', -1, 64)
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", prefix, formatOpenMetricsValue(float64(v.Value)), ts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var openMetricsLabelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeOpenMetricsLabel(v string) string {
+	return openMetricsLabelEscaper.Replace(v)
+}
+
+func formatOpenMetricsValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
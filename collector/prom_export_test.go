@@ -0,0 +1,268 @@
+package collector
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestMetricDump(t *testing.T, file string, compress bool, series ...*model.SampleStream) {
+	assert := require.New(t)
+
+	data, err := json.Marshal(promDump{
+		Status: "success",
+		Data:   promResult{ResultType: "matrix", Result: series},
+	})
+	assert.Nil(err)
+	if compress {
+		enc, err := zstd.NewWriter(nil)
+		assert.Nil(err)
+		data = enc.EncodeAll(data, nil)
+	}
+	assert.Nil(os.WriteFile(file, data, 0644))
+}
+
+func genTestMetricDumps(t *testing.T) string {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	promDir := filepath.Join(dir, subdirMonitor, subdirMetrics, "127.0.0.1-9090")
+	assert.Nil(os.MkdirAll(promDir, 0755))
+
+	samples := make([]model.SamplePair, 0)
+	for i := 0; i < 300; i++ {
+		samples = append(samples, model.SamplePair{Timestamp: model.Time(1000 + i*15000), Value: model.SampleValue(i)})
+	}
+	// the boundary sample is dumped in both files
+	writeTestMetricDump(t, filepath.Join(promDir, "up-a-b.json"), true,
+		&model.SampleStream{Metric: model.Metric{"__name__": "up", "instance": "b", "job": "tikv"}, Values: samples[:151]},
+		&model.SampleStream{Metric: model.Metric{"__name__": "up", "instance": "a", "job": "tidb"}, Values: samples[:2]},
+	)
+	writeTestMetricDump(t, filepath.Join(promDir, "up-b-c.json"), false,
+		&model.SampleStream{Metric: model.Metric{"__name__": "up", "instance": "b", "job": "tikv"}, Values: samples[150:]},
+	)
+	writeTestMetricDump(t, filepath.Join(promDir, "tidb_server_info-a-b.json"), false,
+		&model.SampleStream{Metric: model.Metric{"__name__": "tidb_server_info", "version": "v\"8\"\n"}, Values: samples[:1]},
+	)
+	return dir
+}
+
+func TestExportOpenMetrics(t *testing.T) {
+	assert := require.New(t)
+
+	dir := genTestMetricDumps(t)
+	output := filepath.Join(t.TempDir(), "metrics.txt")
+	path, err := ExportMetrics(dir, &MetricExportOptions{
+		Format:      MetricExportOpenMetrics,
+		Output:      output,
+		StripLabels: []string{"job"},
+	})
+	assert.Nil(err)
+	assert.Equal(output, path)
+
+	data, err := os.ReadFile(output)
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	assert.Len(lines, 1+1+1+2+300+1)
+	assert.Equal([]string{
+		"# TYPE tidb_server_info unknown",
+		`tidb_server_info{version="v\"8\"\n"} 0 1`,
+		"# TYPE up unknown",
+		`up{instance="a"} 0 1`,
+		`up{instance="a"} 1 16`,
+		`up{instance="b"} 0 1`,
+	}, lines[:6])
+	assert.Equal(`up{instance="b"} 299 4486`, lines[len(lines)-2])
+	assert.Equal("# EOF", lines[len(lines)-1])
+
+	_, err = ExportMetrics(dir, &MetricExportOptions{Format: "influx", Output: output})
+	assert.NotNil(err)
+}
+
+// readTestTSDBBlock reads all series in a block with the all postings list
+func readTestTSDBBlock(t *testing.T, dir string) map[string][]model.SamplePair {
+	assert := require.New(t)
+
+	index, err := os.ReadFile(filepath.Join(dir, "index"))
+	assert.Nil(err)
+	chunks, err := os.ReadFile(filepath.Join(dir, "chunks", "000001"))
+	assert.Nil(err)
+	assert.Equal(uint32(tsdbMagicIndex), binary.BigEndian.Uint32(index))
+	assert.Equal(uint32(tsdbMagicChunks), binary.BigEndian.Uint32(chunks))
+
+	tocData := index[len(index)-6*8-4:]
+	assert.Equal(crc32.Checksum(tocData[:6*8], castagnoliTable), binary.BigEndian.Uint32(tocData[6*8:]))
+	section := func(off uint64) []byte {
+		l := binary.BigEndian.Uint32(index[off:])
+		content := index[off+4 : off+4+uint64(l)]
+		assert.Equal(crc32.Checksum(content, castagnoliTable), binary.BigEndian.Uint32(index[off+4+uint64(l):]))
+		return content
+	}
+	uvarint := func(b []byte) (uint64, []byte) {
+		v, n := binary.Uvarint(b)
+		assert.Greater(n, 0)
+		return v, b[n:]
+	}
+	str := func(b []byte) (string, []byte) {
+		l, b := uvarint(b)
+		return string(b[:l]), b[l:]
+	}
+
+	symbols := make([]string, 0)
+	b := section(binary.BigEndian.Uint64(tocData[0:]))
+	for cnt, b := binary.BigEndian.Uint32(b), b[4:]; cnt > 0; cnt-- {
+		var s string
+		s, b = str(b)
+		symbols = append(symbols, s)
+	}
+	assert.IsIncreasing(symbols)
+
+	// the first entry of the postings offset table is the all postings list
+	b = section(binary.BigEndian.Uint64(tocData[5*8:]))[4:]
+	_, b = uvarint(b)
+	name, b := str(b)
+	value, b := str(b)
+	assert.Equal("", name+value)
+	off, _ := uvarint(b)
+	b = section(off)[4:]
+
+	result := make(map[string][]model.SamplePair)
+	for ; len(b) > 0; b = b[4:] {
+		off := uint64(binary.BigEndian.Uint32(b)) * tsdbSeriesAlign
+		l, n := binary.Uvarint(index[off:])
+		s := index[off+uint64(n) : off+uint64(n)+l]
+		assert.Equal(crc32.Checksum(s, castagnoliTable), binary.BigEndian.Uint32(index[off+uint64(n)+l:]))
+
+		labels := make([]string, 0)
+		var cnt, v uint64
+		for cnt, s = uvarint(s); cnt > 0; cnt-- {
+			v, s = uvarint(s)
+			name := symbols[v]
+			v, s = uvarint(s)
+			labels = append(labels, name+"="+symbols[v])
+		}
+		key := strings.Join(labels, ",")
+
+		var ref, mint, maxt int64
+		cnt, s = uvarint(s)
+		for i := uint64(0); i < cnt; i++ {
+			if i == 0 {
+				t0, n := binary.Varint(s)
+				s = s[n:]
+				d, rest := uvarint(s)
+				r, rest := uvarint(rest)
+				s = rest
+				mint, maxt, ref = t0, t0+int64(d), int64(r)
+			} else {
+				d1, rest := uvarint(s)
+				d2, rest := uvarint(rest)
+				r, n := binary.Varint(rest)
+				s = rest[n:]
+				mint = maxt + int64(d1)
+				maxt = mint + int64(d2)
+				ref += r
+			}
+			// chunks are all in the first segment
+			l, n := binary.Uvarint(chunks[ref:])
+			data := chunks[ref+int64(n) : ref+int64(n)+1+int64(l)]
+			assert.Equal(crc32.Checksum(data, castagnoliTable), binary.BigEndian.Uint32(chunks[ref+int64(n)+1+int64(l):]))
+			assert.EqualValues(tsdbChunkEncXOR, data[0])
+			samples, err := decodeXORChunk(data[1:])
+			assert.Nil(err)
+			assert.EqualValues(mint, samples[0].Timestamp)
+			assert.EqualValues(maxt, samples[len(samples)-1].Timestamp)
+			result[key] = append(result[key], samples...)
+		}
+	}
+	return result
+}
+
+func TestExportTSDB(t *testing.T) {
+	assert := require.New(t)
+
+	dir := genTestMetricDumps(t)
+	output := t.TempDir()
+	block, err := ExportMetrics(dir, &MetricExportOptions{Format: MetricExportTSDB, Output: output})
+	assert.Nil(err)
+	assert.Equal(output, filepath.Dir(block))
+
+	var meta tsdbBlockMeta
+	data, err := os.ReadFile(filepath.Join(block, "meta.json"))
+	assert.Nil(err)
+	assert.Nil(json.Unmarshal(data, &meta))
+	assert.Equal(filepath.Base(block), meta.ULID)
+	assert.EqualValues(1000, meta.MinTime)
+	assert.EqualValues(1000+299*15000+1, meta.MaxTime)
+	assert.EqualValues(3, meta.Stats.NumSeries)
+	assert.EqualValues(1+2+300, meta.Stats.NumSamples)
+	assert.EqualValues(1+1+3, meta.Stats.NumChunks)
+
+	series := readTestTSDBBlock(t, block)
+	assert.Len(series, 3)
+	assert.Len(series[`__name__=tidb_server_info,version=v"8"`+"\n"], 1)
+	assert.Len(series["__name__=up,instance=a,job=tidb"], 2)
+	samples := series["__name__=up,instance=b,job=tikv"]
+	assert.Len(samples, 300)
+	for i, s := range samples {
+		assert.EqualValues(1000+i*15000, s.Timestamp)
+		assert.EqualValues(i, s.Value)
+	}
+}
+
+func TestEncodeXORChunk(t *testing.T) {
+	assert := require.New(t)
+
+	// delta of deltas in all ranges
+	samples := []model.SamplePair{
+		{Timestamp: -5, Value: 1},
+		{Timestamp: 10, Value: 1},
+		{Timestamp: 25, Value: 0.1},
+		{Timestamp: 8000, Value: 0.2},
+		{Timestamp: 8001, Value: -0.2},
+		{Timestamp: 70000, Value: 1e100},
+		{Timestamp: 70001, Value: 0},
+		{Timestamp: 600000, Value: 3},
+		{Timestamp: 600001, Value: 3},
+		{Timestamp: 100600001, Value: 3},
+		{Timestamp: 100600002, Value: 3},
+	}
+	decoded, err := decodeXORChunk(encodeXORChunk(samples))
+	assert.Nil(err)
+	assert.Equal(samples, decoded)
+}
+
+func TestLoadMetricStorage(t *testing.T) {
+	assert := require.New(t)
+
+	dir := genTestMetricDumps(t)
+	storage, err := LoadMetricStorage(dir, []string{"up", "not_dumped"})
+	assert.Nil(err)
+	begin, end, err := storage.TimeRange()
+	assert.Nil(err)
+	assert.EqualValues(1000, begin.UnixMilli())
+	assert.EqualValues(1000+299*15000, end.UnixMilli())
+
+	result, err := storage.Query(`count(up)`, end, end, time.Minute)
+	assert.Nil(err)
+	assert.Len(result, 1)
+	assert.EqualValues(1, result[0].Values[0].Value)
+
+	result, err = storage.Query(`{__name__=~".+"}`, begin, begin, time.Minute)
+	assert.Nil(err)
+	assert.Len(result, 2)
+
+	storage, err = LoadMetricStorage(dir, []string{""})
+	assert.Nil(err)
+	result, err = storage.Query(`{__name__=~".+"}`, begin, begin, time.Minute)
+	assert.Nil(err)
+	assert.Len(result, 3)
+}
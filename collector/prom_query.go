@@ -0,0 +1,55 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"github.com/pingcap/diag/pkg/promql"
+)
+
+// LoadMetricStorage reads the dumped metric JSON files into an in-memory
+// storage for querying, only the named metrics are loaded unless an empty
+// name is in the list
+func LoadMetricStorage(dataDir string, names []string) (*promql.Storage, error) {
+	_, metrics, err := listMetricDumps(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	all := false
+	for _, name := range names {
+		if name == "" {
+			all = true
+		}
+	}
+	if all {
+		names = make([]string, 0, len(metrics))
+		for name := range metrics {
+			names = append(names, name)
+		}
+	}
+
+	storage := promql.NewStorage()
+	for _, name := range names {
+		files, ok := metrics[name]
+		if !ok {
+			continue
+		}
+		series, err := loadMetricSeries(name, files, nil)
+		if err != nil {
+			return nil, err
+		}
+		storage.Add(series...)
+	}
+	return storage, nil
+}
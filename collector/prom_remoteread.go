@@ -0,0 +1,620 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pingcap/diag/pkg/utils"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The remote read API of Prometheus, messages are defined in prompb/remote.proto
+// and prompb/types.proto of Prometheus, only fields used here are implemented.
+const (
+	remoteReadPath    = "/api/v1/read"
+	remoteReadVersion = "0.1.0"
+
+	remoteReadSamples          = 0 // ReadRequest.ResponseType SAMPLES
+	remoteReadStreamedXORChunk = 1 // ReadRequest.ResponseType STREAMED_XOR_CHUNKS
+
+	remoteReadMatchEQ  = 0 // LabelMatcher.Type EQ
+	remoteReadChunkXOR = 1 // Chunk.Encoding XOR
+
+	remoteReadStreamedContentType = "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse"
+	remoteReadMaxFrameSize        = 64 * 1024 * 1024
+)
+
+// staleNaN is the value marking a series as stale, it is never returned by
+// the query API so it's dropped
+const staleNaN uint64 = 0x7ff0000000000002
+
+// errRemoteReadUnsupported indicates the server does not support remote read
+var errRemoteReadUnsupported = errors.New("remote read is not supported by the server")
+
+// remoteReadLabel is a label matcher used in the query
+type remoteReadLabel struct {
+	name  string
+	value string
+}
+
+// encodeRemoteReadRequest builds a ReadRequest with one query
+func encodeRemoteReadRequest(start, end time.Time, matchers []remoteReadLabel) []byte {
+	var query []byte
+	query = protowire.AppendTag(query, 1, protowire.VarintType)
+	query = protowire.AppendVarint(query, uint64(start.UnixMilli()))
+	query = protowire.AppendTag(query, 2, protowire.VarintType)
+	query = protowire.AppendVarint(query, uint64(end.UnixMilli()))
+	for _, m := range matchers {
+		var matcher []byte
+		matcher = protowire.AppendTag(matcher, 1, protowire.VarintType)
+		matcher = protowire.AppendVarint(matcher, remoteReadMatchEQ)
+		matcher = protowire.AppendTag(matcher, 2, protowire.BytesType)
+		matcher = protowire.AppendString(matcher, m.name)
+		matcher = protowire.AppendTag(matcher, 3, protowire.BytesType)
+		matcher = protowire.AppendString(matcher, m.value)
+		query = protowire.AppendTag(query, 3, protowire.BytesType)
+		query = protowire.AppendBytes(query, matcher)
+	}
+
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, query)
+	// prefer streamed chunks, old servers only support samples
+	for _, t := range []uint64{remoteReadStreamedXORChunk, remoteReadSamples} {
+		req = protowire.AppendTag(req, 2, protowire.VarintType)
+		req = protowire.AppendVarint(req, t)
+	}
+	return req
+}
+
+// remoteReadSeries is a series decoded from the response
+type remoteReadSeries struct {
+	labels  model.Metric
+	samples []model.SamplePair
+}
+
+// protoFields iterates over the fields of a message, fn returns the length
+// of the value consumed or a negative number to skip it
+func protoFields(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := fn(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// consumeBytes is a helper for protoFields to read a length-delimited value
+func consumeBytes(typ protowire.Type, b []byte, fn func([]byte) error) (int, error) {
+	if typ != protowire.BytesType {
+		return -1, nil
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, protowire.ParseError(n)
+	}
+	return n, fn(v)
+}
+
+func decodeRemoteReadLabel(b []byte, metric model.Metric) error {
+	var name, value string
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(typ, b, func(v []byte) error { name = string(v); return nil })
+		case 2:
+			return consumeBytes(typ, b, func(v []byte) error { value = string(v); return nil })
+		}
+		return -1, nil
+	})
+	metric[model.LabelName(name)] = model.LabelValue(value)
+	return err
+}
+
+func decodeRemoteReadSample(b []byte) (model.SamplePair, error) {
+	var s model.SamplePair
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			s.Value = model.SampleValue(math.Float64frombits(v))
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			s.Timestamp = model.Time(int64(v))
+			return n, nil
+		}
+		return -1, nil
+	})
+	return s, err
+}
+
+// decodeRemoteReadSeries decodes a TimeSeries or a ChunkedSeries message,
+// both of them have labels as field 1, samples or chunks as field 2
+func decodeRemoteReadSeries(b []byte, chunked bool) (*remoteReadSeries, error) {
+	series := &remoteReadSeries{labels: make(model.Metric)}
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(typ, b, func(v []byte) error {
+				return decodeRemoteReadLabel(v, series.labels)
+			})
+		case 2:
+			return consumeBytes(typ, b, func(v []byte) error {
+				if chunked {
+					samples, err := decodeRemoteReadChunk(v)
+					series.samples = append(series.samples, samples...)
+					return err
+				}
+				s, err := decodeRemoteReadSample(v)
+				series.samples = append(series.samples, s)
+				return err
+			})
+		}
+		return -1, nil
+	})
+	return series, err
+}
+
+// decodeRemoteReadChunk decodes a Chunk message
+func decodeRemoteReadChunk(b []byte) ([]model.SamplePair, error) {
+	var enc uint64
+	var data []byte
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			enc = v
+			return n, nil
+		case num == 4:
+			return consumeBytes(typ, b, func(v []byte) error { data = v; return nil })
+		}
+		return -1, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if enc != remoteReadChunkXOR {
+		return nil, fmt.Errorf("unsupported chunk encoding %d", enc)
+	}
+	return decodeXORChunk(data)
+}
+
+// bitReader reads a bit stream from the highest bit of each byte
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	if r.pos+n > len(r.data)*8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+func (r *bitReader) ReadByte() (byte, error) {
+	v, err := r.readBits(8)
+	return byte(v), err
+}
+
+// decodeXORChunk decodes samples in the Gorilla XOR encoding used by the
+// TSDB of Prometheus (tsdb/chunkenc/xor.go)
+func decodeXORChunk(data []byte) ([]model.SamplePair, error) {
+	if len(data) < 2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	num := int(binary.BigEndian.Uint16(data))
+	r := &bitReader{data: data[2:]}
+	samples := make([]model.SamplePair, 0, num)
+
+	var t, tDelta int64
+	var v uint64
+	var leading, trailing int
+	for i := 0; i < num; i++ {
+		switch i {
+		case 0:
+			ts, err := binary.ReadVarint(r)
+			if err != nil {
+				return nil, err
+			}
+			if v, err = r.readBits(64); err != nil {
+				return nil, err
+			}
+			t = ts
+		case 1:
+			delta, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			tDelta = int64(delta)
+			t += tDelta
+			if v, leading, trailing, err = readXORValue(r, v, leading, trailing); err != nil {
+				return nil, err
+			}
+		default:
+			// the delta of delta is prefixed by 0, 10, 110, 1110 or 1111
+			prefix := 0
+			for prefix < 4 {
+				bit, err := r.readBits(1)
+				if err != nil {
+					return nil, err
+				}
+				if bit == 0 {
+					break
+				}
+				prefix++
+			}
+			var dod int64
+			if sz := []int{0, 14, 17, 20, 64}[prefix]; sz > 0 {
+				bits, err := r.readBits(sz)
+				if err != nil {
+					return nil, err
+				}
+				// negative numbers are stored as high unsigned numbers
+				if sz < 64 && bits > 1<<(sz-1) {
+					bits -= 1 << sz
+				}
+				dod = int64(bits)
+			}
+			tDelta += dod
+			t += tDelta
+			var err error
+			if v, leading, trailing, err = readXORValue(r, v, leading, trailing); err != nil {
+				return nil, err
+			}
+		}
+		if v == staleNaN {
+			continue
+		}
+		samples = append(samples, model.SamplePair{
+			Timestamp: model.Time(t),
+			Value:     model.SampleValue(math.Float64frombits(v)),
+		})
+	}
+	return samples, nil
+}
+
+func readXORValue(r *bitReader, v uint64, leading, trailing int) (uint64, int, int, error) {
+	bit, err := r.readBits(1)
+	if err != nil || bit == 0 {
+		// the value is not changed
+		return v, leading, trailing, err
+	}
+	if bit, err = r.readBits(1); err != nil {
+		return v, leading, trailing, err
+	}
+	if bit == 1 {
+		// new leading and trailing zeros
+		l, err := r.readBits(5)
+		if err != nil {
+			return v, leading, trailing, err
+		}
+		m, err := r.readBits(6)
+		if err != nil {
+			return v, leading, trailing, err
+		}
+		// 0 significant bits means 64 as it overflows 6 bits
+		if m == 0 {
+			m = 64
+		}
+		leading = int(l)
+		trailing = 64 - leading - int(m)
+	}
+	bits, err := r.readBits(64 - leading - trailing)
+	if err != nil {
+		return v, leading, trailing, err
+	}
+	return v ^ bits<<trailing, leading, trailing, nil
+}
+
+// readRemoteReadResponse decodes the response and calls fn for each series,
+// chunks of a series may be split into several frames, they are merged
+// before fn is called
+func readRemoteReadResponse(resp *http.Response, fn func(*remoteReadSeries) error) error {
+	if resp.Header.Get("Content-Type") != remoteReadStreamedContentType {
+		// a snappy compressed ReadResponse of samples
+		compressed, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		data, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			return err
+		}
+		// ReadResponse.results -> QueryResult.timeseries
+		return protoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+			if num != 1 {
+				return -1, nil
+			}
+			return consumeBytes(typ, b, func(result []byte) error {
+				return protoFields(result, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					if num != 1 {
+						return -1, nil
+					}
+					return consumeBytes(typ, b, func(ts []byte) error {
+						series, err := decodeRemoteReadSeries(ts, false)
+						if err != nil {
+							return err
+						}
+						return fn(series)
+					})
+				})
+			})
+		})
+	}
+
+	// frames of ChunkedReadResponse, each frame is an uvarint size, a CRC32
+	// checksum in castagnoli and the message
+	var current *remoteReadSeries
+	table := crc32.MakeTable(crc32.Castagnoli)
+	br := bufio.NewReader(resp.Body)
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if size > remoteReadMaxFrameSize {
+			return fmt.Errorf("frame size %d exceeds the limit", size)
+		}
+		var crc [4]byte
+		if _, err := io.ReadFull(br, crc[:]); err != nil {
+			return err
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return err
+		}
+		if crc32.Checksum(frame, table) != binary.BigEndian.Uint32(crc[:]) {
+			return fmt.Errorf("checksum mismatch of remote read frame")
+		}
+
+		err = protoFields(frame, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+			if num != 1 {
+				return -1, nil
+			}
+			return consumeBytes(typ, b, func(cs []byte) error {
+				series, err := decodeRemoteReadSeries(cs, true)
+				if err != nil {
+					return err
+				}
+				if current != nil && current.labels.Equal(series.labels) {
+					current.samples = append(current.samples, series.samples...)
+					return nil
+				}
+				if current != nil {
+					if err := fn(current); err != nil {
+						return err
+					}
+				}
+				current = series
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if current != nil {
+		return fn(current)
+	}
+	return nil
+}
+
+// remoteReadMetric dumps a metric with the remote read API, the result is
+// saved in the same format as the query API, so the data could be loaded
+// in the same way. If it fails, all files of the metric dumped by remote
+// read are removed along with their progress, so that the metric could be
+// dumped again with the query API.
+func remoteReadMetric(
+	l *logprinter.Logger,
+	c *http.Client,
+	promAddr string,
+	beginTime, endTime time.Time,
+	mtc string,
+	label map[string]string,
+	resultDir string,
+	compress bool,
+	customHeader []string,
+	stripLabels []string,
+	progress *collectProgress,
+) (err error) {
+	matchers := []remoteReadLabel{{name: model.MetricNameLabel, value: mtc}}
+	for name, value := range label {
+		matchers = append(matchers, remoteReadLabel{name: name, value: value})
+	}
+	sort.Slice(matchers[1:], func(i, j int) bool { return matchers[i+1].name < matchers[j+1].name })
+
+	dir := filepath.Join(resultDir, subdirMonitor, subdirMetrics, utils.URL2Name(promAddr))
+	dumped := make([]string, 0)
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, fname := range dumped {
+			if rerr := os.Remove(filepath.Join(dir, fname)); rerr != nil && !os.IsNotExist(rerr) {
+				l.Warnf("failed to remove %s dumped with remote read: %s", fname, rerr)
+			}
+			progress.UnfinishTarget(progressKeyMetric, fname)
+		}
+	}()
+
+	// the server streams data, so the range of a file could be larger
+	block := time.Duration(maxQueryRange) * time.Second
+	for queryEnd := endTime; queryEnd.After(beginTime); queryEnd = queryEnd.Add(-block) {
+		queryBegin := queryEnd.Add(-block)
+		if queryBegin.Before(beginTime) {
+			queryBegin = beginTime
+		}
+		fname := fmt.Sprintf("%s-%s-%s.json", mtc, queryBegin.Format(time.RFC3339), queryEnd.Format(time.RFC3339))
+		dumped = append(dumped, fname)
+		if progress.TargetDone(progressKeyMetric, fname) {
+			l.Debugf("Metric %s from %s to %s is already dumped, skip", mtc, queryBegin.Format(time.RFC3339), queryEnd.Format(time.RFC3339))
+			continue
+		}
+
+		// the remote read API returns samples in the closed range, so the end
+		// of a block is excluded unless it is the end of the collection, to
+		// avoid samples on the boundary being dumped into two files
+		readEnd := queryEnd
+		if queryEnd.Before(endTime) {
+			readEnd = queryEnd.Add(-time.Millisecond)
+		}
+		body := snappy.Encode(nil, encodeRemoteReadRequest(queryBegin, readEnd, matchers))
+		fp := filepath.Join(dir, fname)
+		unsupported := false
+		if err := tiuputils.Retry(
+			func() error {
+				req, err := http.NewRequest(http.MethodPost, makeURL(promAddr, remoteReadPath, nil), bytes.NewReader(body))
+				if err != nil {
+					return err
+				}
+				req.Header.Set("Content-Encoding", "snappy")
+				req.Header.Set("Content-Type", "application/x-protobuf")
+				req.Header.Set("X-Prometheus-Remote-Read-Version", remoteReadVersion)
+				utils.AddHeaders(req.Header, customHeader)
+				resp, err := c.Do(req)
+				if err != nil {
+					l.Errorf("failed remote reading metric %s: %s, retry...", mtc, err)
+					return err
+				}
+				defer resp.Body.Close()
+				switch {
+				case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed ||
+					resp.StatusCode == http.StatusNotImplemented:
+					unsupported = true
+					return nil
+				case resp.StatusCode/100 != 2:
+					msg, _ := io.ReadAll(resp.Body)
+					return fmt.Errorf("[%d] %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+				}
+
+				n, err := writeRemoteReadResult(fp, resp, compress, stripLabels)
+				if err != nil {
+					l.Errorf("failed writing metric %s to file: %s, retry...\n", mtc, err)
+					return err
+				}
+				l.Debugf(" Dumped metric %s from %s to %s (%d series) with remote read", mtc, queryBegin.Format(time.RFC3339), queryEnd.Format(time.RFC3339), n)
+				return nil
+			},
+			tiuputils.RetryOption{
+				Attempts: 3,
+				Delay:    time.Microsecond * 300,
+				Timeout:  c.Timeout*3 + 5*time.Second, //make sure the retry timeout is longer than the api timeout
+			},
+		); err != nil {
+			return err
+		}
+		if unsupported {
+			return errRemoteReadUnsupported
+		}
+		progress.FinishTarget(progressKeyMetric, fname)
+	}
+	return nil
+}
+
+// writeRemoteReadResult writes series in the response as a matrix result of
+// the query API, it returns the number of series written
+func writeRemoteReadResult(fp string, resp *http.Response, compress bool, stripLabels []string) (int, error) {
+	dst, err := os.Create(fp)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	var w io.Writer = dst
+	if compress {
+		enc, err := zstd.NewWriter(dst)
+		if err != nil {
+			return 0, err
+		}
+		defer enc.Close()
+		w = enc
+	}
+	bw := bufio.NewWriter(w)
+
+	cnt := 0
+	if _, err := bw.WriteString(`{"status":"success","data":{"resultType":"matrix","result":[`); err != nil {
+		return cnt, err
+	}
+	err = readRemoteReadResponse(resp, func(series *remoteReadSeries) error {
+		if len(series.samples) == 0 {
+			return nil
+		}
+		for _, label := range stripLabels {
+			delete(series.labels, model.LabelName(label))
+		}
+		data, err := json.Marshal(&model.SampleStream{Metric: series.labels, Values: series.samples})
+		if err != nil {
+			return err
+		}
+		if cnt > 0 {
+			if err := bw.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		cnt++
+		_, err = bw.Write(data)
+		return err
+	})
+	if err != nil {
+		return cnt, err
+	}
+	if _, err := bw.WriteString(`]}}`); err != nil {
+		return cnt, err
+	}
+	return cnt, bw.Flush()
+}
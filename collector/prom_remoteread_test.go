@@ -0,0 +1,287 @@
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/klauspost/compress/snappy"
+	"github.com/pingcap/diag/pkg/utils"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testBitWriter struct {
+	data []byte
+	n    int // bits written
+}
+
+func (w *testBitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+// encodeTestXORChunk is a simplified encoder of tsdb/chunkenc/xor.go, it is
+// kept apart from encodeXORChunk so the decoder is not only verified by the
+// encoder of this package
+func encodeTestXORChunk(samples []model.SamplePair) []byte {
+	w := &testBitWriter{data: binary.BigEndian.AppendUint16(nil, uint16(len(samples)))}
+	w.n = 16
+	var t, tDelta int64
+	var v uint64
+	leading, trailing := 0xff, 0
+	writeValue := func(nv uint64) {
+		delta := v ^ nv
+		v = nv
+		if delta == 0 {
+			w.writeBits(0, 1)
+			return
+		}
+		w.writeBits(1, 1)
+		l, tr := bits.LeadingZeros64(delta), bits.TrailingZeros64(delta)
+		if l >= 32 {
+			l = 31
+		}
+		if leading != 0xff && l >= leading && tr >= trailing {
+			w.writeBits(0, 1)
+			w.writeBits(delta>>trailing, 64-leading-trailing)
+			return
+		}
+		leading, trailing = l, tr
+		w.writeBits(1, 1)
+		w.writeBits(uint64(l), 5)
+		w.writeBits(uint64(64-l-tr), 6)
+		w.writeBits(delta>>tr, 64-l-tr)
+	}
+	for i, s := range samples {
+		nt, nv := int64(s.Timestamp), math.Float64bits(float64(s.Value))
+		switch i {
+		case 0:
+			for _, b := range binary.AppendVarint(nil, nt) {
+				w.writeBits(uint64(b), 8)
+			}
+			w.writeBits(nv, 64)
+			v = nv
+		case 1:
+			tDelta = nt - t
+			for _, b := range binary.AppendUvarint(nil, uint64(tDelta)) {
+				w.writeBits(uint64(b), 8)
+			}
+			writeValue(nv)
+		default:
+			delta := nt - t
+			dod := delta - tDelta
+			tDelta = delta
+			switch {
+			case dod == 0:
+				w.writeBits(0, 1)
+			case -(1<<13) < dod && dod <= 1<<13:
+				w.writeBits(0b10, 2)
+				w.writeBits(uint64(dod), 14)
+			default:
+				w.writeBits(0b1111, 4)
+				w.writeBits(uint64(dod), 64)
+			}
+			writeValue(nv)
+		}
+		t = nt
+	}
+	return w.data
+}
+
+// decodeTestReadRange returns the time range of the query in a ReadRequest
+func decodeTestReadRange(req []byte) (start, end int64, err error) {
+	err = protoFields(req, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 {
+			return -1, nil
+		}
+		return consumeBytes(typ, b, func(query []byte) error {
+			return protoFields(query, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+				if typ != protowire.VarintType || (num != 1 && num != 2) {
+					return -1, nil
+				}
+				v, n := protowire.ConsumeVarint(b)
+				if num == 1 {
+					start = int64(v)
+				} else {
+					end = int64(v)
+				}
+				return n, nil
+			})
+		})
+	})
+	return
+}
+
+func encodeTestSeries(labels map[string]string, samples []model.SamplePair) []byte {
+	var series []byte
+	for name, value := range labels {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, value)
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, label)
+	}
+	var chunk []byte
+	chunk = protowire.AppendTag(chunk, 3, protowire.VarintType)
+	chunk = protowire.AppendVarint(chunk, remoteReadChunkXOR)
+	chunk = protowire.AppendTag(chunk, 4, protowire.BytesType)
+	chunk = protowire.AppendBytes(chunk, encodeTestXORChunk(samples))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	return protowire.AppendBytes(series, chunk)
+}
+
+func TestDecodeXORChunk(t *testing.T) {
+	assert := require.New(t)
+
+	samples := []model.SamplePair{
+		{Timestamp: 1000, Value: 1},
+		{Timestamp: 16000, Value: 1},
+		{Timestamp: 31000, Value: 2.5},
+		{Timestamp: 46500, Value: -3},
+		{Timestamp: 46600, Value: 1e10},
+		{Timestamp: 100046600, Value: 0},
+	}
+	decoded, err := decodeXORChunk(encodeTestXORChunk(samples))
+	assert.Nil(err)
+	assert.Equal(samples, decoded)
+
+	_, err = decodeXORChunk(encodeTestXORChunk(samples)[:10])
+	assert.NotNil(err)
+}
+
+func TestRemoteReadMetric(t *testing.T) {
+	assert := require.New(t)
+
+	samples := []model.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 16000, Value: 2}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(remoteReadPath, r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		_, err := snappy.Decode(nil, body)
+		assert.Nil(err)
+
+		w.Header().Set("Content-Type", remoteReadStreamedContentType)
+		// chunks of the first series are split into 2 frames
+		for _, s := range [][]byte{
+			encodeTestSeries(map[string]string{"__name__": "up", "instance": "a", "job": "tidb"}, samples[:1]),
+			encodeTestSeries(map[string]string{"__name__": "up", "instance": "a", "job": "tidb"}, samples[1:]),
+			encodeTestSeries(map[string]string{"__name__": "up", "instance": "b", "job": "tidb"}, samples),
+		} {
+			var frame []byte
+			frame = protowire.AppendTag(frame, 1, protowire.BytesType)
+			frame = protowire.AppendBytes(frame, s)
+			w.Write(binary.AppendUvarint(nil, uint64(len(frame))))
+			w.Write(binary.BigEndian.AppendUint32(nil, crc32.Checksum(frame, crc32.MakeTable(crc32.Castagnoli))))
+			w.Write(frame)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.Nil(ensureMonitorDir(dir, subdirMetrics, utils.URL2Name(srv.URL)))
+	end := time.Unix(3600, 0).UTC()
+	err := remoteReadMetric(logprinter.NewLogger(""), srv.Client(), srv.URL, end.Add(-time.Hour), end,
+		"up", map[string]string{"job": "tidb"}, dir, false, nil, []string{"job"}, nil)
+	assert.Nil(err)
+
+	files, err := os.ReadDir(filepath.Join(dir, subdirMonitor, subdirMetrics, utils.URL2Name(srv.URL)))
+	assert.Nil(err)
+	assert.Len(files, 1)
+	data, err := os.ReadFile(filepath.Join(dir, subdirMonitor, subdirMetrics, utils.URL2Name(srv.URL), files[0].Name()))
+	assert.Nil(err)
+
+	var dump promDump
+	assert.Nil(json.Unmarshal(data, &dump))
+	assert.Equal("matrix", dump.Data.ResultType)
+	assert.Len(dump.Data.Result, 2)
+	assert.Equal(model.Metric{"__name__": "up", "instance": "a"}, dump.Data.Result[0].Metric)
+	assert.Equal(samples, dump.Data.Result[0].Values)
+
+	unsupported := httptest.NewServer(http.NotFoundHandler())
+	defer unsupported.Close()
+	err = remoteReadMetric(logprinter.NewLogger(""), unsupported.Client(), unsupported.URL, end.Add(-time.Hour), end,
+		"up", nil, dir, false, nil, nil, nil)
+	assert.Equal(errRemoteReadUnsupported, err)
+}
+
+func TestRemoteReadMetricRanges(t *testing.T) {
+	assert := require.New(t)
+
+	ranges := make([][2]int64, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := snappy.Decode(nil, body)
+		assert.Nil(err)
+		start, end, err := decodeTestReadRange(req)
+		assert.Nil(err)
+		ranges = append(ranges, [2]int64{start, end})
+		w.Header().Set("Content-Type", remoteReadStreamedContentType)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.Nil(ensureMonitorDir(dir, subdirMetrics, utils.URL2Name(srv.URL)))
+	end := time.Unix(3*maxQueryRange, 0).UTC()
+	err := remoteReadMetric(logprinter.NewLogger(""), srv.Client(), srv.URL, end.Add(-2*maxQueryRange*time.Second), end,
+		"up", nil, dir, false, nil, nil, nil)
+	assert.Nil(err)
+
+	// the end of the collection is included, blocks do not overlap
+	mid := end.Add(-maxQueryRange * time.Second).UnixMilli()
+	assert.Equal([][2]int64{
+		{mid, end.UnixMilli()},
+		{end.Add(-2 * maxQueryRange * time.Second).UnixMilli(), mid - 1},
+	}, ranges)
+}
+
+func TestRemoteReadMetricCleanup(t *testing.T) {
+	assert := require.New(t)
+
+	// the first block is dumped, the second one fails
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", remoteReadStreamedContentType)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.Nil(ensureMonitorDir(dir, subdirMetrics, utils.URL2Name(srv.URL)))
+	progress, err := loadCollectProgress(dir)
+	assert.Nil(err)
+	defer progress.Close()
+	end := time.Unix(3*maxQueryRange, 0).UTC()
+	err = remoteReadMetric(logprinter.NewLogger(""), srv.Client(), srv.URL, end.Add(-2*maxQueryRange*time.Second), end,
+		"up", nil, dir, false, nil, nil, progress)
+	assert.NotNil(err)
+
+	// nothing is left for the fallback to the query API
+	files, err := os.ReadDir(filepath.Join(dir, subdirMonitor, subdirMetrics, utils.URL2Name(srv.URL)))
+	assert.Nil(err)
+	assert.Empty(files)
+	fname := fmt.Sprintf("up-%s-%s.json", end.Add(-maxQueryRange*time.Second).Format(time.RFC3339), end.Format(time.RFC3339))
+	assert.False(progress.TargetDone(progressKeyMetric, fname))
+}
@@ -0,0 +1,582 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+// This file writes Prometheus TSDB blocks, the on-disk format is described in
+// https://github.com/prometheus/prometheus/tree/main/tsdb/docs/format
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+
+	json "github.com/json-iterator/go"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	tsdbMagicIndex    = 0xBAAAD700
+	tsdbMagicChunks   = 0x85BD40DD
+	tsdbIndexV2       = 2
+	tsdbChunksV1      = 1
+	tsdbChunkEncXOR   = 1
+	tsdbSegmentSize   = 512 * 1024 * 1024
+	tsdbChunkSamples  = 120 // same as the head of Prometheus
+	tsdbSeriesAlign   = 16
+	tsdbPostingsAlign = 4
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+type tsdbLabel struct {
+	name  string
+	value string
+}
+
+type tsdbChunkMeta struct {
+	ref  uint64
+	minT int64
+	maxT int64
+}
+
+type tsdbSeries struct {
+	labels []tsdbLabel
+	chunks []tsdbChunkMeta
+}
+
+type tsdbBlockMeta struct {
+	ULID    string `json:"ulid"`
+	MinTime int64  `json:"minTime"`
+	MaxTime int64  `json:"maxTime"`
+	Stats   struct {
+		NumSamples uint64 `json:"numSamples"`
+		NumSeries  uint64 `json:"numSeries"`
+		NumChunks  uint64 `json:"numChunks"`
+	} `json:"stats"`
+	Compaction struct {
+		Level   int      `json:"level"`
+		Sources []string `json:"sources"`
+	} `json:"compaction"`
+	Version int `json:"version"`
+}
+
+// tsdbBlockWriter writes series to a block, chunks are written to segment
+// files as soon as series are added, while the index is written on close
+// as series must be sorted by labels in it
+type tsdbBlockWriter struct {
+	parent string
+	tmpDir string
+	id     ulid.ULID
+
+	segment *tsdbFileWriter
+	segSeq  int
+
+	series []*tsdbSeries
+	meta   tsdbBlockMeta
+}
+
+func newTSDBBlockWriter(parent string) (*tsdbBlockWriter, error) {
+	id, err := ulid.New(ulid.Now(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	w := &tsdbBlockWriter{
+		parent: parent,
+		tmpDir: filepath.Join(parent, id.String()+".tmp"),
+		id:     id,
+	}
+	w.meta.MinTime = math.MaxInt64
+	w.meta.MaxTime = math.MinInt64
+	if err := os.MkdirAll(filepath.Join(w.tmpDir, "chunks"), 0755); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// addSeries adds a series to the block, labels must be sorted by name and
+// samples must be sorted by timestamp without duplicates
+func (w *tsdbBlockWriter) addSeries(labels []tsdbLabel, samples []model.SamplePair) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	s := &tsdbSeries{labels: labels}
+	for i := 0; i < len(samples); i += tsdbChunkSamples {
+		end := i + tsdbChunkSamples
+		if end > len(samples) {
+			end = len(samples)
+		}
+		ref, err := w.writeChunk(encodeXORChunk(samples[i:end]))
+		if err != nil {
+			return err
+		}
+		s.chunks = append(s.chunks, tsdbChunkMeta{
+			ref:  ref,
+			minT: int64(samples[i].Timestamp),
+			maxT: int64(samples[end-1].Timestamp),
+		})
+	}
+	if t := int64(samples[0].Timestamp); t < w.meta.MinTime {
+		w.meta.MinTime = t
+	}
+	if t := int64(samples[len(samples)-1].Timestamp); t > w.meta.MaxTime {
+		w.meta.MaxTime = t
+	}
+	w.meta.Stats.NumSamples += uint64(len(samples))
+	w.meta.Stats.NumChunks += uint64(len(s.chunks))
+	w.series = append(w.series, s)
+	return nil
+}
+
+// writeChunk appends a chunk to the current segment file and returns its
+// reference, the upper 4 bytes are the segment sequence and the lower 4
+// bytes are the offset in the segment
+func (w *tsdbBlockWriter) writeChunk(data []byte) (uint64, error) {
+	head := binary.AppendUvarint(nil, uint64(len(data)))
+	size := uint64(len(head) + 1 + len(data) + crc32.Size)
+	if w.segment == nil || w.segment.pos+size > tsdbSegmentSize {
+		if err := w.cutSegment(); err != nil {
+			return 0, err
+		}
+	}
+
+	ref := uint64(w.segSeq-1)<<32 | w.segment.pos
+	crc := crc32.New(castagnoliTable)
+	crc.Write([]byte{tsdbChunkEncXOR})
+	crc.Write(data)
+	err := w.segment.write(head, []byte{tsdbChunkEncXOR}, data, crc.Sum(nil))
+	return ref, err
+}
+
+func (w *tsdbBlockWriter) cutSegment() error {
+	if w.segment != nil {
+		if err := w.segment.close(); err != nil {
+			return err
+		}
+	}
+	w.segSeq++
+	seg, err := newTSDBFileWriter(filepath.Join(w.tmpDir, "chunks", fmt.Sprintf("%06d", w.segSeq)))
+	if err != nil {
+		return err
+	}
+	w.segment = seg
+	header := binary.BigEndian.AppendUint32(nil, tsdbMagicChunks)
+	return seg.write(header, []byte{tsdbChunksV1, 0, 0, 0})
+}
+
+// close writes the index and meta of the block, then moves the block to its
+// final location and returns the path of it
+func (w *tsdbBlockWriter) close() (string, error) {
+	dir, err := w.finish()
+	if err != nil {
+		w.abort()
+	}
+	return dir, err
+}
+
+func (w *tsdbBlockWriter) finish() (string, error) {
+	if w.segment != nil {
+		if err := w.segment.close(); err != nil {
+			return "", err
+		}
+	}
+	if len(w.series) == 0 {
+		return "", fmt.Errorf("no series to write")
+	}
+
+	sort.Slice(w.series, func(i, j int) bool {
+		return compareTSDBLabels(w.series[i].labels, w.series[j].labels) < 0
+	})
+	if err := writeTSDBIndex(filepath.Join(w.tmpDir, "index"), w.series); err != nil {
+		return "", err
+	}
+
+	// the max time of a block is exclusive
+	w.meta.MaxTime++
+	w.meta.ULID = w.id.String()
+	w.meta.Stats.NumSeries = uint64(len(w.series))
+	w.meta.Compaction.Level = 1
+	w.meta.Compaction.Sources = []string{w.id.String()}
+	w.meta.Version = 1
+	data, err := json.MarshalIndent(w.meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(w.tmpDir, "meta.json"), data, 0644); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(w.parent, w.id.String())
+	return dir, os.Rename(w.tmpDir, dir)
+}
+
+func (w *tsdbBlockWriter) abort() {
+	if w.segment != nil {
+		w.segment.close()
+	}
+	os.RemoveAll(w.tmpDir)
+}
+
+func compareTSDBLabels(a, b []tsdbLabel) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].name != b[i].name {
+			if a[i].name < b[i].name {
+				return -1
+			}
+			return 1
+		}
+		if a[i].value != b[i].value {
+			if a[i].value < b[i].value {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// writeTSDBIndex writes the index file in format v2, series must be sorted
+func writeTSDBIndex(path string, series []*tsdbSeries) error {
+	w, err := newTSDBFileWriter(path)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	// label names and their values
+	values := make(map[string]map[string]struct{})
+	for _, s := range series {
+		for _, l := range s.labels {
+			if values[l.name] == nil {
+				values[l.name] = make(map[string]struct{})
+			}
+			values[l.name][l.value] = struct{}{}
+		}
+	}
+	symbolSet := make(map[string]struct{})
+	names := make([]string, 0, len(values))
+	sortedValues := make(map[string][]string, len(values))
+	for name, vals := range values {
+		names = append(names, name)
+		symbolSet[name] = struct{}{}
+		for v := range vals {
+			sortedValues[name] = append(sortedValues[name], v)
+			symbolSet[v] = struct{}{}
+		}
+		sort.Strings(sortedValues[name])
+	}
+	sort.Strings(names)
+	symbols := make([]string, 0, len(symbolSet))
+	for s := range symbolSet {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	symbolRefs := make(map[string]uint32, len(symbols))
+	for i, s := range symbols {
+		symbolRefs[s] = uint32(i)
+	}
+
+	var toc [6]uint64
+	header := binary.BigEndian.AppendUint32(nil, tsdbMagicIndex)
+	if err := w.write(header, []byte{tsdbIndexV2}); err != nil {
+		return err
+	}
+
+	// symbol table
+	toc[0] = w.pos
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(symbols)))
+	for _, s := range symbols {
+		buf = appendUvarintString(buf, s)
+	}
+	if err := w.writeSection(buf); err != nil {
+		return err
+	}
+
+	// series, they are referenced by offset/16
+	toc[1] = w.pos
+	postings := make(map[tsdbLabel][]uint32)
+	all := make([]uint32, 0, len(series))
+	for _, s := range series {
+		if err := w.addPadding(tsdbSeriesAlign); err != nil {
+			return err
+		}
+		if w.pos/tsdbSeriesAlign > math.MaxUint32 {
+			return fmt.Errorf("too many series in the index")
+		}
+		ref := uint32(w.pos / tsdbSeriesAlign)
+		all = append(all, ref)
+
+		buf = binary.AppendUvarint(buf[:0], uint64(len(s.labels)))
+		for _, l := range s.labels {
+			buf = binary.AppendUvarint(buf, uint64(symbolRefs[l.name]))
+			buf = binary.AppendUvarint(buf, uint64(symbolRefs[l.value]))
+			postings[l] = append(postings[l], ref)
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(s.chunks)))
+		var t0, ref0 int64
+		for i, c := range s.chunks {
+			if i == 0 {
+				buf = binary.AppendVarint(buf, c.minT)
+			} else {
+				buf = binary.AppendUvarint(buf, uint64(c.minT-t0))
+			}
+			buf = binary.AppendUvarint(buf, uint64(c.maxT-c.minT))
+			if i == 0 {
+				buf = binary.AppendUvarint(buf, c.ref)
+			} else {
+				buf = binary.AppendVarint(buf, int64(c.ref)-ref0)
+			}
+			t0, ref0 = c.maxT, int64(c.ref)
+		}
+		if err := w.write(binary.AppendUvarint(nil, uint64(len(buf))), buf, crc32Sum(buf)); err != nil {
+			return err
+		}
+	}
+
+	// label indices, they are not used since v2 but still written by Prometheus
+	toc[2] = w.pos
+	labelOffsets := make([]uint64, 0, len(names))
+	for _, name := range names {
+		if err := w.addPadding(tsdbPostingsAlign); err != nil {
+			return err
+		}
+		labelOffsets = append(labelOffsets, w.pos)
+		buf = binary.BigEndian.AppendUint32(buf[:0], 1)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(sortedValues[name])))
+		for _, v := range sortedValues[name] {
+			buf = binary.BigEndian.AppendUint32(buf, symbolRefs[v])
+		}
+		if err := w.writeSection(buf); err != nil {
+			return err
+		}
+	}
+
+	toc[3] = w.pos
+	buf = binary.BigEndian.AppendUint32(buf[:0], uint32(len(names)))
+	for i, name := range names {
+		buf = binary.AppendUvarint(buf, 1)
+		buf = appendUvarintString(buf, name)
+		buf = binary.AppendUvarint(buf, labelOffsets[i])
+	}
+	if err := w.writeSection(buf); err != nil {
+		return err
+	}
+
+	// postings, the special all postings list with empty name and value
+	// goes first
+	toc[4] = w.pos
+	offsetTable := binary.BigEndian.AppendUint32(nil, 0)
+	cnt := uint32(0)
+	writePostings := func(name, value string, refs []uint32) error {
+		if err := w.addPadding(tsdbPostingsAlign); err != nil {
+			return err
+		}
+		offsetTable = binary.AppendUvarint(offsetTable, 2)
+		offsetTable = appendUvarintString(offsetTable, name)
+		offsetTable = appendUvarintString(offsetTable, value)
+		offsetTable = binary.AppendUvarint(offsetTable, w.pos)
+		cnt++
+
+		buf = binary.BigEndian.AppendUint32(buf[:0], uint32(len(refs)))
+		for _, ref := range refs {
+			buf = binary.BigEndian.AppendUint32(buf, ref)
+		}
+		return w.writeSection(buf)
+	}
+	if err := writePostings("", "", all); err != nil {
+		return err
+	}
+	for _, name := range names {
+		for _, value := range sortedValues[name] {
+			if err := writePostings(name, value, postings[tsdbLabel{name, value}]); err != nil {
+				return err
+			}
+		}
+	}
+
+	toc[5] = w.pos
+	binary.BigEndian.PutUint32(offsetTable, cnt)
+	if err := w.writeSection(offsetTable); err != nil {
+		return err
+	}
+
+	buf = buf[:0]
+	for _, off := range toc {
+		buf = binary.BigEndian.AppendUint64(buf, off)
+	}
+	if err := w.write(buf, crc32Sum(buf)); err != nil {
+		return err
+	}
+	return w.close()
+}
+
+func appendUvarintString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func crc32Sum(data []byte) []byte {
+	return binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, castagnoliTable))
+}
+
+// tsdbFileWriter is a buffered file writer that tracks the position
+type tsdbFileWriter struct {
+	f   *os.File
+	w   *bufio.Writer
+	pos uint64
+}
+
+func newTSDBFileWriter(path string) (*tsdbFileWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tsdbFileWriter{f: f, w: bufio.NewWriterSize(f, 1<<20)}, nil
+}
+
+func (w *tsdbFileWriter) write(bufs ...[]byte) error {
+	for _, b := range bufs {
+		n, err := w.w.Write(b)
+		w.pos += uint64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSection writes the content prefixed by its 4 bytes length and
+// followed by its checksum
+func (w *tsdbFileWriter) writeSection(content []byte) error {
+	return w.write(binary.BigEndian.AppendUint32(nil, uint32(len(content))), content, crc32Sum(content))
+}
+
+func (w *tsdbFileWriter) addPadding(align uint64) error {
+	if p := w.pos % align; p != 0 {
+		return w.write(make([]byte, align-p))
+	}
+	return nil
+}
+
+func (w *tsdbFileWriter) close() error {
+	if w.f == nil {
+		return nil
+	}
+	err := w.w.Flush()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.f = nil
+	return err
+}
+
+type bitWriter struct {
+	data []byte
+	n    int // bits written
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+// encodeXORChunk encodes samples the same way as tsdb/chunkenc/xor.go, at
+// most 65535 samples could be put in a chunk
+func encodeXORChunk(samples []model.SamplePair) []byte {
+	w := &bitWriter{data: binary.BigEndian.AppendUint16(nil, uint16(len(samples)))}
+	w.n = 16
+	var t, tDelta int64
+	var v uint64
+	leading, trailing := 0xff, 0
+	writeValue := func(nv uint64) {
+		delta := v ^ nv
+		v = nv
+		if delta == 0 {
+			w.writeBits(0, 1)
+			return
+		}
+		w.writeBits(1, 1)
+		l, tr := bits.LeadingZeros64(delta), bits.TrailingZeros64(delta)
+		// the number of leading zeros is stored in 5 bits
+		if l >= 32 {
+			l = 31
+		}
+		if leading != 0xff && l >= leading && tr >= trailing {
+			w.writeBits(0, 1)
+			w.writeBits(delta>>trailing, 64-leading-trailing)
+			return
+		}
+		leading, trailing = l, tr
+		w.writeBits(1, 1)
+		w.writeBits(uint64(l), 5)
+		// 64 significant bits overflows to 0, which is handled by readers
+		w.writeBits(uint64(64-l-tr), 6)
+		w.writeBits(delta>>tr, 64-l-tr)
+	}
+	inRange := func(dod int64, n int) bool {
+		return -(1<<(n-1))+1 <= dod && dod <= 1<<(n-1)
+	}
+	for i, s := range samples {
+		nt, nv := int64(s.Timestamp), math.Float64bits(float64(s.Value))
+		switch i {
+		case 0:
+			for _, b := range binary.AppendVarint(nil, nt) {
+				w.writeBits(uint64(b), 8)
+			}
+			w.writeBits(nv, 64)
+			v = nv
+		case 1:
+			tDelta = nt - t
+			for _, b := range binary.AppendUvarint(nil, uint64(tDelta)) {
+				w.writeBits(uint64(b), 8)
+			}
+			writeValue(nv)
+		default:
+			delta := nt - t
+			dod := delta - tDelta
+			tDelta = delta
+			switch {
+			case dod == 0:
+				w.writeBits(0, 1)
+			case inRange(dod, 14):
+				w.writeBits(0b10, 2)
+				w.writeBits(uint64(dod), 14)
+			case inRange(dod, 17):
+				w.writeBits(0b110, 3)
+				w.writeBits(uint64(dod), 17)
+			case inRange(dod, 20):
+				w.writeBits(0b1110, 4)
+				w.writeBits(uint64(dod), 20)
+			default:
+				w.writeBits(0b1111, 4)
+				w.writeBits(uint64(dod), 64)
+			}
+			writeValue(nv)
+		}
+		t = nt
+	}
+	return w.data
+}
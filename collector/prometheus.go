@@ -28,6 +28,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/joomcode/errorx"
@@ -44,6 +45,7 @@ import (
 	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/tui/progress"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/portforward"
@@ -57,6 +59,9 @@ const (
 	subdirRaw     = "raw"
 	maxQueryRange = 120 * 60 // 120min
 	minQueryRange = 1 * 60   // 1min
+
+	metricScrapeInterval = 15 // seconds, the default scrape interval of tiup deployed Prometheus
+	metricSampleSize     = 24 // bytes of a sample in the query result
 )
 
 type collectMonitor struct {
@@ -99,20 +104,24 @@ func (c *AlertCollectOptions) SetDir(dir string) {
 
 // Prepare implements the Collector interface
 func (c *AlertCollectOptions) Prepare(m *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
-	return nil, nil
-}
-
-// Collect implements the Collector interface
-func (c *AlertCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error {
 	if c.Kubeconfig != "" && m.diagMode == DiagModeCmd {
-		// ignore collect alerts for "diag collectk"
-		return nil
+		return nil, nil
 	}
-	if m.mode != CollectModeManual && len(topo.Monitors) < 1 {
-		m.logger.Warnf("No monitoring node (prometheus) found in topology, skip collecting alert.")
-		return nil
+	result := make(map[string][]CollectStat)
+	for _, promAddr := range alertEndpoints(topo) {
+		result[promAddr] = append(result[promAddr], CollectStat{
+			Target: "alerts",
+			Size:   10 * 1024,
+			Attributes: map[string]interface{}{
+				StatAttrAPICalls: 1,
+			},
+		})
 	}
+	return result, nil
+}
 
+// alertEndpoints returns addresses of the Prometheus servers to query alerts
+func alertEndpoints(topo *models.TiDBCluster) []string {
 	monitors := make([]string, 0)
 	if eps, found := topo.Attributes[AttrKeyPromEndpoint]; found && len(eps.([]string)) > 0 && eps.([]string)[0] != "" {
 		monitors = append(monitors, eps.([]string)...)
@@ -122,6 +131,21 @@ func (c *AlertCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) erro
 			monitors = append(monitors, fmt.Sprintf("http://%s:%d", prom.Host(), prom.MainPort()))
 		}
 	}
+	return monitors
+}
+
+// Collect implements the Collector interface
+func (c *AlertCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error {
+	if c.Kubeconfig != "" && m.diagMode == DiagModeCmd {
+		// ignore collect alerts for "diag collectk"
+		return nil
+	}
+	if m.mode != CollectModeManual && len(topo.Monitors) < 1 {
+		m.logger.Warnf("No monitoring node (prometheus) found in topology, skip collecting alert.")
+		return nil
+	}
+
+	monitors := alertEndpoints(topo)
 
 	var queryOK bool
 	var queryErr error
@@ -187,6 +211,10 @@ type MetricCollectOptions struct {
 	portForward  bool
 	stopChans    []chan struct{}
 	stripLabels  []string
+	remoteRead   bool // use the remote read API instead of the query API
+	dryRun       bool // count series of the metrics to estimate the size
+	// set when the server does not support remote read
+	remoteReadUnsupported atomic.Bool
 }
 
 // Desc implements the Collector interface
@@ -269,6 +297,10 @@ func (c *MetricCollectOptions) Prepare(m *Manager, topo *models.TiDBCluster) (ma
 	c.metrics = filterMetrics(c.metrics, c.filter, c.exclude)
 
 	result := make(map[string][]CollectStat)
+	if c.dryRun {
+		result[c.endpoint] = append(result[c.endpoint], c.estimateMetrics(m, client, tsStart, tsEnd))
+		return result, nil
+	}
 	insCnt := len(topo.Components())
 	cStat := CollectStat{
 		Target: fmt.Sprintf("%d metrics, compressed", len(c.metrics)),
@@ -305,11 +337,7 @@ func (c *MetricCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) err
 		defer mb.StopRenderLoop()
 	}
 
-	qLimit := c.opt.Concurrency
-	cpuCnt := runtime.NumCPU()
-	if cpuCnt < qLimit {
-		qLimit = cpuCnt
-	}
+	qLimit := c.queryConcurrency()
 	tl := utils.NewTokenLimiter(uint(qLimit))
 
 	done := 1
@@ -343,7 +371,9 @@ func (c *MetricCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) err
 
 			tsEnd, _ := utils.ParseTime(c.GetBaseOptions().ScrapeEnd)
 			tsStart, _ := utils.ParseTime(c.GetBaseOptions().ScrapeBegin)
-			collectMetric(m.logger, client, key, tsStart, tsEnd, mtc, c.label, c.resultDir, c.limit, c.minInterval, c.compress, c.customHeader, "", c.stripLabels)
+			if !c.tryRemoteRead(m, client, tsStart, tsEnd, mtc) {
+				collectMetric(m.logger, client, key, tsStart, tsEnd, mtc, c.label, c.resultDir, c.limit, c.minInterval, c.compress, c.customHeader, "", c.stripLabels, m.progress)
+			}
 
 			mu.Lock()
 			done++
@@ -365,6 +395,118 @@ func (c *MetricCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) err
 	return nil
 }
 
+// queryConcurrency is the max number of metrics queried at the same time
+func (c *MetricCollectOptions) queryConcurrency() int {
+	return max(min(c.opt.Concurrency, runtime.NumCPU()), 1)
+}
+
+// estimateMetrics counts series of the metrics, and estimates the size and
+// the number of requests to dump them the same way as collectMetric
+func (c *MetricCollectOptions) estimateMetrics(m *Manager, client *http.Client, tsStart, tsEnd time.Time) CollectStat {
+	nsec := int64(tsEnd.Sub(tsStart).Seconds())
+	minInterval := max(c.minInterval, minQueryRange)
+	queries := map[string]string{
+		"start": tsStart.Format(time.RFC3339),
+		"end":   tsEnd.Format(time.RFC3339),
+	}
+
+	var (
+		mu       sync.Mutex
+		series   int64
+		calls    int64
+		size     int64
+		inflight time.Duration // total time of the series requests
+	)
+	qLimit := c.queryConcurrency()
+	var errg errgroup.Group
+	errg.SetLimit(qLimit)
+	for _, mtc := range c.metrics {
+		errg.Go(func() error {
+			q := maps.Clone(queries)
+			q["match[]"] = generateQueryWitLabel(mtc, c.label)
+			start := time.Now()
+			num, err := getSeriesNum(client, c.endpoint, q, c.customHeader)
+			elapsed := time.Since(start)
+			if err != nil {
+				m.logger.Debugf("Failed to get series of %s: %s", mtc, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			inflight += elapsed
+			calls++
+			if num <= 0 {
+				return nil
+			}
+			block := int64(metricQueryBlock(num, c.limit, minInterval))
+			series += int64(num)
+			calls += (nsec + block - 1) / block
+			size += int64(num) * (nsec / metricScrapeInterval) * metricSampleSize
+			return nil
+		})
+	}
+	// the queries never fail, wait for all of them before reading the totals
+	_ = errg.Wait()
+
+	target := fmt.Sprintf("%d metrics", len(c.metrics))
+	if c.compress {
+		target += ", compressed"
+		// compression rate is approximately 2.5%
+		size = int64(float64(size) * 0.025)
+	}
+	var duration int64
+	if n := int64(len(c.metrics)); n > 0 {
+		// assume dumping a block takes about the same time as counting series
+		duration = int64((inflight/time.Duration(n)).Seconds()*float64(calls)) / int64(qLimit)
+	}
+	return CollectStat{
+		Target: target,
+		Size:   size,
+		Attributes: map[string]interface{}{
+			StatAttrFiles:    calls - int64(len(c.metrics)),
+			StatAttrSeries:   series,
+			StatAttrAPICalls: calls,
+			StatAttrDuration: duration,
+		},
+	}
+}
+
+// metricQueryBlock returns the seconds of the time range dumped by one
+// request, to avoid querying too many data in one request
+func metricQueryBlock(series, speedlimit, minInterval int) int {
+	if speedlimit == 0 {
+		speedlimit = 10000
+	}
+	block := 3600 * speedlimit / series
+	if block > maxQueryRange {
+		block = maxQueryRange
+	}
+	if block < minInterval {
+		block = minInterval
+	}
+	return block
+}
+
+// tryRemoteRead dumps the metric with the remote read API if it's enabled,
+// false is returned if the metric should be dumped with the query API
+func (c *MetricCollectOptions) tryRemoteRead(m *Manager, client *http.Client, tsStart, tsEnd time.Time, mtc string) bool {
+	if !c.remoteRead || c.remoteReadUnsupported.Load() {
+		return false
+	}
+	err := remoteReadMetric(m.logger, client, c.endpoint, tsStart, tsEnd, mtc, c.label, c.resultDir, c.compress, c.customHeader, c.stripLabels, m.progress)
+	switch {
+	case err == errRemoteReadUnsupported:
+		if !c.remoteReadUnsupported.Swap(true) {
+			m.logger.Warnf("Remote read is not supported by %s, fallback to the query API", c.endpoint)
+		}
+		return false
+	case err != nil:
+		m.logger.Errorf("Error remote reading metric %s: %s, fallback to the query API", mtc, err)
+		return false
+	}
+	return true
+}
+
 func getMetricList(c *http.Client, addr string, customHeader []string, start, end string) ([]string, error) {
 	queries := make(map[string]string)
 	if start != "" {
@@ -444,6 +586,7 @@ func collectMetric(
 	customHeader []string,
 	instance string,
 	stripLabels []string,
+	progress *collectProgress, // finished blocks are skipped when resuming
 ) {
 	nameSuffix := ""
 	if len(instance) > 0 {
@@ -500,7 +643,7 @@ func collectMetric(
 				newLabel := make(map[string]string)
 				maps.Copy(newLabel, label)
 				newLabel["instance"] = instance
-				collectMetric(l, c, promAddr, beginTime, endTime, mtc, newLabel, resultDir, speedlimit, minInterval, compress, customHeader, instance, stripLabels)
+				collectMetric(l, c, promAddr, beginTime, endTime, mtc, newLabel, resultDir, speedlimit, minInterval, compress, customHeader, instance, stripLabels, progress)
 			}
 		}
 		return
@@ -512,16 +655,7 @@ func collectMetric(
 	}
 
 	// split time into smaller ranges to avoid querying too many data in one request
-	if speedlimit == 0 {
-		speedlimit = 10000
-	}
-	block := 3600 * speedlimit / series
-	if block > maxQueryRange {
-		block = maxQueryRange
-	}
-	if block < minInterval {
-		block = minInterval
-	}
+	block := metricQueryBlock(series, speedlimit, minInterval)
 
 	l.Debugf("Dumping metric %s-%s-%s%s...", mtc, beginTime.Format(time.RFC3339), endTime.Format(time.RFC3339), nameSuffix)
 	for queryEnd := endTime; queryEnd.After(beginTime); queryEnd = queryEnd.Add(time.Duration(-block) * time.Second) {
@@ -531,6 +665,11 @@ func collectMetric(
 			querySec = int(queryEnd.Sub(beginTime).Seconds())
 			queryBegin = beginTime
 		}
+		fname := fmt.Sprintf("%s-%s-%s%s.json", mtc, queryBegin.Format(time.RFC3339), queryEnd.Format(time.RFC3339), nameSuffix)
+		if progress.TargetDone(progressKeyMetric, fname) {
+			l.Debugf("Metric %s from %s to %s is already dumped, skip", mtc+nameSuffix, queryBegin.Format(time.RFC3339), queryEnd.Format(time.RFC3339))
+			continue
+		}
 		if err := tiuputils.Retry(
 			func() error {
 				req, err := http.NewRequest(
@@ -557,8 +696,7 @@ func collectMetric(
 
 				dst, err := os.Create(
 					filepath.Join(
-						resultDir, subdirMonitor, subdirMetrics, utils.URL2Name(promAddr),
-						fmt.Sprintf("%s-%s-%s%s.json", mtc, queryBegin.Format(time.RFC3339), queryEnd.Format(time.RFC3339), nameSuffix),
+						resultDir, subdirMonitor, subdirMetrics, utils.URL2Name(promAddr), fname,
 					),
 				)
 				if err != nil {
@@ -634,7 +772,9 @@ func collectMetric(
 			},
 		); err != nil {
 			l.Errorf("Error quering metrics %s: %s", mtc+nameSuffix, err)
+			continue
 		}
+		progress.FinishTarget(progressKeyMetric, fname)
 	}
 }
 
@@ -676,6 +816,7 @@ type TSDBCollectOptions struct {
 	fileStats map[string][]CollectStat
 	compress  bool
 	limit     int
+	dryRun    bool // list the data dirs without running the scraper on hosts
 }
 
 // Desc implements the Collector interface
@@ -777,6 +918,9 @@ func (c *TSDBCollectOptions) Prepare(m *Manager, cls *models.TiDBCluster) (map[s
 			hostPaths[inst.GetHost()].Insert(inst.DataDir())
 		}
 	}
+	if c.dryRun {
+		return planHostPaths(hostPaths, "Prometheus data"), nil
+	}
 
 	// build scraper tasks
 	for h, t := range hostTasks {
@@ -1,8 +1,15 @@
 package collector
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +36,42 @@ func TestMetricFilter(t *testing.T) {
 	assert.Equal(filterMetrics(list, filter, nil), []string{"tikv_xxx", "tidb_xxx", "node_xxx"})
 	assert.Equal(filterMetrics(list, nil, nil), []string{"tikv_xxx", "tidb_xxx", "ticdc_xxx", "node_xxx"})
 }
+
+func TestEstimateMetrics(t *testing.T) {
+	assert := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Query().Get("match[]"), `"tikv_a"`):
+			fmt.Fprint(w, `{"status":"success","data":[{"__name__":"tikv_a","instance":"a"},{"__name__":"tikv_a","instance":"b"}]}`)
+		case strings.Contains(r.URL.Query().Get("match[]"), `"tikv_b"`):
+			fmt.Fprint(w, `{"status":"success","data":[]}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	c := &MetricCollectOptions{
+		BaseOptions: &BaseOptions{},
+		opt:         &operator.Options{Concurrency: 2},
+		metrics:     []string{"tikv_a", "tikv_b", "tikv_c"},
+		endpoint:    srv.URL,
+		minInterval: 120,
+	}
+	m := &Manager{logger: logprinter.NewLogger("")}
+	end := time.Now()
+	stat := c.estimateMetrics(m, srv.Client(), end.Add(-time.Hour), end)
+
+	assert.Equal("3 metrics", stat.Target)
+	assert.Equal(int64(2*(3600/metricScrapeInterval)*metricSampleSize), stat.Size)
+	assert.Equal(int64(2), stat.Attributes[StatAttrSeries])
+	// a series request for each metric and a dump request of the hour
+	assert.Equal(int64(4), stat.Attributes[StatAttrAPICalls])
+	assert.Equal(int64(1), stat.Attributes[StatAttrFiles])
+
+	// small blocks for metrics of many series
+	assert.Equal(maxQueryRange, metricQueryBlock(1, 10000, 120))
+	assert.Equal(360, metricQueryBlock(1000, 100, 120))
+	assert.Equal(120, metricQueryBlock(100000, 100, 120))
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultCollectorConcurrency is the default number of collectors running
+// at the same time, collectors run one by one by default as they share the
+// logger, progress bars and stdout of the manager
+const DefaultCollectorConcurrency = 1
+
+// ValidateCollectorConcurrency checks the concurrency options of collectors
+func ValidateCollectorConcurrency(concurrency, hostConcurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("collector concurrency must be at least 1, got %d", concurrency)
+	}
+	if hostConcurrency < 0 {
+		return fmt.Errorf("host concurrency must not be negative, got %d", hostConcurrency)
+	}
+	return nil
+}
+
+// globalHost is the host key of collectors without hosts in their prepare
+// stats, they share the per-host limit with each other instead of running
+// without a limit
+const globalHost = "global"
+
+// collectJob is a collector waiting to be run by the scheduler
+type collectJob struct {
+	collector Collector
+	hosts     []string      // hosts the collector will touch, from its prepare stats
+	deps      []*collectJob // jobs that must be finished before this one starts
+	done      bool
+	err       error
+}
+
+// newCollectJob creates a job for the collector, the host list is taken
+// from the keys of the stats returned by Prepare(), or is globalHost if
+// there is no stat
+func newCollectJob(c Collector, stat map[string][]CollectStat, deps ...*collectJob) *collectJob {
+	hosts := make([]string, 0, len(stat))
+	for host := range stat {
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		hosts = append(hosts, globalHost)
+	}
+	sort.Strings(hosts)
+	return &collectJob{
+		collector: c,
+		hosts:     hosts,
+		deps:      deps,
+	}
+}
+
+// collectScheduler runs collectors concurrently, a job is started only when
+// all its dependencies are finished and neither the global concurrency nor
+// the per-host concurrency limit is reached
+type collectScheduler struct {
+	concurrency     int // max number of jobs running at the same time, <1 means DefaultCollectorConcurrency for callers not setting it
+	hostConcurrency int // max number of running jobs touching one host, <1 means unlimited
+	exitOnError     bool
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	running     int
+	hostRunning map[string]int
+	stopped     bool
+}
+
+func newCollectScheduler(concurrency, hostConcurrency int, exitOnError bool) *collectScheduler {
+	if concurrency < 1 {
+		concurrency = DefaultCollectorConcurrency
+	}
+	s := &collectScheduler{
+		concurrency:     concurrency,
+		hostConcurrency: hostConcurrency,
+		exitOnError:     exitOnError,
+		hostRunning:     make(map[string]int),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Run executes all jobs with the run function and waits for them to finish,
+// the first error is returned if exitOnError is set, in that case no new job
+// is started after the error but the running ones are waited.
+func (s *collectScheduler) Run(jobs []*collectJob, run func(Collector) error) error {
+	var firstErr error
+	pending := append([]*collectJob{}, jobs...)
+	wg := sync.WaitGroup{}
+
+	s.mu.Lock()
+	for len(pending) > 0 && !s.stopped {
+		idx := -1
+		for i, j := range pending {
+			if s.runnable(j) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			s.cond.Wait()
+			continue
+		}
+
+		job := pending[idx]
+		pending = append(pending[:idx], pending[idx+1:]...)
+		s.acquire(job)
+
+		wg.Add(1)
+		go func(job *collectJob) {
+			defer wg.Done()
+			err := run(job.collector)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			job.err = err
+			job.done = true
+			s.release(job)
+			if err != nil && s.exitOnError {
+				if firstErr == nil {
+					firstErr = err
+				}
+				s.stopped = true
+			}
+			s.cond.Broadcast()
+		}(job)
+	}
+	s.mu.Unlock()
+
+	wg.Wait()
+	return firstErr
+}
+
+// runnable checks if a job could be started now, must be called with lock held
+func (s *collectScheduler) runnable(j *collectJob) bool {
+	if s.running >= s.concurrency {
+		return false
+	}
+	for _, dep := range j.deps {
+		if !dep.done {
+			return false
+		}
+	}
+	if s.hostConcurrency < 1 {
+		return true
+	}
+	for _, host := range j.hosts {
+		if s.hostRunning[host] >= s.hostConcurrency {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *collectScheduler) acquire(j *collectJob) {
+	s.running++
+	for _, host := range j.hosts {
+		s.hostRunning[host]++
+	}
+}
+
+func (s *collectScheduler) release(j *collectJob) {
+	s.running--
+	for _, host := range j.hosts {
+		s.hostRunning[host]--
+		if s.hostRunning[host] <= 0 {
+			delete(s.hostRunning, host)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/diag/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCollector struct {
+	BaseOptions
+	name string
+}
+
+func (c *fakeCollector) Prepare(*Manager, *models.TiDBCluster) (map[string][]CollectStat, error) {
+	return nil, nil
+}
+func (c *fakeCollector) Collect(*Manager, *models.TiDBCluster) error { return nil }
+func (c *fakeCollector) GetBaseOptions() *BaseOptions                { return &c.BaseOptions }
+func (c *fakeCollector) SetBaseOptions(*BaseOptions)                 {}
+func (c *fakeCollector) Desc() string                                { return c.name }
+
+func TestSchedulerDependency(t *testing.T) {
+	assert := require.New(t)
+
+	meta := newCollectJob(&fakeCollector{name: "meta"}, nil)
+	jobs := []*collectJob{meta}
+	for _, name := range []string{"log", "config", "metric"} {
+		jobs = append(jobs, newCollectJob(&fakeCollector{name: name}, nil, meta))
+	}
+
+	var mu sync.Mutex
+	order := make([]string, 0)
+	s := newCollectScheduler(4, 0, false)
+	err := s.Run(jobs, func(c Collector) error {
+		if c.Desc() != "meta" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		order = append(order, c.Desc())
+		mu.Unlock()
+		return nil
+	})
+	assert.Nil(err)
+	assert.Len(order, 4)
+	assert.Equal("meta", order[0])
+}
+
+func TestSchedulerLimits(t *testing.T) {
+	assert := require.New(t)
+
+	stat := map[string][]CollectStat{"host1": {{Target: "a"}}}
+	jobs := make([]*collectJob, 0)
+	for i := 0; i < 6; i++ {
+		jobs = append(jobs, newCollectJob(&fakeCollector{}, stat))
+	}
+
+	var mu sync.Mutex
+	var running, peak int
+	s := newCollectScheduler(4, 2, false)
+	err := s.Run(jobs, func(c Collector) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(2, peak)
+
+	// collectors without hosts share the global host
+	jobs = jobs[:0]
+	for i := 0; i < 6; i++ {
+		jobs = append(jobs, newCollectJob(&fakeCollector{}, nil))
+	}
+	assert.Equal([]string{globalHost}, jobs[0].hosts)
+	peak = 0
+	s = newCollectScheduler(4, 1, false)
+	err = s.Run(jobs, func(c Collector) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(1, peak)
+}
+
+func TestSchedulerExitOnError(t *testing.T) {
+	assert := require.New(t)
+
+	jobs := []*collectJob{
+		newCollectJob(&fakeCollector{name: "a"}, nil),
+		newCollectJob(&fakeCollector{name: "b"}, nil),
+		newCollectJob(&fakeCollector{name: "c"}, nil),
+	}
+
+	called := make([]string, 0)
+	s := newCollectScheduler(1, 0, true)
+	err := s.Run(jobs, func(c Collector) error {
+		called = append(called, c.Desc())
+		if c.Desc() == "b" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.EqualError(err, "failed")
+	assert.Equal([]string{"a", "b"}, called)
+}
+
+func TestValidateCollectorConcurrency(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Nil(ValidateCollectorConcurrency(DefaultCollectorConcurrency, 0))
+	assert.Nil(ValidateCollectorConcurrency(4, 2))
+	assert.NotNil(ValidateCollectorConcurrency(0, 2))
+	assert.NotNil(ValidateCollectorConcurrency(-1, 2))
+	assert.NotNil(ValidateCollectorConcurrency(4, -1))
+}
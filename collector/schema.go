@@ -15,7 +15,7 @@ package collector
 
 import (
 	"context"
-	"database/sql"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -39,6 +39,7 @@ type SchemaCollectOptions struct {
 	dbpasswd  string
 	resultDir string
 	fileStats map[string][]CollectStat
+	tlsCfg    *tls.Config
 }
 
 type schemaStruct struct {
@@ -83,13 +84,14 @@ func (c *SchemaCollectOptions) SetDir(dir string) {
 }
 
 // Prepare implements the Collector interface
-func (c *SchemaCollectOptions) Prepare(_ *Manager, _ *models.TiDBCluster) (map[string][]CollectStat, error) {
-	return nil, nil
+func (c *SchemaCollectOptions) Prepare(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+	return sqlStats(topo, "db_vars", len(collectedSchemas), len(collectedSchemas),
+		int64(len(collectedSchemas))*50*1024), nil
 }
 
 // Collect implements the Collector interface
 func (c *SchemaCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error {
-	err := os.Mkdir(filepath.Join(c.resultDir, DirNameSchema), 0755)
+	err := os.MkdirAll(filepath.Join(c.resultDir, DirNameSchema), 0755)
 	if err != nil {
 		return err
 	}
@@ -105,22 +107,11 @@ func (c *SchemaCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) err
 		Func(
 			"collect db_vars",
 			func(ctx context.Context) error {
-				var db *sql.DB
-				for _, inst := range tidbInstants {
-					trydb, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/", c.dbuser, c.dbpasswd, inst.Host(), inst.MainPort()))
-					if err != nil {
-						return err
-					}
-					defer trydb.Close()
-					err = trydb.Ping()
-					if err == nil {
-						db = trydb
-						break
-					}
-				}
-				if db == nil {
-					return fmt.Errorf("cannot connect to any TiDB instance")
+				db, err := openTiDB(tidbInstants, c.dbuser, c.dbpasswd, c.tlsCfg)
+				if err != nil {
+					return err
 				}
+				defer db.Close()
 
 				var errs []string
 				for _, s := range collectedSchemas {
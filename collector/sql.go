@@ -0,0 +1,389 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/joomcode/errorx"
+	json "github.com/json-iterator/go"
+	"github.com/pingcap/diag/pkg/models"
+	"github.com/pingcap/diag/pkg/utils/toml"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+)
+
+//go:embed sql_catalog.toml
+var builtinSQLCatalog []byte
+
+// default timeout in seconds and row limit of a query
+const (
+	defaultSQLTimeout = 30
+	defaultSQLLimit   = 10000
+)
+
+const (
+	fileNameSQLManifest = "manifest.json"
+	tlsConfigNameTiDB   = "diag-cluster" // name of the cluster TLS config registered to the driver
+)
+
+// SQLQuery is a query of the sql_snapshot collector
+type SQLQuery struct {
+	Name    string `toml:"name"`
+	SQL     string `toml:"sql"`
+	Timeout int    `toml:"timeout,omitempty"` // seconds
+	Limit   int    `toml:"limit,omitempty"`   // max number of rows
+}
+
+type sqlCatalog struct {
+	Queries []SQLQuery `toml:"query"`
+}
+
+// loadSQLQueries merges the extra queries to the built-in catalog, an extra
+// query replaces the built-in one of the same name, and is removed if its
+// sql is empty
+func loadSQLQueries(extra []SQLQuery) ([]SQLQuery, error) {
+	var catalog sqlCatalog
+	if err := toml.Unmarshal(builtinSQLCatalog, &catalog); err != nil {
+		return nil, err
+	}
+	queries := catalog.Queries
+	for _, q := range extra {
+		found := false
+		for i := range queries {
+			if queries[i].Name == q.Name {
+				queries[i] = q
+				found = true
+				break
+			}
+		}
+		if !found {
+			queries = append(queries, q)
+		}
+	}
+
+	result := make([]SQLQuery, 0, len(queries))
+	names := make(map[string]struct{})
+	for _, q := range queries {
+		if q.Name == "" || strings.ContainsAny(q.Name, `/\`) {
+			return nil, fmt.Errorf("invalid name of sql query: '%s'", q.Name)
+		}
+		if _, ok := names[q.Name]; ok {
+			return nil, fmt.Errorf("duplicate sql query: %s", q.Name)
+		}
+		names[q.Name] = struct{}{}
+		if strings.TrimSpace(q.SQL) == "" {
+			continue
+		}
+		if q.Timeout <= 0 {
+			q.Timeout = defaultSQLTimeout
+		}
+		if q.Limit <= 0 {
+			q.Limit = defaultSQLLimit
+		}
+		result = append(result, q)
+	}
+	return result, nil
+}
+
+// openTiDBInstance connects to a TiDB instance with dbName as the default
+// database, the TLS config is registered to the driver and tried first if
+// it is set, as the SQL port may not enable TLS even if the cluster does,
+// plaintext is only tried if the server does not support TLS
+func openTiDBInstance(inst *models.TiDBSpec, user, passwd, dbName string, tlsCfg *tls.Config) (*sql.DB, error) {
+	tlsNames := []string{""}
+	if tlsCfg != nil {
+		if err := mysql.RegisterTLSConfig(tlsConfigNameTiDB, tlsCfg); err != nil {
+			return nil, err
+		}
+		tlsNames = []string{tlsConfigNameTiDB, ""}
+	}
+
+	var lastErr error
+	for _, tlsName := range tlsNames {
+		if lastErr != nil && !errors.Is(lastErr, mysql.ErrNoTLS) {
+			// other errors of TLS, e.g., a bad certificate, are not bypassed
+			break
+		}
+		cfg := mysql.NewConfig()
+		cfg.User = user
+		cfg.Passwd = passwd
+		cfg.Net = "tcp"
+		cfg.Addr = fmt.Sprintf("%s:%d", inst.Host(), inst.MainPort())
+		cfg.DBName = dbName
+		cfg.TLSConfig = tlsName
+		db, err := sql.Open("mysql", cfg.FormatDSN())
+		if err != nil {
+			return nil, err
+		}
+		if lastErr = db.Ping(); lastErr == nil {
+			return db, nil
+		}
+		db.Close()
+	}
+	return nil, lastErr
+}
+
+// openTiDB connects to the first available TiDB instance
+func openTiDB(tidbs []*models.TiDBSpec, user, passwd string, tlsCfg *tls.Config) (*sql.DB, error) {
+	var lastErr error
+	for _, inst := range tidbs {
+		db, err := openTiDBInstance(inst, user, passwd, "", tlsCfg)
+		if err == nil {
+			return db, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return nil, fmt.Errorf("no TiDB instance found")
+	}
+	return nil, fmt.Errorf("cannot connect to any TiDB instance: %s", lastErr)
+}
+
+// sqlStats returns the estimation of a collector querying TiDB, the queries
+// are sent to the first TiDB instance
+func sqlStats(topo *models.TiDBCluster, target string, files, queries int, size int64) map[string][]CollectStat {
+	if len(topo.TiDB) < 1 {
+		return nil
+	}
+	host := topo.TiDB[0].Host()
+	return map[string][]CollectStat{
+		host: {{
+			Target: target,
+			Size:   size,
+			Attributes: map[string]interface{}{
+				StatAttrFiles:    files,
+				StatAttrAPICalls: queries,
+			},
+		}},
+	}
+}
+
+// sqlRows is the subset of *sql.Rows used to write results
+type sqlRows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// writeRowsCSV writes at most limit rows as CSV with a header, returns the
+// number of rows written and if there are more
+func writeRowsCSV(fp string, rows sqlRows, limit int) (int, bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, false, err
+	}
+	f, err := os.Create(fp)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write(columns); err != nil {
+		return 0, false, err
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	count, truncated := 0, false
+	for rows.Next() {
+		if count >= limit {
+			truncated = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, false, err
+		}
+		record := make([]string, len(columns))
+		for i, v := range values {
+			switch v := v.(type) {
+			case nil:
+			case []byte:
+				record[i] = string(v)
+			default:
+				record[i] = fmt.Sprintf("%v", v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return count, false, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, false, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return count, false, err
+	}
+	return count, truncated, f.Close()
+}
+
+// SQLResult is the result of a query in the manifest
+type SQLResult struct {
+	Name      string `json:"name"`
+	SQL       string `json:"sql"`
+	File      string `json:"file,omitempty"`
+	Rows      int    `json:"rows"`
+	Truncated bool   `json:"truncated,omitempty"` // there are more rows than the limit
+	Elapsed   int64  `json:"elapsed_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SQLManifest lists the results of the sql_snapshot collector
+type SQLManifest struct {
+	CollectedAt time.Time   `json:"collected_at"`
+	Results     []SQLResult `json:"results"`
+}
+
+// SQLSnapshotCollectOptions are options used collecting query results of TiDB
+type SQLSnapshotCollectOptions struct {
+	*BaseOptions
+	opt       *operator.Options // global operations from cli
+	dbuser    string
+	dbpasswd  string
+	queries   []SQLQuery
+	resultDir string
+	tlsCfg    *tls.Config
+}
+
+// Desc implements the Collector interface
+func (c *SQLSnapshotCollectOptions) Desc() string {
+	return "SQL snapshots of the cluster"
+}
+
+// GetBaseOptions implements the Collector interface
+func (c *SQLSnapshotCollectOptions) GetBaseOptions() *BaseOptions {
+	return c.BaseOptions
+}
+
+// SetBaseOptions implements the Collector interface
+func (c *SQLSnapshotCollectOptions) SetBaseOptions(opt *BaseOptions) {
+	c.BaseOptions = opt
+}
+
+// SetGlobalOperations sets the global operation fileds
+func (c *SQLSnapshotCollectOptions) SetGlobalOperations(opt *operator.Options) {
+	c.opt = opt
+}
+
+// SetDir sets the result directory path
+func (c *SQLSnapshotCollectOptions) SetDir(dir string) {
+	c.resultDir = dir
+}
+
+// Prepare implements the Collector interface
+func (c *SQLSnapshotCollectOptions) Prepare(_ *Manager, topo *models.TiDBCluster) (map[string][]CollectStat, error) {
+	// the results and the manifest
+	return sqlStats(topo, fmt.Sprintf("%d sql_snapshot queries", len(c.queries)),
+		len(c.queries)+1, len(c.queries), int64(len(c.queries))*100*1024), nil
+}
+
+// Collect implements the Collector interface
+func (c *SQLSnapshotCollectOptions) Collect(m *Manager, topo *models.TiDBCluster) error {
+	dir := filepath.Join(c.resultDir, DirNameSQLSnapshot)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	ctx := ctxt.New(
+		context.Background(),
+		c.opt.Concurrency,
+		m.logger,
+	)
+
+	t := task.NewBuilder(m.logger).
+		Func(
+			"collect sql_snapshot",
+			func(ctx context.Context) error {
+				db, err := openTiDB(topo.TiDB, c.dbuser, c.dbpasswd, c.tlsCfg)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				manifest := runSQLQueries(ctx, db, c.queries, dir)
+				for _, r := range manifest.Results {
+					if r.Error != "" {
+						m.logger.Warnf("fail querying %s: %s, continue", r.Name, r.Error)
+					}
+				}
+				data, err := json.MarshalIndent(manifest, "", "  ")
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, fileNameSQLManifest), data, 0644)
+			},
+		).
+		BuildAsStep("  - Querying sql_snapshot")
+
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	return nil
+}
+
+// runSQLQueries saves results of the queries to dir, errors of queries are
+// recorded in the manifest
+func runSQLQueries(ctx context.Context, db *sql.DB, queries []SQLQuery, dir string) *SQLManifest {
+	manifest := &SQLManifest{CollectedAt: time.Now()}
+	for _, q := range queries {
+		result := SQLResult{Name: q.Name, SQL: q.SQL}
+		start := time.Now()
+		err := func() error {
+			qctx, cancel := context.WithTimeout(ctx, time.Duration(q.Timeout)*time.Second)
+			defer cancel()
+			rows, err := db.QueryContext(qctx, q.SQL)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			result.File = q.Name + ".csv"
+			result.Rows, result.Truncated, err = writeRowsCSV(filepath.Join(dir, result.File), rows, q.Limit)
+			if result.Truncated {
+				// the rest rows would be read and discarded by rows.Close(),
+				// cancelling the query closes the connection instead
+				cancel()
+			}
+			return err
+		}()
+		result.Elapsed = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Error = err.Error()
+		}
+		manifest.Results = append(manifest.Results, result)
+	}
+	return manifest
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSQLQueries(t *testing.T) {
+	assert := require.New(t)
+
+	builtin, err := loadSQLQueries(nil)
+	assert.Nil(err)
+	assert.Greater(len(builtin), 3)
+	assert.Equal("cluster_info", builtin[0].Name)
+	assert.Equal(defaultSQLTimeout, builtin[0].Timeout)
+	assert.Equal(defaultSQLLimit, builtin[0].Limit)
+
+	queries, err := loadSQLQueries([]SQLQuery{
+		{Name: "cluster_info", SQL: "SELECT TYPE FROM INFORMATION_SCHEMA.CLUSTER_INFO", Limit: 10},
+		{Name: "ddl_jobs"},
+		{Name: "users", SQL: "SELECT user FROM mysql.user"},
+	})
+	assert.Nil(err)
+	assert.Len(queries, len(builtin)) // one replaced, one removed and one added
+	assert.Equal("SELECT TYPE FROM INFORMATION_SCHEMA.CLUSTER_INFO", queries[0].SQL)
+	assert.Equal(10, queries[0].Limit)
+	assert.Equal("users", queries[len(queries)-1].Name)
+	for _, q := range queries {
+		assert.NotEqual("ddl_jobs", q.Name)
+	}
+
+	_, err = loadSQLQueries([]SQLQuery{{Name: "../users", SQL: "SELECT 1"}})
+	assert.NotNil(err)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]interface{}
+	cur     int
+}
+
+func (r *fakeRows) Columns() ([]string, error) { return r.columns, nil }
+
+func (r *fakeRows) Next() bool {
+	r.cur++
+	return r.cur <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	for i, v := range r.rows[r.cur-1] {
+		*dest[i].(*interface{}) = v
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func TestWriteRowsCSV(t *testing.T) {
+	assert := require.New(t)
+
+	rows := &fakeRows{
+		columns: []string{"INSTANCE", "VERSION", "UPTIME"},
+		rows: [][]interface{}{
+			{[]byte("10.0.0.1:4000"), []byte("v8.5.0"), int64(10)},
+			{[]byte("10.0.0.2:4000"), nil, int64(20)},
+			{[]byte("10.0.0.3:4000"), []byte("v8.5.0, patched"), int64(30)},
+		},
+	}
+	fp := filepath.Join(t.TempDir(), "cluster_info.csv")
+	count, truncated, err := writeRowsCSV(fp, rows, 2)
+	assert.Nil(err)
+	assert.Equal(2, count)
+	assert.True(truncated)
+	data, err := os.ReadFile(fp)
+	assert.Nil(err)
+	assert.Equal("INSTANCE,VERSION,UPTIME\n10.0.0.1:4000,v8.5.0,10\n10.0.0.2:4000,,20\n", string(data))
+
+	rows.cur = 0
+	count, truncated, err = writeRowsCSV(fp, rows, 10)
+	assert.Nil(err)
+	assert.Equal(3, count)
+	assert.False(truncated)
+	data, err = os.ReadFile(fp)
+	assert.Nil(err)
+	assert.Contains(string(data), "\"v8.5.0, patched\"")
+}
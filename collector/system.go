@@ -14,28 +14,63 @@
 package collector
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/google/uuid"
 	"github.com/joomcode/errorx"
 	json "github.com/json-iterator/go"
 	insight "github.com/pingcap/diag/collector/sysinfo"
 	"github.com/pingcap/diag/pkg/models"
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/repository"
 	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
+// insightArgs enables collecting of extra info with insight
+const insightArgs = "--syscfg --dmesg"
+
+// manualToolsDir is the prefix of temp dirs to run insight in manual mode,
+// they are owned by the login user as sudo may not be available
+const manualToolsDir = "/tmp/diag-insight"
+
+// newToolsDir returns a temp dir to run insight, it is unique for each run
+// so that diag runs against the same host do not remove tools of others
+func newToolsDir() string {
+	return fmt.Sprintf("%s-%s", manualToolsDir, uuid.NewString())
+}
+
+// insightPackageDir is where the image of diag in kubernetes ships insight
+// packages, named insight-linux-<arch>.tar.gz with insight in the root
+var insightPackageDir = "/usr/local/share/diag"
+
 // SystemCollectOptions are options used collecting system information
 type SystemCollectOptions struct {
 	*BaseOptions
 	opt       *operator.Options // global operations from cli
 	resultDir string
+	kubeCli   *kubernetes.Clientset
+	restCfg   *rest.Config
 }
 
 // Desc implements the Collector interface
@@ -64,16 +99,35 @@ func (c *SystemCollectOptions) SetDir(dir string) {
 }
 
 // Prepare implements the Collector interface
-func (c *SystemCollectOptions) Prepare(_ *Manager, _ *models.TiDBCluster) (map[string][]CollectStat, error) {
-	return nil, nil
+func (c *SystemCollectOptions) Prepare(_ *Manager, cls *models.TiDBCluster) (map[string][]CollectStat, error) {
+	result := make(map[string][]CollectStat)
+	for host := range c.systemHosts(cls) {
+		result[host] = append(result[host], CollectStat{
+			Target: fmt.Sprintf("%s system info", host),
+			Size:   200 * 1024,
+			Attributes: map[string]interface{}{
+				StatAttrFiles: 2, // insight.json and ss.txt
+			},
+		})
+	}
+	return result, nil
 }
 
 // Collect implements the Collector interface
 func (c *SystemCollectOptions) Collect(m *Manager, cls *models.TiDBCluster) error {
-	if m.mode != CollectModeTiUP {
+	switch m.mode {
+	case CollectModeTiUP:
+		return c.collectTiUP(m, cls)
+	case CollectModeManual:
+		return c.collectManual(m, cls)
+	case CollectModeK8s:
+		return c.collectK8s(m, cls)
+	default:
 		return nil
 	}
+}
 
+func (c *SystemCollectOptions) collectTiUP(m *Manager, cls *models.TiDBCluster) error {
 	topo := cls.Attributes[CollectModeTiUP].(spec.Topology)
 	var (
 		collectInsightTasks []*task.StepDisplay
@@ -136,7 +190,7 @@ func (c *SystemCollectOptions) Collect(m *Manager, cls *models.TiDBCluster) erro
 						host,
 						fmt.Sprintf("%s %s",
 							filepath.Join(task.CheckToolsPathDir, "bin", "insight"),
-							"--syscfg --dmesg", // enable collecting of extra info
+							insightArgs,
 						),
 						"",
 						true,
@@ -235,6 +289,12 @@ func saveRawOutput(ctx context.Context, host, dir, fname string) error {
 
 func saveInsightOutput(ctx context.Context, host, dir string) error {
 	stdout, stderr, _ := ctxt.GetInner(ctx).GetOutputs(host)
+	return saveInsightData(stdout, stderr, host, dir)
+}
+
+// saveInsightData saves the output of insight to the dir of host, it is
+// shared by all deploy modes so the checker reads the same files
+func saveInsightData(stdout, stderr []byte, host, dir string) error {
 	if len(stderr) > 0 {
 		if err := saveOutput(stderr, filepath.Join(dir, host, "insight.stderr")); err != nil {
 			return err
@@ -242,7 +302,7 @@ func saveInsightOutput(ctx context.Context, host, dir string) error {
 	}
 
 	var info insight.InsightInfo
-	if err := json.Unmarshal(stdout, &info); err != nil {
+	if err := json.Unmarshal(stdout, &info); err != nil || info.SysConfig == nil {
 		// save output directly on parsing errors
 		return saveOutput(stdout, filepath.Join(dir, host, "insight.json"))
 	}
@@ -278,3 +338,349 @@ func saveInsightOutput(ctx context.Context, host, dir string) error {
 
 	return saveOutput(stdout, filepath.Join(dir, host, "insight.json"))
 }
+
+// systemHosts returns the unique hosts of the filtered components, with the
+// SSH port of the first instance on each host
+func (c *SystemCollectOptions) systemHosts(cls *models.TiDBCluster) map[string]int {
+	roleFilter := set.NewStringSet(c.opt.Roles...)
+	nodeFilter := set.NewStringSet(c.opt.Nodes...)
+	comps := models.FilterComponent(cls.Components(), roleFilter)
+
+	hosts := make(map[string]int)
+	for _, inst := range models.FilterInstance(comps, nodeFilter) {
+		if _, found := hosts[inst.Host()]; found {
+			continue
+		}
+		port := inst.SSHPort()
+		if port == 0 {
+			port = 22
+		}
+		hosts[inst.Host()] = port
+	}
+	return hosts
+}
+
+// normalizeArch converts the output of `uname -m` to the arch name used by
+// tiup packages
+func normalizeArch(machine string) (string, error) {
+	switch strings.TrimSpace(machine) {
+	case "x86_64", "amd64":
+		return "amd64", nil
+	case "aarch64", "arm64":
+		return "arm64", nil
+	default:
+		return "", fmt.Errorf("unsupported arch '%s'", strings.TrimSpace(machine))
+	}
+}
+
+// collectManual runs insight on the hosts with plain SSH, as there is no
+// topology of tiup, the arch of each host is detected before the download
+func (c *SystemCollectOptions) collectManual(m *Manager, cls *models.TiDBCluster) error {
+	hosts := c.systemHosts(cls)
+	if len(hosts) < 1 {
+		return nil
+	}
+	insightVer := spec.TiDBComponentVersion(componentDiagCollector, "")
+
+	var (
+		mu     sync.Mutex
+		arches = make(map[string]string) // host -> arch
+	)
+	var detectTasks []*task.StepDisplay
+	for h, port := range hosts {
+		host := h
+		t := c.manualSSH(m, host, port).
+			Shell(host, "uname -m", "", false).
+			Func(host, func(ctx context.Context) error {
+				stdout, _, _ := ctxt.GetInner(ctx).GetOutputs(host)
+				arch, err := normalizeArch(string(stdout))
+				if err != nil {
+					return perrs.Annotatef(err, "failed to detect arch of %s", host)
+				}
+				mu.Lock()
+				arches[host] = arch
+				mu.Unlock()
+				return nil
+			}).
+			BuildAsStep(fmt.Sprintf("  - Detecting arch of %s:%d", host, port))
+		detectTasks = append(detectTasks, t)
+	}
+
+	ctx := ctxt.New(
+		context.Background(),
+		c.opt.Concurrency,
+		m.logger,
+	)
+	// unreachable hosts and unsupported arches are skipped
+	if err := task.NewBuilder(m.logger).
+		ParallelStep("+ Detect arch of hosts", true, detectTasks...).
+		Build().
+		Execute(ctx); err != nil {
+		return perrs.Trace(err)
+	}
+	for host, port := range hosts {
+		if _, ok := arches[host]; !ok {
+			m.logger.Warnf("Failed to detect arch of %s:%d, skip collecting its system info", host, port)
+			delete(hosts, host)
+		}
+	}
+	if len(hosts) < 1 {
+		return fmt.Errorf("failed to detect arch of all hosts")
+	}
+	toolsDir := newToolsDir()
+
+	var (
+		downloadTasks       []*task.StepDisplay
+		collectInsightTasks []*task.StepDisplay
+		cleanTasks          []*task.StepDisplay
+		uniqueArchList      = make(map[string]struct{})
+	)
+	for h, port := range hosts {
+		host := h
+		arch := arches[host]
+		if _, found := uniqueArchList[arch]; !found {
+			uniqueArchList[arch] = struct{}{}
+			t0 := task.NewBuilder(m.logger).
+				Download(componentDiagCollector, "linux", arch, insightVer).
+				BuildAsStep(fmt.Sprintf("  - Downloading check tools for linux/%s", arch))
+			downloadTasks = append(downloadTasks, t0)
+		}
+
+		// the SSH executors are already in the context
+		t1 := task.NewBuilder(m.logger).
+			Shell(host, fmt.Sprintf("mkdir -p %s", filepath.Join(toolsDir, "bin")), "", false).
+			CopyComponent(
+				componentDiagCollector,
+				"linux",
+				arch,
+				insightVer,
+				"", // use default srcPath
+				host,
+				toolsDir,
+			).
+			Shell(
+				host,
+				fmt.Sprintf("%s %s", filepath.Join(toolsDir, "bin", "insight"), insightArgs),
+				"",
+				false,
+			).
+			Func(host, func(ctx context.Context) error {
+				return saveInsightOutput(ctx, host, c.resultDir)
+			}).
+			Shell(host, "ss -lanp", "", false).
+			Func(host, func(ctx context.Context) error {
+				return saveRawOutput(ctx, host, c.resultDir, "ss.txt")
+			}).
+			BuildAsStep(fmt.Sprintf("  - Getting system info of %s:%d", host, port))
+		collectInsightTasks = append(collectInsightTasks, t1)
+
+		t2 := task.NewBuilder(m.logger).
+			Shell(host, fmt.Sprintf("rm -rf %s", toolsDir), "", false).
+			BuildAsStep(fmt.Sprintf("  - Cleanup temp files on %s:%d", host, port))
+		cleanTasks = append(cleanTasks, t2)
+	}
+
+	t := task.NewBuilder(m.logger).
+		ParallelStep("+ Download necessary tools", false, downloadTasks...).
+		ParallelStep("+ Collect host information", false, collectInsightTasks...).
+		ParallelStep("+ Cleanup temp files", false, cleanTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return perrs.Trace(err)
+	}
+	return nil
+}
+
+// manualSSH builds a task connecting to the host with the credentials from cli
+func (c *SystemCollectOptions) manualSSH(m *Manager, host string, port int) *task.Builder {
+	sshProps := c.GetBaseOptions().SSH
+	if sshProps == nil {
+		sshProps = &tui.SSHConnectionProps{}
+	}
+	return task.NewBuilder(m.logger).
+		RootSSH(
+			host,
+			port,
+			c.GetBaseOptions().User,
+			sshProps.Password,
+			sshProps.IdentityFile,
+			sshProps.IdentityFilePassphrase,
+			c.opt.SSHTimeout,
+			c.opt.OptTimeout,
+			c.opt.SSHProxyHost,
+			c.opt.SSHProxyPort,
+			c.opt.SSHProxyUser,
+			"", "", "", // proxy credentials are not supported in manual mode
+			c.opt.SSHProxyTimeout,
+			c.opt.SSHType,
+			executor.SSHTypeBuiltin,
+		)
+}
+
+// collectK8s runs insight in the containers of pods, the package is
+// streamed to the pod via stdin, so only sh and tar are required in the image
+func (c *SystemCollectOptions) collectK8s(m *Manager, cls *models.TiDBCluster) error {
+	if c.kubeCli == nil || c.restCfg == nil {
+		return fmt.Errorf("kubernetes client is not initialized")
+	}
+	roleFilter := set.NewStringSet(c.opt.Roles...)
+	nodeFilter := set.NewStringSet(c.opt.Nodes...)
+	comps := models.FilterComponent(cls.Components(), roleFilter)
+	insts := make([]models.Component, 0)
+	for _, inst := range models.FilterInstance(comps, nodeFilter) {
+		// component like prometheus does not have pod name
+		if _, ok := inst.Attributes()["pod"].(string); ok {
+			insts = append(insts, inst)
+		}
+	}
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].ID() < insts[j].ID()
+	})
+
+	var (
+		mu       sync.Mutex
+		packages = make(map[string]string) // arch -> package path
+	)
+	getPackage := func(arch string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if pkg, found := packages[arch]; found {
+			return pkg, nil
+		}
+		pkg, err := insightPackage(arch)
+		if err != nil {
+			return "", err
+		}
+		packages[arch] = pkg
+		return pkg, nil
+	}
+
+	var errg errgroup.Group
+	if c.opt.Concurrency > 0 {
+		errg.SetLimit(c.opt.Concurrency)
+	}
+	for _, i := range insts {
+		inst := i
+		errg.Go(func() error {
+			pod := inst.Attributes()["pod"].(string)
+			ns, _ := inst.Attributes()["namespace"].(string)
+			m.logger.Infof("  - Getting system info of pod %s/%s", ns, pod)
+			// a failed pod does not stop collecting from the others
+			if err := c.collectPod(inst, ns, pod, getPackage); err != nil {
+				m.logger.Warnf("failed to get system info of pod %s/%s: %s", ns, pod, err)
+			}
+			return nil
+		})
+	}
+	return errg.Wait()
+}
+
+// collectPod runs insight in the container of an instance
+func (c *SystemCollectOptions) collectPod(
+	inst models.Component,
+	ns, pod string,
+	getPackage func(arch string) (string, error),
+) error {
+	container := string(inst.Type())
+
+	stdout, _, err := c.execInPod(ns, pod, container, []string{"uname", "-m"}, nil)
+	if err != nil {
+		return perrs.Annotatef(err, "failed to detect arch of pod %s", pod)
+	}
+	arch, err := normalizeArch(string(stdout))
+	if err != nil {
+		return err
+	}
+	pkg, err := getPackage(arch)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(pkg)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	script := fmt.Sprintf(
+		"mkdir -p %[1]s && tar --no-same-owner -zxf - -C %[1]s && %[1]s/insight %[2]s; rc=$?; rm -rf %[1]s; exit $rc",
+		newToolsDir(), insightArgs,
+	)
+	stdout, stderr, err := c.execInPod(ns, pod, container, []string{"sh", "-c", script}, f)
+	if err != nil {
+		return perrs.Annotatef(err, "failed to run insight in pod %s, stderr: %s", pod, stderr)
+	}
+	if err := saveInsightData(stdout, stderr, inst.Host(), c.resultDir); err != nil {
+		return err
+	}
+
+	// ss is not always available in the image, ignore the errors
+	if stdout, _, err := c.execInPod(ns, pod, container, []string{"ss", "-lanp"}, nil); err == nil {
+		return saveOutput(stdout, filepath.Join(c.resultDir, inst.Host(), "ss.txt"))
+	}
+	return nil
+}
+
+// insightPackage returns the insight package of the arch shipped in the
+// image, or downloads it with tiup if not found
+func insightPackage(arch string) (string, error) {
+	pkg := filepath.Join(insightPackageDir, fmt.Sprintf("insight-linux-%s.tar.gz", arch))
+	if _, err := os.Stat(pkg); err == nil {
+		return pkg, nil
+	}
+	return downloadInsight(arch)
+}
+
+// downloadInsight downloads the latest diag package of the arch to the local
+// cache and returns the path of it
+func downloadInsight(arch string) (string, error) {
+	env := environment.GlobalEnv()
+	if env == nil {
+		return "", fmt.Errorf("tiup environment is not initialized")
+	}
+	ver, _, err := env.V1Repository().WithOptions(repository.Options{
+		GOOS:   "linux",
+		GOARCH: arch,
+	}).LatestStableVersion(componentDiagCollector, false)
+	if err != nil {
+		return "", err
+	}
+	if err := operator.Download(componentDiagCollector, "linux", arch, string(ver)); err != nil {
+		return "", err
+	}
+	return spec.PackagePath(componentDiagCollector, string(ver), "linux", arch), nil
+}
+
+// execInPod runs a command in the container and returns its outputs
+func (c *SystemCollectOptions) execInPod(
+	ns, pod, container string,
+	command []string,
+	stdin io.Reader,
+) ([]byte, []byte, error) {
+	req := c.kubeCli.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(ns).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(c.restCfg, "POST", req.URL())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = exec.Stream(remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	return stdout.Bytes(), stderr.Bytes(), err
+}
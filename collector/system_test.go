@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/diag/pkg/models"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeArch(t *testing.T) {
+	assert := require.New(t)
+
+	for machine, arch := range map[string]string{
+		"x86_64\n": "amd64",
+		"aarch64":  "arm64",
+		"arm64":    "arm64",
+	} {
+		got, err := normalizeArch(machine)
+		assert.Nil(err)
+		assert.Equal(arch, got)
+	}
+	_, err := normalizeArch("ppc64le")
+	assert.NotNil(err)
+}
+
+func TestSaveInsightData(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	stdout := []byte(`{"system_configs":{"sysctl":{"vm.swappiness":"0"},"sec_limit":[{"domain":"tidb","type":"soft","item":"nofile","value":1000000}]},"dmesg":[]}`)
+	assert.Nil(saveInsightData(stdout, []byte("warning"), "10.0.0.1", dir))
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, "10.0.0.1", name))
+		assert.Nil(err, name)
+		return string(data)
+	}
+	assert.Equal(string(stdout), read("insight.json"))
+	assert.Equal("vm.swappiness = 0\n", read("sysctl.conf"))
+	assert.Equal("tidb\tsoft\tnofile\t1000000\n", read("limits.conf"))
+	assert.Equal("warning", read("insight.stderr"))
+
+	// unparsable outputs are saved as is
+	assert.Nil(saveInsightData([]byte("not json"), nil, "10.0.0.2", dir))
+	data, err := os.ReadFile(filepath.Join(dir, "10.0.0.2", "insight.json"))
+	assert.Nil(err)
+	assert.Equal("not json", string(data))
+}
+
+func TestInsightPackage(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	origin := insightPackageDir
+	insightPackageDir = dir
+	defer func() { insightPackageDir = origin }()
+
+	pkg := filepath.Join(dir, "insight-linux-arm64.tar.gz")
+	assert.Nil(os.WriteFile(pkg, nil, 0644))
+	got, err := insightPackage("arm64")
+	assert.Nil(err)
+	assert.Equal(pkg, got)
+}
+
+func TestSystemHosts(t *testing.T) {
+	assert := require.New(t)
+
+	topo := &models.TiDBCluster{
+		TiDB: []*models.TiDBSpec{
+			{ComponentSpec: models.ComponentSpec{Host: "10.0.0.1", Port: 4000, SSHPort: 2222}},
+			{ComponentSpec: models.ComponentSpec{Host: "10.0.0.1", Port: 4001}},
+			{ComponentSpec: models.ComponentSpec{Host: "10.0.0.2", Port: 4000}},
+		},
+		PD: []*models.PDSpec{{ComponentSpec: models.ComponentSpec{Host: "10.0.0.3", Port: 2379}}},
+	}
+	c := &SystemCollectOptions{opt: &operator.Options{}}
+	assert.Equal(map[string]int{"10.0.0.1": 2222, "10.0.0.2": 22, "10.0.0.3": 22}, c.systemHosts(topo))
+
+	c.opt.Roles = []string{"tidb"}
+	assert.Equal(map[string]int{"10.0.0.1": 2222, "10.0.0.2": 22}, c.systemHosts(topo))
+}
+
+func TestNewToolsDir(t *testing.T) {
+	assert := require.New(t)
+
+	dir := newToolsDir()
+	assert.True(strings.HasPrefix(dir, manualToolsDir+"-"))
+	assert.NotEqual(dir, newToolsDir())
+}
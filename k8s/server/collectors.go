@@ -111,7 +111,7 @@ func collectData(c *gin.Context) {
 	explainSQLs := req.ExplainSqls
 
 	// run collector
-	go runCollector(diagCtx, &opt, worker, req, explainSQLs, req.Metricfilter)
+	go runCollector(diagCtx, &opt, worker, req, explainSQLs, req.Metricfilter, false)
 
 	c.JSON(http.StatusAccepted, worker.job)
 }
@@ -123,6 +123,7 @@ func runCollector(
 	req interface{},
 	explainSQLs []string,
 	metricFilters []string,
+	resume bool,
 ) {
 	gOpt := operator.Options{
 		Concurrency: 2,
@@ -143,6 +144,13 @@ func runCollector(
 		ExplainSqls:     explainSQLs,
 		MetricsFilter:   metricFilters,
 		CompressMetrics: true,
+		Concurrency:     collector.DefaultCollectorConcurrency,
+		HostConcurrency: 2,
+		Resume:          resume,
+		RestConfig:      ctx.restCfg,
+	}
+	if r, ok := req.(types.CollectJobRequest); ok {
+		cOpt.DBSecret = r.DbSecret
 	}
 
 	// populate logger for the collect job
@@ -377,6 +385,9 @@ func operateCollectJob(c *gin.Context) {
 	case "retry":
 		reCollectData(c, diagCtx, id)
 		return
+	case "resume":
+		resumeCollectData(c, diagCtx, id)
+		return
 	}
 
 	msg := "unknown operation."
@@ -418,12 +429,74 @@ func reCollectData(c *gin.Context, diagCtx *context, id string) {
 		ScrapeEnd:   worker.job.To,
 	}
 
+	req, err := restoreCollectRequest(cluster.RawRequest)
+	if err != nil {
+		diagCtx.setJobStatus(worker.job.ID, taskStatusError)
+		sendErrMsg(c, http.StatusInternalServerError, fmt.Sprintf("can't restore the collect request: %s", err))
+		return
+	}
+
 	// clean data
 	os.RemoveAll(requestDir)
 
 	// run collector
-	go runCollector(diagCtx, &opt, worker, cluster.RawRequest, []string{}, []string{})
+	go runCollector(diagCtx, &opt, worker, req, req.ExplainSqls, req.Metricfilter, false)
+	diagCtx.setJobStatus(worker.job.ID, taskStatusRunning)
+
+	c.JSON(http.StatusAccepted, worker.job)
+}
+
+// resumeCollectData continues an interrupted collect job, data already
+// collected are kept and only the missing parts are collected
+func resumeCollectData(c *gin.Context, diagCtx *context, id string) {
+	worker := diagCtx.getCollectWorker(id)
+	if worker == nil || worker.job == nil ||
+		worker.job.Dir == "" || worker.job.Status == taskStatusPurge {
+		msg := fmt.Sprintf("data set for collect job '%s' not found", id)
+		sendErrMsg(c, http.StatusNotFound, msg)
+		return
+	}
+
+	if worker.job.Status != taskStatusInterrupt {
+		msg := fmt.Sprintf(" collect job %s status is %s, can't resume.", id, worker.job.Status)
+		sendErrMsg(c, http.StatusNotAcceptable, msg)
+		return
+	}
+
+	cluster, err := collector.GetClusterInfoFromFile(worker.job.Dir)
+	if err != nil {
+		msg := "can't get cluster metadata."
+		sendErrMsg(c, http.StatusInternalServerError, msg)
+		return
+	}
+
+	nscluster := strings.Split(worker.job.ClusterName, "/")
+	opt := collector.BaseOptions{
+		Cluster:   nscluster[1],
+		Namespace: nscluster[0],
+	}
+
+	req, err := restoreCollectRequest(cluster.RawRequest)
+	if err != nil {
+		sendErrMsg(c, http.StatusInternalServerError, fmt.Sprintf("can't restore the collect request: %s", err))
+		return
+	}
+
+	// collectors and time range are restored from the data set
+	go runCollector(diagCtx, &opt, worker, req, req.ExplainSqls, req.Metricfilter, true)
 	diagCtx.setJobStatus(worker.job.ID, taskStatusRunning)
 
 	c.JSON(http.StatusAccepted, worker.job)
 }
+
+// restoreCollectRequest converts the raw request saved in cluster.json back
+// to a collect job request, it is decoded as a map from the JSON file
+func restoreCollectRequest(raw interface{}) (types.CollectJobRequest, error) {
+	var req types.CollectJobRequest
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return req, err
+	}
+	err = json.Unmarshal(data, &req)
+	return req, err
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/diag/api/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
 
@@ -71,6 +72,7 @@ type context struct {
 	sync.RWMutex
 
 	kubeCli     *kubernetes.Clientset
+	restCfg     *rest.Config
 	dynCli      dynamic.Interface
 	collectJobs map[string]*collectJobWorker
 }
@@ -98,6 +100,15 @@ func (ctx *context) withKubeCli(kubeCli *kubernetes.Clientset) *context {
 	return ctx
 }
 
+// withRestConfig sets the config of kubernetes API server
+func (ctx *context) withRestConfig(cfg *rest.Config) *context {
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	ctx.restCfg = cfg
+	return ctx
+}
+
 // withDynCli sets kubernetes dynamic client
 func (ctx *context) withDynCli(dynCli dynamic.Interface) *context {
 	ctx.Lock()
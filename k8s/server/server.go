@@ -77,7 +77,7 @@ func NewServer(opt *Options) (*DiagAPIServer, error) {
 	klog.Info("initialized kube clients")
 
 	return &DiagAPIServer{
-		engine:  newEngine(newContext().withKubeCli(kubeCli).withDynCli(dynCli), opt),
+		engine:  newEngine(newContext().withKubeCli(kubeCli).withDynCli(dynCli).withRestConfig(cfg), opt),
 		address: fmt.Sprintf("%s:%d", opt.Host, opt.Port),
 	}, nil
 }
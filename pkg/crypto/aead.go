@@ -0,0 +1,265 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The chunked stream starts with the RSA-OAEP encrypted AES key, followed by
+// a sequence of chunks, each chunk is:
+//
+//	| 4 bytes plaintext length | AES-GCM sealed data (length + 16 bytes) |
+//
+// The nonce of a chunk is its sequence number, and the additional data is the
+// caller provided context followed by a byte marking whether it is the last
+// chunk, so reordered, dropped or truncated chunks, as well as a modified
+// context, are all detected. Any data following the last chunk is treated as
+// tampered.
+const (
+	ChunkSize = 64 * 1024
+
+	chunkHeaderSize = 4
+	chunkFlagMore   = 0
+	chunkFlagFinal  = 1
+)
+
+var (
+	// ErrTruncated is returned when the stream ends before the final chunk
+	ErrTruncated = errors.New("encrypted data is truncated")
+	// ErrTampered is returned when a chunk fails the authentication
+	ErrTampered = errors.New("encrypted data is corrupted or tampered")
+)
+
+// ChunkError indicates the chunk where the decryption failed
+type ChunkError struct {
+	Index uint64
+	Err   error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d: %s", e.Index, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// chunkAD returns the additional data of a chunk
+func chunkAD(ad []byte, flag byte) []byte {
+	out := make([]byte, 0, len(ad)+1)
+	return append(append(out, ad...), flag)
+}
+
+func chunkNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+// ChunkEncryptWriter encrypts data with AES-GCM chunk by chunk, Close() must
+// be called to write the final chunk
+type ChunkEncryptWriter struct {
+	aead   cipher.AEAD
+	header *bytes.Buffer
+	buffer []byte
+	index  uint64
+	closed bool
+	ad     []byte // additional data of chunks, without the flag
+	w      io.Writer
+}
+
+// NewChunkEncryptWriter creates a ChunkEncryptWriter, the ad is authenticated
+// along with every chunk and must be passed to NewChunkDecryptor unchanged
+func NewChunkEncryptWriter(pub *rsa.PublicKey, w io.Writer, ad []byte) (*ChunkEncryptWriter, error) {
+	aesKey := make([]byte, 32)
+	n, err := rand.Read(aesKey)
+	if n != len(aesKey) || err != nil {
+		return nil, fmt.Errorf("generate aes key failed: %v, %d/%d bytes generated", err, n, len(aesKey))
+	}
+	encKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, aesKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChunkEncryptWriter{
+		aead:   aead,
+		header: bytes.NewBuffer(encKey),
+		buffer: make([]byte, 0, ChunkSize),
+		ad:     ad,
+		w:      w,
+	}, nil
+}
+
+func (w *ChunkEncryptWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, fmt.Errorf("write to closed encrypt writer")
+	}
+	for len(p) > 0 {
+		size := ChunkSize - len(w.buffer)
+		if size > len(p) {
+			size = len(p)
+		}
+		w.buffer = append(w.buffer, p[:size]...)
+		p = p[size:]
+		n += size
+		// keep the last chunk in buffer, it is written on closing
+		if len(w.buffer) == ChunkSize && len(p) > 0 {
+			if err := w.flush(chunkFlagMore); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the final chunk, it does not close the underlying writer
+func (w *ChunkEncryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(chunkFlagFinal)
+}
+
+func (w *ChunkEncryptWriter) flush(flag byte) error {
+	// write OAEP header before the first chunk
+	if w.header.Len() > 0 {
+		if _, err := io.Copy(w.w, w.header); err != nil {
+			return err
+		}
+	}
+
+	out := make([]byte, chunkHeaderSize, chunkHeaderSize+len(w.buffer)+w.aead.Overhead())
+	binary.BigEndian.PutUint32(out, uint32(len(w.buffer)))
+	out = w.aead.Seal(out, chunkNonce(w.aead, w.index), w.buffer, chunkAD(w.ad, flag))
+	if _, err := w.w.Write(out); err != nil {
+		return err
+	}
+	w.index++
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+type chunkDecryptor struct {
+	aead   cipher.AEAD
+	buffer *bytes.Buffer
+	index  uint64
+	final  bool
+	err    error // the first error, returned by all following reads
+	ad     []byte
+	reader io.Reader
+}
+
+// NewChunkDecryptor creates a Decryptor for data encrypted by ChunkEncryptWriter,
+// an error of ErrTruncated or ErrTampered wrapped in ChunkError is returned
+// if the data or the ad is broken
+func NewChunkDecryptor(priv *rsa.PrivateKey, reader io.Reader, ad []byte) (Decryptor, error) {
+	encKey := make([]byte, priv.Size())
+	if n, err := io.ReadFull(reader, encKey); err != nil {
+		return nil, fmt.Errorf("read buffer failed: %v, %d/%d bytes read", err, n, len(encKey))
+	}
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, encKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chunkDecryptor{
+		aead:   aead,
+		buffer: bytes.NewBuffer(nil),
+		ad:     ad,
+		reader: reader,
+	}, nil
+}
+
+func (d *chunkDecryptor) Read(p []byte) (n int, err error) {
+	for d.buffer.Len() == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+	return d.buffer.Read(p)
+}
+
+// next reads and opens the next chunk
+func (d *chunkDecryptor) next() error {
+	head := make([]byte, chunkHeaderSize)
+	if _, err := io.ReadFull(d.reader, head); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return &ChunkError{Index: d.index, Err: ErrTruncated}
+		}
+		return err
+	}
+	size := binary.BigEndian.Uint32(head)
+	if size > ChunkSize {
+		return &ChunkError{Index: d.index, Err: ErrTampered}
+	}
+
+	sealed := make([]byte, int(size)+d.aead.Overhead())
+	if _, err := io.ReadFull(d.reader, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return &ChunkError{Index: d.index, Err: ErrTruncated}
+		}
+		return err
+	}
+
+	nonce := chunkNonce(d.aead, d.index)
+	plain, err := d.aead.Open(nil, nonce, sealed, chunkAD(d.ad, chunkFlagMore))
+	if err != nil {
+		plain, err = d.aead.Open(nil, nonce, sealed, chunkAD(d.ad, chunkFlagFinal))
+		if err != nil {
+			return &ChunkError{Index: d.index, Err: ErrTampered}
+		}
+		// nothing could follow the final chunk
+		var extra [1]byte
+		if n, err := io.ReadFull(d.reader, extra[:]); n > 0 {
+			return &ChunkError{Index: d.index + 1, Err: ErrTampered}
+		} else if err != io.EOF {
+			return err
+		}
+		d.final = true
+	}
+	d.index++
+	d.buffer.Write(plain)
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -48,3 +49,82 @@ func TestEncryptAndDecrypt(t *testing.T) {
 
 	assert.Equal("Hello, PingCAP", string(decBuf[:n]))
 }
+
+func TestChunkEncryptAndDecrypt(t *testing.T) {
+	assert := require.New(t)
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err)
+
+	// more than 2 chunks
+	plaintext := make([]byte, ChunkSize*2+100)
+	_, err = rand.Read(plaintext)
+	assert.Nil(err)
+	ad := []byte("header")
+
+	encrypt := func() []byte {
+		encB := bytes.NewBuffer(nil)
+		encW, err := NewChunkEncryptWriter(&priv.PublicKey, encB, ad)
+		assert.Nil(err)
+		n, err := encW.Write(plaintext)
+		assert.Nil(err)
+		assert.Equal(len(plaintext), n)
+		assert.Nil(encW.Close())
+		return encB.Bytes()
+	}
+	decryptWithAD := func(data, ad []byte) ([]byte, error) {
+		dec, err := NewChunkDecryptor(priv, bytes.NewReader(data), ad)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(dec)
+	}
+	decrypt := func(data []byte) ([]byte, error) {
+		return decryptWithAD(data, ad)
+	}
+
+	data := encrypt()
+	out, err := decrypt(data)
+	assert.Nil(err)
+	assert.Equal(plaintext, out)
+
+	// the additional data is modified
+	_, err = decryptWithAD(data, []byte("Header"))
+	assert.ErrorIs(err, ErrTampered)
+	_, err = decryptWithAD(data, nil)
+	assert.ErrorIs(err, ErrTampered)
+
+	chunkLen := chunkHeaderSize + ChunkSize + 16
+
+	// tampered in the 2nd chunk
+	tampered := append([]byte{}, data...)
+	tampered[priv.Size()+chunkLen+100] ^= 0xFF
+	_, err = decrypt(tampered)
+	assert.ErrorIs(err, ErrTampered)
+	var chunkErr *ChunkError
+	assert.ErrorAs(err, &chunkErr)
+	assert.EqualValues(1, chunkErr.Index)
+
+	// truncated at the chunk boundary
+	_, err = decrypt(data[:priv.Size()+chunkLen*2])
+	assert.ErrorIs(err, ErrTruncated)
+	assert.ErrorAs(err, &chunkErr)
+	assert.EqualValues(2, chunkErr.Index)
+
+	// truncated inside a chunk
+	_, err = decrypt(data[:len(data)-1])
+	assert.ErrorIs(err, ErrTruncated)
+
+	// data appended after the final chunk
+	_, err = decrypt(append(append([]byte{}, data...), 0))
+	assert.ErrorIs(err, ErrTampered)
+
+	// empty data is still terminated by a final chunk
+	encB := bytes.NewBuffer(nil)
+	encW, err := NewChunkEncryptWriter(&priv.PublicKey, encB, ad)
+	assert.Nil(err)
+	assert.Nil(encW.Close())
+	out, err = decrypt(encB.Bytes())
+	assert.Nil(err)
+	assert.Len(out, 0)
+}
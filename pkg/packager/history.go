@@ -18,6 +18,8 @@ import (
 	"os"
 	"path"
 	"strings"
+
+	json "github.com/json-iterator/go"
 )
 
 type Histroy struct {
@@ -25,12 +27,18 @@ type Histroy struct {
 	list []string
 }
 
-func LoadHistroy() (*Histroy, error) {
+// historyDir returns the dir to save upload records
+func historyDir() (string, error) {
 	dir := os.Getenv("TIUP_COMPONENT_DATA_DIR")
 	if dir == "" {
 		dir = path.Join(os.Getenv("HOME"), ".clinic")
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+func LoadHistroy() (*Histroy, error) {
+	dir, err := historyDir()
+	if err != nil {
 		return nil, err
 	}
 	file := path.Join(dir, "history.txt")
@@ -59,3 +67,65 @@ func (h *Histroy) PrintList() {
 		fmt.Println(url)
 	}
 }
+
+// UploadIndex maps checksums of manifests of uploaded packages to their
+// result URLs, so that a package identical to a previous upload to the same
+// endpoint is not uploaded again
+type UploadIndex struct {
+	file    string
+	uploads map[string]string
+}
+
+// maxUploadIndex is the max number of uploads in the index
+const maxUploadIndex = 100
+
+func LoadUploadIndex() (*UploadIndex, error) {
+	dir, err := historyDir()
+	if err != nil {
+		return nil, err
+	}
+	idx := &UploadIndex{
+		file:    path.Join(dir, "uploads.json"),
+		uploads: make(map[string]string),
+	}
+	data, err := os.ReadFile(idx.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	return idx, json.Unmarshal(data, &idx.uploads)
+}
+
+// uploadKey is the key of an upload in the index, the same package uploaded
+// to different endpoints are different uploads
+func uploadKey(endpoint, sum string) string {
+	return strings.TrimSuffix(endpoint, "/") + " " + sum
+}
+
+// Lookup returns the result URL of the package with the manifest checksum
+// uploaded to the endpoint
+func (i *UploadIndex) Lookup(endpoint, sum string) (string, bool) {
+	url, ok := i.uploads[uploadKey(endpoint, sum)]
+	return url, ok
+}
+
+func (i *UploadIndex) Add(endpoint, sum, url string) {
+	if len(i.uploads) >= maxUploadIndex {
+		// the index is a cache, dropping any entry is fine
+		for k := range i.uploads {
+			delete(i.uploads, k)
+			break
+		}
+	}
+	i.uploads[uploadKey(endpoint, sum)] = url
+}
+
+func (i *UploadIndex) Store() error {
+	data, err := json.Marshal(i.uploads)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(i.file, data, 0600)
+}
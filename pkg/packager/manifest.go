@@ -0,0 +1,261 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	// ManifestFileName is the name of the manifest, it is the first entry
+	// of the archive in a package
+	ManifestFileName = "manifest.json"
+	// ManifestSuffix is the suffix of the manifest saved along with the
+	// package, for listing the content of a package without decrypting it
+	ManifestSuffix = ".manifest.json"
+)
+
+// ManifestFile is a collected file in the package
+type ManifestFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	Collector string `json:"collector,omitempty"`
+	Host      string `json:"host,omitempty"`
+}
+
+// Manifest lists all files in the package
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestStat is the file count and size of a collector
+type ManifestStat struct {
+	Files int   `json:"files"`
+	Size  int64 `json:"size"`
+}
+
+// ManifestSummary is the summary of a manifest saved in the package meta
+type ManifestSummary struct {
+	SHA256     string                  `json:"sha256"` // checksum of the manifest file
+	Files      int                     `json:"files"`
+	Size       int64                   `json:"size"`
+	Collectors map[string]ManifestStat `json:"collectors"`
+	Hosts      []string                `json:"hosts"`
+	Missing    []string                `json:"missing,omitempty"` // enabled collectors without any file
+}
+
+// BuildManifest walks the collected data dir and computes checksums of all
+// files in it
+func BuildManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Files: make([]ManifestFile, 0)}
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		collector, host := classifyFile(rel)
+		m.Files = append(m.Files, ManifestFile{
+			Path:      rel,
+			Size:      info.Size(),
+			SHA256:    sum,
+			Collector: collector,
+			Host:      host,
+		})
+		return nil
+	})
+	return m, err
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Summary counts files of each collector, enabled is the list of collectors
+// in cluster.json, e.g., "log.std" or "db_vars"
+func (m *Manifest) Summary(data []byte, enabled []string) *ManifestSummary {
+	sum := sha256.Sum256(data)
+	s := &ManifestSummary{
+		SHA256:     hex.EncodeToString(sum[:]),
+		Collectors: make(map[string]ManifestStat),
+		Hosts:      make([]string, 0),
+	}
+	hosts := make(map[string]struct{})
+	for _, f := range m.Files {
+		s.Files++
+		s.Size += f.Size
+		if f.Collector != "" {
+			stat := s.Collectors[f.Collector]
+			stat.Files++
+			stat.Size += f.Size
+			s.Collectors[f.Collector] = stat
+		}
+		if _, ok := hosts[f.Host]; !ok && f.Host != "" {
+			hosts[f.Host] = struct{}{}
+			s.Hosts = append(s.Hosts, f.Host)
+		}
+	}
+	sort.Strings(s.Hosts)
+
+	missing := make(map[string]struct{})
+	for _, c := range enabled {
+		name := strings.SplitN(c, ".", 2)[0]
+		if _, ok := s.Collectors[name]; !ok {
+			missing[name] = struct{}{}
+		}
+	}
+	for name := range missing {
+		s.Missing = append(s.Missing, name)
+	}
+	sort.Strings(s.Missing)
+	return s
+}
+
+// CollectorNames returns sorted names of collectors in the summary
+func (s *ManifestSummary) CollectorNames() []string {
+	names := make([]string, 0, len(s.Collectors))
+	for name := range s.Collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadManifestSummary reads the manifest summary saved along with the
+// package, the meta in the header is only readable without the private key
+// if the package is not encrypted
+func LoadManifestSummary(pkg string) (*ManifestSummary, error) {
+	data, err := os.ReadFile(pkg + ManifestSuffix)
+	if os.IsNotExist(err) {
+		data, err = readRawManifestSummary(pkg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &ManifestSummary{}
+	return s, json.Unmarshal(data, s)
+}
+
+func readRawManifestSummary(pkg string) ([]byte, error) {
+	f, err := os.Open(pkg)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h, err := ReadD1agHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	if h.Format != "unknown" {
+		return nil, fmt.Errorf("no manifest summary found for the encrypted package %s", pkg)
+	}
+	meta := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(h.Meta, &meta); err != nil {
+		return nil, err
+	}
+	data, ok := meta["manifest"]
+	if !ok {
+		return nil, fmt.Errorf("no manifest summary in the meta of %s", pkg)
+	}
+	return data, nil
+}
+
+// names of top level files and dirs, they are the same as in the collector package
+var manifestTopLevel = map[string]string{
+	"cluster.json":      "",
+	"meta.yaml":         "",
+	"tidbcluster.json":  "",
+	"tidbmonitor.json":  "",
+	"diag.log":          "",
+	"monitor":           "monitor",
+	"db_vars":           "db_vars",
+	"sql_bind":          "sql_bind",
+	"sql_snapshot":      "sql_snapshot",
+	"plan_replayer.zip": "plan_replayer",
+	"logs":              "log",
+}
+
+// classifyFile guesses the collector and the host of a file from its path
+// in the collected data dir
+func classifyFile(path string) (collector, host string) {
+	parts := strings.Split(path, "/")
+	if c, ok := manifestTopLevel[parts[0]]; ok {
+		switch {
+		case parts[0] == "logs" && len(parts) > 2:
+			// logs/<pod>/<file> in kubernetes
+			host = parts[1]
+		case parts[0] == "monitor" && len(parts) > 3 && parts[1] == "raw":
+			// monitor/raw/<host>-<port>/<file>
+			if i := strings.LastIndex(parts[2], "-"); i > 0 {
+				host = parts[2][:i]
+			}
+		}
+		return c, host
+	}
+	if strings.HasSuffix(parts[0], "_audit") {
+		return "audit_log", ""
+	}
+	if len(parts) == 1 {
+		return "", ""
+	}
+
+	// files in host dirs
+	host = parts[0]
+	if len(parts) == 2 {
+		return "system", host
+	}
+	switch parts[len(parts)-2] {
+	case "debug", "perf", "component_meta":
+		return parts[len(parts)-2], host
+	case "conf":
+		return "config", host
+	case "log":
+		return "log", host
+	}
+	switch filepath.Ext(path) {
+	case ".toml", ".yaml", ".yml":
+		return "config", host
+	}
+	return "log", host
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyFile(t *testing.T) {
+	assert := require.New(t)
+
+	cases := []struct {
+		path      string
+		collector string
+		host      string
+	}{
+		{"cluster.json", "", ""},
+		{"db_vars/mysql.tidb.csv", "db_vars", ""},
+		{"monitor/metrics/up_1.json", "monitor", ""},
+		{"monitor/raw/172.16.0.1-9090/01GX.tar", "monitor", "172.16.0.1"},
+		{"logs/basic-tidb-0/tidb.log", "log", "basic-tidb-0"},
+		{"tidb-cluster_audit/audit.log", "audit_log", ""},
+		{"172.16.0.1/insight.json", "system", "172.16.0.1"},
+		{"172.16.0.1/tidb-4000/debug/info.txt", "debug", "172.16.0.1"},
+		{"172.16.0.1/tidb-4000/conf/config.json", "config", "172.16.0.1"},
+		{"172.16.0.1/tidb-deploy/tidb-4000/conf/tidb.toml", "config", "172.16.0.1"},
+		{"172.16.0.1/tidb-deploy/tidb-4000/log/tidb.log", "log", "172.16.0.1"},
+	}
+	for _, c := range cases {
+		collector, host := classifyFile(c.path)
+		assert.Equal(c.collector, collector, c.path)
+		assert.Equal(c.host, host, c.path)
+	}
+}
+
+func TestManifestSummary(t *testing.T) {
+	assert := require.New(t)
+
+	m := &Manifest{Files: []ManifestFile{
+		{Path: "cluster.json", Size: 10},
+		{Path: "h2/insight.json", Size: 20, Collector: "system", Host: "h2"},
+		{Path: "h1/insight.json", Size: 30, Collector: "system", Host: "h1"},
+		{Path: "h1/tidb/log/tidb.log", Size: 40, Collector: "log", Host: "h1"},
+	}}
+	s := m.Summary([]byte("{}"), []string{"system", "log.std", "log.slow", "config.file"})
+	assert.Equal(4, s.Files)
+	assert.EqualValues(100, s.Size)
+	assert.Equal(ManifestStat{Files: 2, Size: 50}, s.Collectors["system"])
+	assert.Equal([]string{"log", "system"}, s.CollectorNames())
+	assert.Equal([]string{"h1", "h2"}, s.Hosts)
+	assert.Equal([]string{"config"}, s.Missing)
+}
+
+func TestLoadManifestSummaryFromMeta(t *testing.T) {
+	assert := require.New(t)
+
+	pkg := filepath.Join(t.TempDir(), "test.diag")
+	meta := map[string]interface{}{
+		"cluster_id": "1",
+		"manifest":   &ManifestSummary{SHA256: "abc", Files: 1, Hosts: []string{"h1"}},
+	}
+	header, err := GenerateD1agHeaderWithVersion(meta, VersionAEAD, TypeZST, nil)
+	assert.Nil(err)
+	assert.Nil(os.WriteFile(pkg, header, 0644))
+
+	// not encrypted, read from the meta
+	s, err := LoadManifestSummary(pkg)
+	assert.Nil(err)
+	assert.Equal("abc", s.SHA256)
+	assert.Equal([]string{"h1"}, s.Hosts)
+
+	// the sidecar file is preferred
+	assert.Nil(os.WriteFile(pkg+ManifestSuffix, []byte(`{"sha256":"def"}`), 0644))
+	s, err = LoadManifestSummary(pkg)
+	assert.Nil(err)
+	assert.Equal("def", s.SHA256)
+}
+
+func TestUploadIndex(t *testing.T) {
+	assert := require.New(t)
+
+	t.Setenv("TIUP_COMPONENT_DATA_DIR", t.TempDir())
+	idx, err := LoadUploadIndex()
+	assert.Nil(err)
+	_, ok := idx.Lookup("https://clinic.pingcap.com", "abc")
+	assert.False(ok)
+	idx.Add("https://clinic.pingcap.com", "abc", "https://clinic.pingcap.com/1")
+	assert.Nil(idx.Store())
+
+	idx, err = LoadUploadIndex()
+	assert.Nil(err)
+	url, ok := idx.Lookup("https://clinic.pingcap.com/", "abc")
+	assert.True(ok)
+	assert.Equal("https://clinic.pingcap.com/1", url)
+	// uploaded to another endpoint
+	_, ok = idx.Lookup("https://clinic.pingcap.com.cn", "abc")
+	assert.False(ok)
+}
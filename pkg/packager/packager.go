@@ -17,6 +17,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	json "github.com/json-iterator/go"
 	"github.com/klauspost/compress/zstd"
@@ -40,6 +42,16 @@ type PackageOptions struct {
 	CertPath   string // crt file to encrypt data
 	Rebuild    bool
 	Meta       map[string]interface{}
+	// AEADFormat packages in VersionAEAD, it is opt-in until all consumers
+	// reject the versions they do not support
+	AEADFormat bool
+}
+
+func (p *PackageOptions) version() byte {
+	if p.AEADFormat {
+		return VersionAEAD
+	}
+	return VersionLegacy
 }
 
 const (
@@ -48,78 +60,197 @@ const (
 	TypeZST        = 02
 	TypeRaw        = 020
 	TypeEncryption = 030
+
+	// package versions, the highest 2 bits of the type byte
+	VersionLegacy = 0000 // AES-CFB without authentication
+	VersionAEAD   = 0100 // chunked AES-GCM, see crypto.ChunkEncryptWriter
 )
 
+// D1agHeader is the parsed header of a .diag package
+type D1agHeader struct {
+	Meta     []byte // meta data, encrypted if the format is "diag"
+	Version  byte
+	Format   string
+	Compress string
+	Offset   int    // where the payload starts
+	Raw      []byte // the raw bytes of the header
+}
+
+// d1agMetaAD returns the additional data of the encrypted meta of
+// VersionAEAD, which is the magic and the type byte of the header
+func d1agMetaAD(header []byte) []byte {
+	return header[:5]
+}
+
+// d1agPayloadAD returns the additional data of the encrypted payload of
+// VersionAEAD, which is the checksum of the whole header
+func d1agPayloadAD(header []byte) []byte {
+	sum := sha256.Sum256(header)
+	return sum[:]
+}
+
+// MetaAD returns the additional data to decrypt the meta
+func (h *D1agHeader) MetaAD() []byte {
+	return d1agMetaAD(h.Raw)
+}
+
+// PayloadAD returns the additional data to decrypt the payload
+func (h *D1agHeader) PayloadAD() []byte {
+	return d1agPayloadAD(h.Raw)
+}
+
 // meta not compress
 func GenerateD1agHeader(meta map[string]interface{}, compress byte, cert *x509.Certificate) ([]byte, error) {
+	return GenerateD1agHeaderWithVersion(meta, VersionLegacy, compress, cert)
+}
+
+// GenerateD1agHeaderWithVersion generates the header of a package of the
+// given version
+func GenerateD1agHeaderWithVersion(meta map[string]interface{}, version, compress byte, cert *x509.Certificate) ([]byte, error) {
 	header := []byte("D1ag")
-	packageType := compress & 070
+	if version != VersionLegacy && version != VersionAEAD {
+		return nil, fmt.Errorf("unknown package version: %x", version)
+	}
+	packageType := version | compress&007
+	if cert == nil {
+		packageType |= TypeRaw
+	} else {
+		packageType |= TypeEncryption
+	}
+	header = append(header, packageType)
 
 	var w io.Writer
+	var closeW func() error
 	metaBuf := new(bytes.Buffer)
 
 	if cert == nil {
-		packageType |= TypeRaw
 		w = metaBuf
 	} else {
 		// encryption meta information
-		packageType |= TypeEncryption
 		publicKey := cert.PublicKey.(*rsa.PublicKey)
-		w, _ = crypto.NewEncryptWriter(publicKey, metaBuf)
+		encW, err := newD1agEncryptWriter(version, publicKey, metaBuf, d1agMetaAD(header))
+		if err != nil {
+			return nil, err
+		}
+		w, closeW = encW, encW.Close
 	}
 
 	j, err := json.Marshal(meta)
 	if err != nil {
 		return nil, err
 	}
-	w.Write(j)
+	if _, err := w.Write(j); err != nil {
+		return nil, err
+	}
+	if closeW != nil {
+		if err := closeW(); err != nil {
+			return nil, err
+		}
+	}
 
 	if metaBuf.Len() > 0xFFFFFF {
 		return nil, fmt.Errorf("the meta is too big")
 	}
-	header = append(header, packageType, byte(metaBuf.Len()>>16), byte(metaBuf.Len()>>8), byte(metaBuf.Len()))
+	header = append(header, byte(metaBuf.Len()>>16), byte(metaBuf.Len()>>8), byte(metaBuf.Len()))
 	header = append(header, metaBuf.Bytes()...)
 	return header, nil
 }
 
 func ParserD1agHeader(r io.Reader) (meta []byte, format, compress string, offset int, err error) {
-	buf := make([]byte, 8)
-	_, err = r.Read(buf)
+	h, err := ReadD1agHeader(r)
 	if err != nil {
 		return nil, "", "", 0, err
 	}
+	return h.Meta, h.Format, h.Compress, h.Offset, nil
+}
+
+// ReadD1agHeader reads and parses the header of a .diag package
+func ReadD1agHeader(r io.Reader) (*D1agHeader, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
 
 	if string(buf[0:4]) != "D1ag" {
-		// return nil, "legacy", "zstd", 0, nil
-		return nil, "", "", 0, fmt.Errorf("input is not a diag package, please use diag v0.7.0 or newer version to package and upload")
+		return nil, fmt.Errorf("input is not a diag package, please use diag v0.7.0 or newer version to package and upload")
+	}
+
+	h := &D1agHeader{}
+	// byte 1~2
+	h.Version = buf[4] & 0300
+	switch h.Version {
+	case VersionLegacy, VersionAEAD:
+	default:
+		return nil, fmt.Errorf("unsupported package version: %x, please use a newer version of diag", buf[4])
 	}
 
 	// byte 3~5
 	switch buf[4] & 070 {
 	case TypeRaw:
-		format = "unknown"
+		h.Format = "unknown"
 	case TypeEncryption:
-		format = "diag"
+		h.Format = "diag"
 	default:
-		return nil, "", "", 0, fmt.Errorf("unknown type: %x", buf[4])
+		return nil, fmt.Errorf("unknown type: %x", buf[4])
 	}
 
 	// byte 6~8
 	switch buf[4] & 007 {
 	case TypeNoCompress:
-		compress = "none"
+		h.Compress = "none"
 	case TypeGZ:
-		compress = "gzip"
+		h.Compress = "gzip"
 	case TypeZST:
-		compress = "zstd"
+		h.Compress = "zstd"
 	default:
-		return nil, "", "", 0, fmt.Errorf("unknown type: %x", buf[4])
+		return nil, fmt.Errorf("unknown type: %x", buf[4])
 	}
 
 	metaLen := int(buf[5])<<16 + int(buf[6])<<8 + int(buf[7])
-	meta = make([]byte, metaLen)
-	_, err = r.Read(meta)
-	return meta, format, compress, metaLen + 8, err
+	h.Meta = make([]byte, metaLen)
+	if _, err := io.ReadFull(r, h.Meta); err != nil {
+		return nil, err
+	}
+	h.Offset = metaLen + 8
+	h.Raw = append(buf, h.Meta...)
+	return h, nil
+}
+
+// nopWriteCloser is a writer which needs nothing to be done on closing
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newD1agEncryptWriter creates an encrypt writer for the meta or payload of a
+// package of the given version, the ad is ignored by VersionLegacy
+func newD1agEncryptWriter(version byte, pub *rsa.PublicKey, w io.Writer, ad []byte) (io.WriteCloser, error) {
+	switch version {
+	case VersionLegacy:
+		encW, err := crypto.NewEncryptWriter(pub, w)
+		if err != nil {
+			return nil, err
+		}
+		return nopWriteCloser{encW}, nil
+	case VersionAEAD:
+		return crypto.NewChunkEncryptWriter(pub, w, ad)
+	default:
+		return nil, fmt.Errorf("unsupported package version: %x", version)
+	}
+}
+
+// NewD1agDecryptor creates a decryptor for the encrypted meta or payload of
+// a package of the given version, the ad is ignored by VersionLegacy
+func NewD1agDecryptor(version byte, priv *rsa.PrivateKey, r io.Reader, ad []byte) (crypto.Decryptor, error) {
+	switch version {
+	case VersionLegacy:
+		return crypto.NewDecryptor(priv, r)
+	case VersionAEAD:
+		return crypto.NewChunkDecryptor(priv, r, ad)
+	default:
+		return nil, fmt.Errorf("unsupported package version: %x", version)
+	}
 }
 
 func PackageCollectedData(pOpt *PackageOptions, skipConfirm bool) (string, error) {
@@ -148,17 +279,6 @@ func PackageCollectedData(pOpt *PackageOptions, skipConfirm bool) (string, error
 	}
 	publicKey := cert.PublicKey.(*rsa.PublicKey)
 
-	fileW, err := os.Create(output)
-	if err != nil {
-		return "", err
-	}
-	defer fileW.Close()
-	encryptW, _ := crypto.NewEncryptWriter(publicKey, fileW)
-	compressW, _ := zstd.NewWriter(encryptW)
-	defer compressW.Close()
-	tarW := tar.NewWriter(compressW)
-	defer tarW.Close()
-
 	// read cluster name and id
 	body, err := os.ReadFile(filepath.Join(input, "cluster.json"))
 	if err != nil {
@@ -183,26 +303,84 @@ func PackageCollectedData(pOpt *PackageOptions, skipConfirm bool) (string, error
 	}
 	meta["rebuild"] = pOpt.Rebuild
 
+	// only regular files have content in the archive, the same as the manifest
 	var size int64
 	filepath.Walk(input, func(path string, info fs.FileInfo, err error) error {
-		if !info.IsDir() {
+		if err == nil && info.Mode().IsRegular() {
 			size += info.Size()
 		}
 		return nil
 	})
 	meta["dir_size"] = size
 
-	header, err := GenerateD1agHeader(meta, TypeZST, cert)
+	manifest, err := BuildManifest(input)
+	if err != nil {
+		return "", err
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	enabled := make([]string, 0)
+	if list, ok := clusterJSON["collectors"].([]interface{}); ok {
+		for _, c := range list {
+			enabled = append(enabled, fmt.Sprint(c))
+		}
+	}
+	summary := manifest.Summary(manifestData, enabled)
+	meta["manifest"] = summary
+
+	version := pOpt.version()
+	header, err := GenerateD1agHeaderWithVersion(meta, version, TypeZST, cert)
+	if err != nil {
+		return "", err
+	}
+
+	fileW, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	defer fileW.Close()
+	if _, err := fileW.Write(header); err != nil {
+		return "", err
+	}
+	// the header is authenticated along with the payload
+	encryptW, err := newD1agEncryptWriter(version, publicKey, fileW, d1agPayloadAD(header))
+	if err != nil {
+		return "", err
+	}
+	compressW, err := zstd.NewWriter(encryptW)
+	if err != nil {
+		return "", err
+	}
+	tarW := tar.NewWriter(compressW)
+
+	// the manifest is always the first entry
+	err = tarW.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     ManifestFileName,
+		Mode:     0644,
+		Size:     int64(len(manifestData)),
+		ModTime:  time.Now(),
+	})
 	if err != nil {
 		return "", err
 	}
-	fileW.Write(header)
+	if _, err := tarW.Write(manifestData); err != nil {
+		return "", err
+	}
 
 	err = filepath.Walk(input, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		header, _ := tar.FileInfoHeader(info, "")
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, _ := tar.FileInfoHeader(info, link)
 		header.Name, _ = filepath.Rel(input, path)
 		//skip "."
 		if header.Name == "." {
@@ -214,7 +392,7 @@ func PackageCollectedData(pOpt *PackageOptions, skipConfirm bool) (string, error
 			return err
 		}
 
-		if !info.IsDir() {
+		if info.Mode().IsRegular() {
 			fd, err := os.Open(path)
 			if err != nil {
 				return err
@@ -225,8 +403,32 @@ func PackageCollectedData(pOpt *PackageOptions, skipConfirm bool) (string, error
 		}
 		return nil
 	})
+	if err != nil {
+		return output, err
+	}
 
-	return output, err
+	// writers must be closed in order, the final chunk is written when
+	// closing the encrypt writer
+	if err := tarW.Close(); err != nil {
+		return output, err
+	}
+	if err := compressW.Close(); err != nil {
+		return output, err
+	}
+	if err := encryptW.Close(); err != nil {
+		return output, err
+	}
+	if err := fileW.Close(); err != nil {
+		return output, err
+	}
+
+	// save the summary along with the package, so that it could be listed
+	// without the private key
+	summaryData, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return output, err
+	}
+	return output, os.WriteFile(output+ManifestSuffix, summaryData, 0644)
 }
 
 func selectInputDir(dir string, skipConfirm bool) (string, error) {
@@ -43,3 +43,27 @@ func TestGenerateAndParserD1agHeader(t *testing.T) {
 	assert.Nil(err)
 	assert.EqualValues(meta, meta2)
 }
+
+func TestD1agHeaderVersion(t *testing.T) {
+	assert := require.New(t)
+
+	meta := map[string]interface{}{"cluster_id": "1"}
+	file, err := GenerateD1agHeaderWithVersion(meta, VersionAEAD, TypeZST, nil)
+	assert.Nil(err)
+	assert.EqualValues(VersionAEAD|TypeRaw|TypeZST, file[4])
+
+	h, err := ReadD1agHeader(bytes.NewBuffer(file))
+	assert.Nil(err)
+	assert.EqualValues(VersionAEAD, h.Version)
+	assert.EqualValues("unknown", h.Format)
+	assert.EqualValues("zstd", h.Compress)
+	assert.EqualValues(len(file), h.Offset)
+
+	// reserved version
+	file[4] |= 0300
+	_, err = ReadD1agHeader(bytes.NewBuffer(file))
+	assert.NotNil(err)
+
+	_, err = GenerateD1agHeaderWithVersion(meta, 0200, TypeZST, nil)
+	assert.NotNil(err)
+}
@@ -0,0 +1,357 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	"github.com/klauspost/compress/zstd"
+	tiuputils "github.com/pingcap/tiup/pkg/utils"
+)
+
+type UnpackOptions struct {
+	InputFile  string // the .diag package
+	OutputDir  string // directory to extract data to
+	PrivateKey string // pem file of the private key to decrypt the package
+}
+
+// PackageFile is a file in the package
+type PackageFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// PackageInfo is the content of a package read by VerifyPackage
+type PackageInfo struct {
+	Version  byte                   `json:"version"`
+	Format   string                 `json:"format"`
+	Compress string                 `json:"compress"`
+	Meta     map[string]interface{} `json:"meta"`
+	Files    []PackageFile          `json:"files"`
+	DataSize int64                  `json:"data_size"`
+}
+
+// LoadPrivateKey reads a RSA private key in PKCS #1 or PKCS #8 PEM format
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key in %s: %s", path, err)
+	}
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a RSA private key", path)
+	}
+	return priv, nil
+}
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// packageReader reads the meta and the archived files of a package
+type packageReader struct {
+	header *D1agHeader
+	meta   map[string]interface{}
+	tar    *tar.Reader
+	data   io.Reader // the decompressed payload
+	raw    io.Reader // the decrypted payload
+	closer func()
+}
+
+func openPackage(opt *UnpackOptions) (*packageReader, error) {
+	var priv *rsa.PrivateKey
+	if opt.PrivateKey != "" {
+		var err error
+		if priv, err = LoadPrivateKey(opt.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(opt.InputFile)
+	if err != nil {
+		return nil, err
+	}
+	r := &packageReader{closer: func() { f.Close() }}
+
+	r.header, err = ReadD1agHeader(f)
+	if err != nil {
+		r.closer()
+		return nil, err
+	}
+
+	var metaR, payload io.Reader = bytes.NewReader(r.header.Meta), f
+	if r.header.Format == "diag" {
+		if priv == nil {
+			r.closer()
+			return nil, fmt.Errorf("%s is encrypted, the private key must be specified", opt.InputFile)
+		}
+		if metaR, err = NewD1agDecryptor(r.header.Version, priv, metaR, r.header.MetaAD()); err != nil {
+			r.closer()
+			return nil, fmt.Errorf("failed to decrypt meta: %s", err)
+		}
+		if payload, err = NewD1agDecryptor(r.header.Version, priv, payload, r.header.PayloadAD()); err != nil {
+			r.closer()
+			return nil, fmt.Errorf("failed to decrypt data: %s", err)
+		}
+	}
+
+	metaData, err := io.ReadAll(metaR)
+	if err != nil {
+		r.closer()
+		return nil, fmt.Errorf("failed to read meta: %s", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(metaData))
+	d.UseNumber()
+	if err := d.Decode(&r.meta); err != nil {
+		r.closer()
+		return nil, fmt.Errorf("meta is not a valid JSON: %s", err)
+	}
+
+	// packages created by older versions are always compressed with zstd but
+	// the compression bits in their headers are not set
+	br := bufio.NewReader(payload)
+	payload = br
+	r.raw = br
+	compress := r.header.Compress
+	if magic, err := br.Peek(4); compress == "none" && err == nil && bytes.Equal(magic, zstdMagic) {
+		compress = "zstd"
+	}
+
+	switch compress {
+	case "zstd":
+		zr, err := zstd.NewReader(payload)
+		if err != nil {
+			r.closer()
+			return nil, err
+		}
+		r.closer = func() { zr.Close(); f.Close() }
+		payload = zr
+	case "gzip":
+		gr, err := gzip.NewReader(payload)
+		if err != nil {
+			r.closer()
+			return nil, err
+		}
+		r.closer = func() { gr.Close(); f.Close() }
+		payload = gr
+	}
+	r.data = payload
+	r.tar = tar.NewReader(payload)
+	return r, nil
+}
+
+// finish reads the payload to the end after the archive is walked, so data
+// following the tar trailer is decrypted and authenticated as well
+func (r *packageReader) finish() error {
+	if _, err := io.Copy(io.Discard, r.data); err != nil {
+		return fmt.Errorf("failed to read archived data: %s", err)
+	}
+	if _, err := io.Copy(io.Discard, r.raw); err != nil {
+		return fmt.Errorf("failed to read archived data: %s", err)
+	}
+	return nil
+}
+
+// walk calls fn for each entry in the archive, the content of regular files
+// must be read by fn from the tar reader
+func (r *packageReader) walk(fn func(hdr *tar.Header) error) error {
+	for {
+		hdr, err := r.tar.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archived data: %s", err)
+		}
+		if err := fn(hdr); err != nil {
+			return err
+		}
+	}
+}
+
+// VerifyPackage reads the whole package without extracting it, and checks
+// the header, the meta and the archived data
+func VerifyPackage(opt *UnpackOptions) (*PackageInfo, error) {
+	r, err := openPackage(opt)
+	if err != nil {
+		return nil, err
+	}
+	defer r.closer()
+
+	info := &PackageInfo{
+		Version:  r.header.Version,
+		Format:   r.header.Format,
+		Compress: r.header.Compress,
+		Meta:     r.meta,
+		Files:    make([]PackageFile, 0),
+	}
+
+	for _, key := range []string{"cluster_id", "cluster_type", "dir_size"} {
+		if _, ok := r.meta[key]; !ok {
+			return info, fmt.Errorf("%s is missing in meta", key)
+		}
+	}
+
+	var manifest map[string]ManifestFile
+	first := true
+	err = r.walk(func(hdr *tar.Header) error {
+		if first && hdr.Name == ManifestFileName {
+			first = false
+			m := &Manifest{}
+			if err := json.NewDecoder(r.tar).Decode(m); err != nil {
+				return fmt.Errorf("invalid manifest: %s", err)
+			}
+			manifest = make(map[string]ManifestFile)
+			for _, f := range m.Files {
+				manifest[f.Path] = f
+			}
+			return nil
+		}
+		first = false
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, r.tar)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %s", hdr.Name, err)
+		}
+		info.Files = append(info.Files, PackageFile{
+			Name:   hdr.Name,
+			Size:   n,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+		info.DataSize += n
+
+		// packages created by older versions have no manifest
+		if manifest == nil {
+			return nil
+		}
+		f := info.Files[len(info.Files)-1]
+		expected, ok := manifest[filepath.ToSlash(f.Name)]
+		if !ok {
+			return fmt.Errorf("%s is not in the manifest", f.Name)
+		}
+		if expected.Size != f.Size || expected.SHA256 != f.SHA256 {
+			return fmt.Errorf("checksum of %s does not match the manifest", f.Name)
+		}
+		delete(manifest, expected.Path)
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+	if err := r.finish(); err != nil {
+		return info, err
+	}
+	for name := range manifest {
+		return info, fmt.Errorf("%s in the manifest is missing in package", name)
+	}
+
+	dirSize, err := strconv.ParseInt(fmt.Sprint(r.meta["dir_size"]), 10, 64)
+	if err != nil {
+		return info, fmt.Errorf("invalid dir_size in meta: %v", r.meta["dir_size"])
+	}
+	if dirSize != info.DataSize {
+		return info, fmt.Errorf("size of archived data %d does not match dir_size %d in meta", info.DataSize, dirSize)
+	}
+	return info, nil
+}
+
+// UnpackPackage decrypts and extracts the package to the output dir
+func UnpackPackage(opt *UnpackOptions) (string, error) {
+	output := opt.OutputDir
+	if output == "" {
+		output = strings.TrimSuffix(filepath.Base(opt.InputFile), ".diag")
+	}
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return "", err
+	}
+	if !tiuputils.IsNotExist(output) {
+		if empty, err := tiuputils.IsEmptyDir(output); err != nil || !empty {
+			return "", fmt.Errorf("%s already exists and is not empty", output)
+		}
+	}
+
+	r, err := openPackage(opt)
+	if err != nil {
+		return "", err
+	}
+	defer r.closer()
+
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return "", err
+	}
+	err = r.walk(func(hdr *tar.Header) error {
+		// the manifest is not a part of collected data
+		if hdr.Name == ManifestFileName {
+			return nil
+		}
+		fp := filepath.Join(output, hdr.Name)
+		if fp != output && !strings.HasPrefix(fp, output+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file path %s in package", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(fp, 0755)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+				return err
+			}
+			fd, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+			if _, err := io.Copy(fd, r.tar); err != nil {
+				return fmt.Errorf("failed to extract %s: %s", hdr.Name, err)
+			}
+			return nil
+		default:
+			// links and special files are never created by diag
+			return nil
+		}
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, r.finish()
+}
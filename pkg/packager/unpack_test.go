@@ -0,0 +1,170 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func genTestKeyPair(t *testing.T, dir string) (cert string, keyFile string) {
+	assert := require.New(t)
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "diag-test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	assert.Nil(err)
+
+	keyFile = filepath.Join(dir, "key.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	assert.Nil(os.WriteFile(keyFile, keyPEM, 0600))
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), keyFile
+}
+
+func genTestPackage(t *testing.T, aead bool) (pkg string, keyFile string) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	cert, keyFile := genTestKeyPair(t, dir)
+
+	input := filepath.Join(dir, "diag-test")
+	assert.Nil(os.MkdirAll(filepath.Join(input, "host1", "log"), 0755))
+	assert.Nil(os.WriteFile(filepath.Join(input, "cluster.json"),
+		[]byte(`{"cluster_id":"123","cluster_type":"tidb-cluster","cluster_name":"test"}`), 0644))
+	assert.Nil(os.WriteFile(filepath.Join(input, "host1", "log", "tidb.log"), make([]byte, 200*1024), 0644))
+	// symlinks have no content in the archive
+	assert.Nil(os.Symlink("tidb.log", filepath.Join(input, "host1", "log", "tidb-current.log")))
+
+	pkg, err := PackageCollectedData(&PackageOptions{
+		InputDir:   input,
+		OutputFile: filepath.Join(dir, "test.diag"),
+		Cert:       cert,
+		AEADFormat: aead,
+	}, true)
+	assert.Nil(err)
+	return pkg, keyFile
+}
+
+func TestVerifyAndUnpack(t *testing.T) {
+	assert := require.New(t)
+
+	pkg, keyFile := genTestPackage(t, true)
+
+	info, err := VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.Nil(err)
+	assert.EqualValues(VersionAEAD, info.Version)
+	assert.Equal("diag", info.Format)
+	assert.Equal("zstd", info.Compress)
+	assert.Equal("test", info.Meta["cluster_name"])
+	assert.Len(info.Files, 2)
+	assert.Contains(info.Meta, "manifest")
+
+	summary, err := LoadManifestSummary(pkg)
+	assert.Nil(err)
+	assert.Equal(2, summary.Files)
+	assert.Equal([]string{"host1"}, summary.Hosts)
+
+	// the private key is required for encrypted packages
+	_, err = VerifyPackage(&UnpackOptions{InputFile: pkg})
+	assert.NotNil(err)
+
+	output := filepath.Join(t.TempDir(), "out")
+	output, err = UnpackPackage(&UnpackOptions{InputFile: pkg, OutputDir: output, PrivateKey: keyFile})
+	assert.Nil(err)
+	data, err := os.ReadFile(filepath.Join(output, "host1", "log", "tidb.log"))
+	assert.Nil(err)
+	assert.Len(data, 200*1024)
+	_, err = os.Stat(filepath.Join(output, ManifestFileName))
+	assert.True(os.IsNotExist(err))
+
+	// not empty
+	_, err = UnpackPackage(&UnpackOptions{InputFile: pkg, OutputDir: output, PrivateKey: keyFile})
+	assert.NotNil(err)
+}
+
+func TestVerifyBrokenPackage(t *testing.T) {
+	assert := require.New(t)
+
+	pkg, keyFile := genTestPackage(t, true)
+	data, err := os.ReadFile(pkg)
+	assert.Nil(err)
+
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-1] ^= 0xFF
+	assert.Nil(os.WriteFile(pkg, tampered, 0644))
+	_, err = VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.ErrorContains(err, "tampered")
+
+	assert.Nil(os.WriteFile(pkg, data[:len(data)-100], 0644))
+	_, err = VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.ErrorContains(err, "truncated")
+
+	// the tar trailer is still intact, but the final chunk is missing
+	assert.Nil(os.WriteFile(pkg, data[:len(data)-16], 0644))
+	_, err = VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.ErrorContains(err, "truncated")
+	_, err = UnpackPackage(&UnpackOptions{InputFile: pkg, OutputDir: filepath.Join(t.TempDir(), "out"), PrivateKey: keyFile})
+	assert.ErrorContains(err, "truncated")
+
+	assert.Nil(os.WriteFile(pkg, append(append([]byte{}, data...), "garbage"...), 0644))
+	_, err = VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.ErrorContains(err, "tampered")
+}
+
+func TestVerifyTamperedHeader(t *testing.T) {
+	assert := require.New(t)
+
+	pkg, keyFile := genTestPackage(t, true)
+	data, err := os.ReadFile(pkg)
+	assert.Nil(err)
+
+	// zstd to gzip, the header is still valid but not the one encrypted with
+	tampered := append([]byte{}, data...)
+	tampered[4] = tampered[4]&^007 | TypeGZ
+	assert.Nil(os.WriteFile(pkg, tampered, 0644))
+	_, err = VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.ErrorContains(err, "tampered")
+}
+
+func TestUnpackLegacyPackage(t *testing.T) {
+	assert := require.New(t)
+
+	pkg, keyFile := genTestPackage(t, false)
+	info, err := VerifyPackage(&UnpackOptions{InputFile: pkg, PrivateKey: keyFile})
+	assert.Nil(err)
+	assert.EqualValues(VersionLegacy, info.Version)
+	assert.Equal("test", info.Meta["cluster_name"])
+
+	output, err := UnpackPackage(&UnpackOptions{InputFile: pkg, OutputDir: filepath.Join(t.TempDir(), "out"), PrivateKey: keyFile})
+	assert.Nil(err)
+	data, err := os.ReadFile(filepath.Join(output, "host1", "log", "tidb.log"))
+	assert.Nil(err)
+	assert.Len(data, 200*1024)
+}
@@ -21,6 +21,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -46,6 +47,10 @@ type UploadOptions struct {
 	Concurrency int
 	Rebuild     bool
 	Cert        string
+	// AEADFormat packages in VersionAEAD if the data is not packaged
+	AEADFormat bool
+	// Force uploads the package even if identical data was uploaded before
+	Force bool
 	ClientOptions
 }
 
@@ -72,6 +77,7 @@ func Upload(ctx context.Context, opt *UploadOptions, skipConfirm bool) (string,
 				OutputFile: opt.FilePath,
 				Cert:       opt.Cert,
 				Rebuild:    opt.Rebuild,
+				AEADFormat: opt.AEADFormat,
 			}, skipConfirm)
 			if err != nil {
 				return "", err
@@ -102,6 +108,31 @@ func Upload(ctx context.Context, opt *UploadOptions, skipConfirm bool) (string,
 	if err != nil {
 		return "", err
 	}
+	summary, err := LoadManifestSummary(opt.FilePath)
+	if err == nil {
+		logger.Infof("the package contains %d files of %d bytes from %d hosts", summary.Files, summary.Size, len(summary.Hosts))
+		for _, name := range summary.CollectorNames() {
+			stat := summary.Collectors[name]
+			logger.Infof("  %s: %d files, %d bytes", name, stat.Files, stat.Size)
+		}
+		if len(summary.Missing) > 0 {
+			logger.Warnf("no data collected by: %s", strings.Join(summary.Missing, ", "))
+		}
+	} else {
+		logger.Debugf("no manifest summary of the package: %s", err)
+	}
+
+	// skip the package if the same data was uploaded to the endpoint before
+	var idx *UploadIndex
+	if summary != nil {
+		if idx, err = LoadUploadIndex(); err != nil {
+			logger.Warnf("failed to load the upload index: %s", err)
+		} else if url, ok := idx.Lookup(opt.Endpoint, summary.SHA256); ok && !opt.Force {
+			logger.Infof("identical data was already uploaded, use --force to upload it again")
+			logger.Infof("Download URL: %s\n", url)
+			return url, nil
+		}
+	}
 
 	presp, err := preCreate(uuid, fileStat.Size()-int64(offset), fileStat.Name(), meta, encryption, compress, opt)
 	if err != nil {
@@ -114,7 +145,14 @@ func Upload(ctx context.Context, opt *UploadOptions, skipConfirm bool) (string,
 		presp,
 		fileStat.Size(),
 		func() (string, error) {
-			return UploadComplete(logger, uuid, opt)
+			url, err := UploadComplete(logger, uuid, opt)
+			if err == nil && idx != nil {
+				idx.Add(opt.Endpoint, summary.SHA256, url)
+				if err := idx.Store(); err != nil {
+					logger.Warnf("failed to save the upload index: %s", err)
+				}
+			}
+			return url, err
 		},
 		func() (io.ReadSeekCloser, error) {
 			rec, err := os.Open(opt.FilePath)
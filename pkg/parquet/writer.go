@@ -0,0 +1,363 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parquet writes flat tables of string and timestamp columns as
+// Parquet files, the format is described in
+// https://github.com/apache/parquet-format
+//
+// Only what is needed to export diagnostic data is implemented: all columns
+// are required, values are PLAIN encoded and pages are not compressed.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const magic = "PAR1"
+
+// DefaultRowGroupSize is the default number of rows in a row group
+const DefaultRowGroupSize = 64 * 1024
+
+// ColumnType is the type of values of a column
+type ColumnType int
+
+// types of columns
+const (
+	ColumnString          ColumnType = iota // UTF-8 strings
+	ColumnTimestampMillis                   // int64 milliseconds since the unix epoch
+)
+
+// Column defines a column of the table
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// physical types, repetition types, encodings and converted types of the
+// parquet format
+const (
+	typeInt64        = 2
+	typeByteArray    = 6
+	repRequired      = 0
+	encodingPlain    = 0
+	encodingRLE      = 3
+	convertedUTF8    = 0
+	convertedTSMilli = 9
+	codecNone        = 0
+	pageData         = 0
+)
+
+type columnChunk struct {
+	offset           int64
+	size             int64
+	numValues        int64
+	uncompressedSize int64
+}
+
+// Writer writes rows to a parquet file, rows are buffered and written as
+// a row group when the buffer is full
+type Writer struct {
+	out          io.Writer
+	offset       int64
+	columns      []Column
+	rowGroupSize int
+
+	values    []bytes.Buffer // plain encoded values of the current row group
+	rows      int64
+	numRows   int64
+	rowGroups [][]columnChunk
+	groupRows []int64
+}
+
+// NewWriter creates a writer of the columns, rowGroupSize <= 0 means
+// DefaultRowGroupSize
+func NewWriter(out io.Writer, columns []Column, rowGroupSize int) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns defined")
+	}
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	w := &Writer{
+		out:          out,
+		columns:      columns,
+		rowGroupSize: rowGroupSize,
+		values:       make([]bytes.Buffer, len(columns)),
+	}
+	if err := w.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) write(data []byte) error {
+	n, err := w.out.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+// Write appends a row, values must be string for string columns and int64
+// for timestamp columns
+func (w *Writer) Write(row []interface{}) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("expect %d values in a row, got %d", len(w.columns), len(row))
+	}
+	// check all values first, so no partial row is written
+	for i, col := range w.columns {
+		ok := false
+		switch col.Type {
+		case ColumnString:
+			_, ok = row[i].(string)
+		case ColumnTimestampMillis:
+			_, ok = row[i].(int64)
+		}
+		if !ok {
+			return fmt.Errorf("invalid value of column %s: %v", col.Name, row[i])
+		}
+	}
+	for i, col := range w.columns {
+		buf := &w.values[i]
+		switch col.Type {
+		case ColumnString:
+			v := row[i].(string)
+			var l [4]byte
+			binary.LittleEndian.PutUint32(l[:], uint32(len(v)))
+			buf.Write(l[:])
+			buf.WriteString(v)
+		case ColumnTimestampMillis:
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], uint64(row[i].(int64)))
+			buf.Write(b[:])
+		}
+	}
+	w.rows++
+	if w.rows >= int64(w.rowGroupSize) {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group, with a data page per column
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	chunks := make([]columnChunk, 0, len(w.columns))
+	for i := range w.columns {
+		data := w.values[i].Bytes()
+		var header thriftWriter
+		header.fieldI32(1, pageData)
+		header.fieldI32(2, int32(len(data)))
+		header.fieldI32(3, int32(len(data)))
+		header.fieldStructBegin(5)
+		header.fieldI32(1, int32(w.rows))
+		header.fieldI32(2, encodingPlain)
+		header.fieldI32(3, encodingRLE)
+		header.fieldI32(4, encodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		chunk := columnChunk{
+			offset:           w.offset,
+			numValues:        w.rows,
+			uncompressedSize: int64(header.buf.Len() + len(data)),
+		}
+		chunk.size = chunk.uncompressedSize
+		if err := w.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := w.write(data); err != nil {
+			return err
+		}
+		w.values[i].Reset()
+		chunks = append(chunks, chunk)
+	}
+	w.rowGroups = append(w.rowGroups, chunks)
+	w.groupRows = append(w.groupRows, w.rows)
+	w.numRows += w.rows
+	w.rows = 0
+	return nil
+}
+
+// Close writes the remaining rows and the footer, the underlying writer is
+// not closed
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	var meta thriftWriter
+	meta.fieldI32(1, 1) // version
+	meta.fieldListBegin(2, thriftStruct, len(w.columns)+1)
+	// the root of the schema
+	meta.structBegin()
+	meta.fieldBinary(4, []byte("schema"))
+	meta.fieldI32(5, int32(len(w.columns)))
+	meta.structEnd()
+	for _, col := range w.columns {
+		physical, converted := columnTypes(col.Type)
+		meta.structBegin()
+		meta.fieldI32(1, physical)
+		meta.fieldI32(3, repRequired)
+		meta.fieldBinary(4, []byte(col.Name))
+		meta.fieldI32(6, converted)
+		meta.structEnd()
+	}
+	meta.fieldI64(3, w.numRows)
+	meta.fieldListBegin(4, thriftStruct, len(w.rowGroups))
+	for g, chunks := range w.rowGroups {
+		var total int64
+		meta.structBegin()
+		meta.fieldListBegin(1, thriftStruct, len(chunks))
+		for i, chunk := range chunks {
+			physical, _ := columnTypes(w.columns[i].Type)
+			meta.structBegin()
+			meta.fieldI64(2, chunk.offset)
+			meta.fieldStructBegin(3)
+			meta.fieldI32(1, physical)
+			meta.fieldListBegin(2, thriftI32, 2)
+			meta.listI32(encodingPlain)
+			meta.listI32(encodingRLE)
+			meta.fieldListBegin(3, thriftBinary, 1)
+			meta.listBinary([]byte(w.columns[i].Name))
+			meta.fieldI32(4, codecNone)
+			meta.fieldI64(5, chunk.numValues)
+			meta.fieldI64(6, chunk.uncompressedSize)
+			meta.fieldI64(7, chunk.size)
+			meta.fieldI64(9, chunk.offset)
+			meta.structEnd()
+			meta.structEnd()
+			total += chunk.uncompressedSize
+		}
+		meta.fieldI64(2, total)
+		meta.fieldI64(3, w.groupRows[g])
+		meta.structEnd()
+	}
+	meta.fieldBinary(6, []byte("diag"))
+	meta.structEnd()
+
+	if err := w.write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	var l [4]byte
+	binary.LittleEndian.PutUint32(l[:], uint32(meta.buf.Len()))
+	if err := w.write(l[:]); err != nil {
+		return err
+	}
+	return w.write([]byte(magic))
+}
+
+func columnTypes(t ColumnType) (physical, converted int32) {
+	if t == ColumnTimestampMillis {
+		return typeInt64, convertedTSMilli
+	}
+	return typeByteArray, convertedUTF8
+}
+
+// element types of the thrift compact protocol
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the thrift compact protocol, a struct
+// is ended with structEnd, and the top level struct needs to be ended too
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // the last field id of each nested struct
+	cur  int16
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.cur; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.cur = id
+}
+
+func (t *thriftWriter) fieldI32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) fieldI64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) fieldBinary(id int16, v []byte) {
+	t.fieldHeader(id, thriftBinary)
+	t.listBinary(v)
+}
+
+// fieldStructBegin starts a nested struct field
+func (t *thriftWriter) fieldStructBegin(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.last = append(t.last, t.cur)
+	t.cur = 0
+}
+
+// fieldListBegin starts a list field, elements of struct type are started
+// with structBegin and ended with structEnd
+func (t *thriftWriter) fieldListBegin(id int16, elem byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(size))
+	}
+}
+
+// structBegin starts a struct element of a list
+func (t *thriftWriter) structBegin() {
+	t.last = append(t.last, t.cur)
+	t.cur = 0
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) listBinary(v []byte) {
+	t.uvarint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+// structEnd writes the stop field of the current struct
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0)
+	if n := len(t.last); n > 0 {
+		t.cur = t.last[n-1]
+		t.last = t.last[:n-1]
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// thriftReader decodes the thrift compact protocol to maps of field ids
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		l := int(r.uvarint())
+		v := string(r.data[r.pos : r.pos+l])
+		r.pos += l
+		return v
+	case thriftList:
+		h := r.data[r.pos]
+		r.pos++
+		size, elem := int(h>>4), h&0x0f
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic("unexpected type")
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		h := r.data[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	assert := require.New(t)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{
+		{Name: "time", Type: ColumnTimestampMillis},
+		{Name: "message", Type: ColumnString},
+	}, 2)
+	assert.Nil(err)
+	assert.NotNil(w.Write([]interface{}{int64(1), 2}))
+	for i, msg := range []string{"first", "second", "third"} {
+		assert.Nil(w.Write([]interface{}{int64(1000 + i), msg}))
+	}
+	assert.Nil(w.Close())
+
+	data := buf.Bytes()
+	assert.Equal(magic, string(data[:4]))
+	assert.Equal(magic, string(data[len(data)-4:]))
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := (&thriftReader{data: data[len(data)-8-metaLen : len(data)-8]}).structure()
+
+	assert.EqualValues(3, meta[3])
+	schema := meta[2].([]interface{})
+	assert.Len(schema, 3)
+	assert.EqualValues(2, schema[0].(map[int16]interface{})[5])
+	assert.Equal("message", schema[2].(map[int16]interface{})[4])
+	assert.EqualValues(typeByteArray, schema[2].(map[int16]interface{})[1])
+
+	// two row groups of 2 and 1 rows
+	groups := meta[4].([]interface{})
+	assert.Len(groups, 2)
+	assert.EqualValues(1, groups[1].(map[int16]interface{})[3])
+
+	// the page of the message column in the first row group
+	chunk := groups[0].(map[int16]interface{})[1].([]interface{})[1].(map[int16]interface{})
+	colMeta := chunk[3].(map[int16]interface{})
+	assert.Equal([]interface{}{"message"}, colMeta[3])
+	assert.EqualValues(2, colMeta[5])
+	offset := int(colMeta[9].(int64))
+	pr := &thriftReader{data: data, pos: offset}
+	page := pr.structure()
+	assert.EqualValues(2, page[5].(map[int16]interface{})[1])
+	size := int(page[2].(int64))
+	assert.EqualValues(colMeta[7], int64(pr.pos-offset+size))
+	values := data[pr.pos : pr.pos+size]
+	assert.Equal("\x05\x00\x00\x00first\x06\x00\x00\x00second", string(values))
+}
@@ -0,0 +1,376 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// LookbackDelta is the max time to look back for the latest sample of an
+// instant vector selector, the same as the default of Prometheus
+const LookbackDelta = 5 * time.Minute
+
+var (
+	posInf = math.Inf(1)
+	nan    = math.NaN()
+)
+
+// Storage is an in-memory series store
+type Storage struct {
+	series  map[string][]*model.SampleStream // by metric names
+	minTime model.Time
+	maxTime model.Time
+}
+
+// NewStorage creates an empty storage
+func NewStorage() *Storage {
+	return &Storage{
+		series:  make(map[string][]*model.SampleStream),
+		minTime: math.MaxInt64,
+		maxTime: math.MinInt64,
+	}
+}
+
+// Add adds series to the storage, samples of series must be sorted by time
+func (s *Storage) Add(series ...*model.SampleStream) {
+	for _, ss := range series {
+		if len(ss.Values) == 0 {
+			continue
+		}
+		name := string(ss.Metric[model.MetricNameLabel])
+		s.series[name] = append(s.series[name], ss)
+		if t := ss.Values[0].Timestamp; t < s.minTime {
+			s.minTime = t
+		}
+		if t := ss.Values[len(ss.Values)-1].Timestamp; t > s.maxTime {
+			s.maxTime = t
+		}
+	}
+}
+
+// TimeRange returns the time of the first and the last sample in the storage
+func (s *Storage) TimeRange() (time.Time, time.Time, error) {
+	if s.minTime > s.maxTime {
+		return time.Time{}, time.Time{}, fmt.Errorf("no samples in the storage")
+	}
+	return s.minTime.Time(), s.maxTime.Time(), nil
+}
+
+func (s *Storage) selectSeries(sel *VectorSelector) []*model.SampleStream {
+	candidates := s.series[sel.Name]
+	if sel.Name == "" {
+		candidates = make([]*model.SampleStream, 0)
+		for _, ss := range s.series {
+			candidates = append(candidates, ss...)
+		}
+	}
+	result := make([]*model.SampleStream, 0)
+	for _, ss := range candidates {
+		matched := true
+		for _, m := range sel.Matchers {
+			if !m.Matches(string(ss.Metric[model.LabelName(m.Name)])) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, ss)
+		}
+	}
+	return result
+}
+
+// Query evaluates the expression at each step between start and end, an
+// instant query could be made by setting start and end to the same time
+func (s *Storage) Query(query string, start, end time.Time, step time.Duration) (model.Matrix, error) {
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end time must not be before start time")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+
+	ev := &evaluator{storage: s, selected: make(map[*VectorSelector][]*model.SampleStream)}
+	result := make(map[string]*model.SampleStream)
+	for t := start; !t.After(end); t = t.Add(step) {
+		ts := model.TimeFromUnixNano(t.UnixNano())
+		v, err := ev.eval(expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		var vec vector
+		switch val := v.(type) {
+		case float64:
+			vec = vector{{metric: model.Metric{}, v: val}}
+		case vector:
+			vec = val
+		}
+		for _, smpl := range vec {
+			key := smpl.metric.String()
+			ss, ok := result[key]
+			if !ok {
+				ss = &model.SampleStream{Metric: smpl.metric}
+				result[key] = ss
+			}
+			ss.Values = append(ss.Values, model.SamplePair{Timestamp: ts, Value: model.SampleValue(smpl.v)})
+		}
+	}
+
+	matrix := make(model.Matrix, 0, len(result))
+	for _, ss := range result {
+		matrix = append(matrix, ss)
+	}
+	sort.Sort(matrix)
+	return matrix, nil
+}
+
+type sample struct {
+	metric model.Metric
+	v      float64
+}
+
+type vector []sample
+
+type evaluator struct {
+	storage  *Storage
+	selected map[*VectorSelector][]*model.SampleStream // cache of selected series
+}
+
+func (ev *evaluator) selectSeries(sel *VectorSelector) []*model.SampleStream {
+	if series, ok := ev.selected[sel]; ok {
+		return series
+	}
+	series := ev.storage.selectSeries(sel)
+	ev.selected[sel] = series
+	return series
+}
+
+// eval returns a float64 for scalars or a vector
+func (ev *evaluator) eval(e Expr, ts model.Time) (any, error) {
+	switch n := e.(type) {
+	case *NumberLiteral:
+		return n.Val, nil
+	case *VectorSelector:
+		vec := make(vector, 0)
+		for _, ss := range ev.selectSeries(n) {
+			// the latest sample in the lookback window
+			i := sort.Search(len(ss.Values), func(i int) bool { return ss.Values[i].Timestamp > ts }) - 1
+			if i < 0 || ss.Values[i].Timestamp <= ts.Add(-LookbackDelta) {
+				continue
+			}
+			vec = append(vec, sample{metric: ss.Metric, v: float64(ss.Values[i].Value)})
+		}
+		return vec, nil
+	case *Call:
+		return ev.evalCall(n, ts)
+	case *AggregateExpr:
+		v, err := ev.eval(n.Expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		vec, ok := v.(vector)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects a vector but got a scalar", n.Op)
+		}
+		return aggregate(n, vec), nil
+	case *BinaryExpr:
+		lhs, err := ev.eval(n.LHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := ev.eval(n.RHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOp(n.Op, lhs, rhs), nil
+	}
+	return nil, fmt.Errorf("unsupported expression '%s'", e)
+}
+
+// rangeSamples returns samples of selected series in (ts-range, ts]
+func (ev *evaluator) rangeSamples(sel *VectorSelector, ts model.Time) []*model.SampleStream {
+	result := make([]*model.SampleStream, 0)
+	start := ts.Add(-sel.Range)
+	for _, ss := range ev.selectSeries(sel) {
+		i := sort.Search(len(ss.Values), func(i int) bool { return ss.Values[i].Timestamp > start })
+		j := sort.Search(len(ss.Values), func(i int) bool { return ss.Values[i].Timestamp > ts })
+		if i < j {
+			result = append(result, &model.SampleStream{Metric: ss.Metric, Values: ss.Values[i:j]})
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) evalCall(n *Call, ts model.Time) (any, error) {
+	fn := functions[n.Func]
+	args := make([]any, len(n.Args))
+	for i, a := range n.Args {
+		if fn.args[i] == valueMatrix {
+			sel := a.(*VectorSelector)
+			args[i] = rangeArg{sel: sel, series: ev.rangeSamples(sel, ts), ts: ts}
+			continue
+		}
+		v, err := ev.eval(a, ts)
+		if err != nil {
+			return nil, err
+		}
+		if fn.args[i] == valueVector {
+			if _, ok := v.(vector); !ok {
+				return nil, fmt.Errorf("function %s() expects a vector but got a scalar", n.Func)
+			}
+		}
+		args[i] = v
+	}
+	return fn.call(args), nil
+}
+
+// dropMetricName returns a copy of the metric without its name
+func dropMetricName(m model.Metric) model.Metric {
+	if _, ok := m[model.MetricNameLabel]; !ok {
+		return m
+	}
+	m = m.Clone()
+	delete(m, model.MetricNameLabel)
+	return m
+}
+
+func groupingKey(m model.Metric, grouping []string, without bool) model.Metric {
+	key := model.Metric{}
+	if without {
+		for k, v := range m {
+			key[k] = v
+		}
+		delete(key, model.MetricNameLabel)
+		for _, l := range grouping {
+			delete(key, model.LabelName(l))
+		}
+		return key
+	}
+	for _, l := range grouping {
+		if v, ok := m[model.LabelName(l)]; ok {
+			key[model.LabelName(l)] = v
+		}
+	}
+	return key
+}
+
+func aggregate(n *AggregateExpr, vec vector) vector {
+	type group struct {
+		metric model.Metric
+		v      float64
+		count  int
+	}
+	groups := make(map[model.Fingerprint]*group)
+	order := make([]model.Fingerprint, 0)
+	for _, s := range vec {
+		m := groupingKey(s.metric, n.Grouping, n.Without)
+		fp := m.Fingerprint()
+		g, ok := groups[fp]
+		if !ok {
+			groups[fp] = &group{metric: m, v: s.v, count: 1}
+			order = append(order, fp)
+			continue
+		}
+		g.count++
+		switch n.Op {
+		case "sum", "avg":
+			g.v += s.v
+		case "min":
+			if s.v < g.v || math.IsNaN(g.v) {
+				g.v = s.v
+			}
+		case "max":
+			if s.v > g.v || math.IsNaN(g.v) {
+				g.v = s.v
+			}
+		}
+	}
+
+	result := make(vector, 0, len(groups))
+	for _, fp := range order {
+		g := groups[fp]
+		switch n.Op {
+		case "avg":
+			g.v /= float64(g.count)
+		case "count":
+			g.v = float64(g.count)
+		}
+		result = append(result, sample{metric: g.metric, v: g.v})
+	}
+	return result
+}
+
+func arith(op string, l, r float64) float64 {
+	switch op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		return l / r
+	}
+	return nan
+}
+
+// binaryOp applies the operator on scalars and vectors, samples of two
+// vectors are matched one-to-one by all labels except the metric name
+func binaryOp(op string, lhs, rhs any) any {
+	switch l := lhs.(type) {
+	case float64:
+		switch r := rhs.(type) {
+		case float64:
+			return arith(op, l, r)
+		case vector:
+			result := make(vector, 0, len(r))
+			for _, s := range r {
+				result = append(result, sample{metric: dropMetricName(s.metric), v: arith(op, l, s.v)})
+			}
+			return result
+		}
+	case vector:
+		if r, ok := rhs.(float64); ok {
+			result := make(vector, 0, len(l))
+			for _, s := range l {
+				result = append(result, sample{metric: dropMetricName(s.metric), v: arith(op, s.v, r)})
+			}
+			return result
+		}
+		r := rhs.(vector)
+		rs := make(map[model.Fingerprint]sample, len(r))
+		for _, s := range r {
+			m := dropMetricName(s.metric)
+			rs[m.Fingerprint()] = sample{metric: m, v: s.v}
+		}
+		result := make(vector, 0, len(l))
+		for _, s := range l {
+			m := dropMetricName(s.metric)
+			if rsmpl, ok := rs[m.Fingerprint()]; ok {
+				result = append(result, sample{metric: m, v: arith(op, s.v, rsmpl.v)})
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStorage creates series sampled every 15s in the first 10 minutes
+func newTestStorage() *Storage {
+	s := NewStorage()
+	series := func(m model.Metric, f func(i int) float64) *model.SampleStream {
+		ss := &model.SampleStream{Metric: m}
+		for i := 0; i <= 40; i++ {
+			ss.Values = append(ss.Values, model.SamplePair{
+				Timestamp: model.Time(i * 15000),
+				Value:     model.SampleValue(f(i)),
+			})
+		}
+		return ss
+	}
+	// counters increase by 1/s and 2/s
+	s.Add(
+		series(model.Metric{"__name__": "requests_total", "instance": "a", "type": "get"}, func(i int) float64 { return float64(i * 15) }),
+		series(model.Metric{"__name__": "requests_total", "instance": "b", "type": "get"}, func(i int) float64 { return float64(i * 30) }),
+		series(model.Metric{"__name__": "requests_total", "instance": "b", "type": "put"}, func(i int) float64 {
+			// reset at the 20th sample
+			if i >= 20 {
+				return float64((i - 19) * 15)
+			}
+			return float64(i * 15)
+		}),
+	)
+	// 10% of requests are in (0, 0.1], 80% in (0.1, 0.2] and 10% in (0.2, 0.4]
+	for le, ratio := range map[string]float64{"0.1": 0.1, "0.2": 0.9, "0.4": 1, "+Inf": 1} {
+		ratio := ratio
+		s.Add(series(model.Metric{"__name__": "duration_bucket", "instance": "a", "le": model.LabelValue(le)},
+			func(i int) float64 { return float64(i*100) * ratio }))
+	}
+	return s
+}
+
+func instant(assert *require.Assertions, s *Storage, query string, ts int64) map[string]float64 {
+	t := time.Unix(ts, 0)
+	result, err := s.Query(query, t, t, time.Minute)
+	assert.Nil(err, query)
+	values := make(map[string]float64)
+	for _, ss := range result {
+		assert.Len(ss.Values, 1)
+		values[ss.Metric.String()] = float64(ss.Values[0].Value)
+	}
+	return values
+}
+
+func TestQueryInstant(t *testing.T) {
+	assert := require.New(t)
+	s := newTestStorage()
+
+	start, end, err := s.TimeRange()
+	assert.Nil(err)
+	assert.EqualValues(0, start.Unix())
+	assert.EqualValues(600, end.Unix())
+
+	assert.Equal(map[string]float64{
+		`requests_total{instance="a", type="get"}`: 150,
+		`requests_total{instance="b", type="get"}`: 300,
+		`requests_total{instance="b", type="put"}`: 150,
+	}, instant(assert, s, `requests_total`, 155))
+	assert.Equal(map[string]float64{
+		`requests_total{instance="b", type="get"}`: 300,
+	}, instant(assert, s, `requests_total{instance="b", type!="put"}`, 150))
+	// out of the lookback window
+	assert.Len(instant(assert, s, `requests_total`, 600+301), 0)
+	assert.Len(instant(assert, s, `requests_total`, 600+299), 3)
+
+	assert.Equal(map[string]float64{
+		`{instance="a"}`: 1,
+		`{instance="b"}`: 3,
+	}, instant(assert, s, `sum(rate(requests_total[1m])) by (instance)`, 300))
+	// counter reset
+	assert.Equal(map[string]float64{
+		`{instance="b", type="put"}`: 60,
+	}, instant(assert, s, `increase(requests_total{type="put"}[1m])`, 330))
+	assert.Equal(map[string]float64{
+		`{instance="a", type="get"}`: 1,
+		`{instance="b", type="get"}`: 2,
+		`{instance="b", type="put"}`: 1,
+	}, instant(assert, s, `irate(requests_total[1m])`, 300))
+	assert.Equal(map[string]float64{
+		`{type="get"}`: 1.5,
+		`{type="put"}`: 1,
+	}, instant(assert, s, `avg without (instance) (rate(requests_total[2m]))`, 300))
+	assert.Equal(map[string]float64{
+		`{}`: 3,
+	}, instant(assert, s, `count(requests_total)`, 300))
+	assert.Equal(map[string]float64{
+		`{}`: 2,
+	}, instant(assert, s, `max(rate(requests_total[1m])) - min(rate(requests_total[1m])) + 1`, 300))
+
+	// one-to-one matching of vectors
+	assert.Equal(map[string]float64{
+		`{instance="a"}`: 2,
+		`{instance="b"}`: 2,
+	}, instant(assert, s, `sum by (instance) (rate(requests_total[1m])) * 2 / sum by (instance) (rate(requests_total[1m])) + 1 - 2 / 2`, 300))
+	assert.Len(instant(assert, s, `rate(requests_total{type="get"}[1m]) / rate(requests_total{type="put"}[1m])`, 300), 0)
+
+	assert.Equal(map[string]float64{`{}`: 42}, instant(assert, s, `40 + 2`, 300))
+
+	q := instant(assert, s, `histogram_quantile(0.5, rate(duration_bucket[1m]))`, 300)
+	assert.InDelta(0.15, q[`{instance="a"}`], 1e-9)
+	q = instant(assert, s, `histogram_quantile(0.99, sum(rate(duration_bucket[1m])) by (le))`, 300)
+	assert.InDelta(0.38, q[`{}`], 1e-9)
+	q = instant(assert, s, `histogram_quantile(1.5, rate(duration_bucket[1m]))`, 300)
+	assert.True(math.IsInf(q[`{instance="a"}`], 1))
+}
+
+func TestQueryRange(t *testing.T) {
+	assert := require.New(t)
+	s := newTestStorage()
+
+	result, err := s.Query(`rate(requests_total{instance="a"}[1m])`, time.Unix(60, 0), time.Unix(600, 0), time.Minute)
+	assert.Nil(err)
+	assert.Len(result, 1)
+	assert.Len(result[0].Values, 10)
+	for _, v := range result[0].Values {
+		assert.InDelta(1, float64(v.Value), 1e-9)
+	}
+
+	_, err = s.Query(`up`, time.Unix(60, 0), time.Unix(0, 0), time.Minute)
+	assert.NotNil(err)
+	_, err = s.Query(`histogram_quantile(0.9, 1)`, time.Unix(60, 0), time.Unix(60, 0), time.Minute)
+	assert.NotNil(err)
+
+	_, _, err = NewStorage().TimeRange()
+	assert.NotNil(err)
+}
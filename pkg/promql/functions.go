@@ -0,0 +1,250 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+)
+
+type valueType int
+
+const (
+	valueScalar valueType = iota
+	valueVector
+	valueMatrix
+)
+
+type rangeArg struct {
+	sel    *VectorSelector
+	series []*model.SampleStream // samples in the range
+	ts     model.Time
+}
+
+type function struct {
+	args []valueType
+	call func(args []any) any
+}
+
+var functions = map[string]function{
+	"rate": {
+		args: []valueType{valueMatrix},
+		call: func(args []any) any { return extrapolatedRate(args[0].(rangeArg), true, true) },
+	},
+	"increase": {
+		args: []valueType{valueMatrix},
+		call: func(args []any) any { return extrapolatedRate(args[0].(rangeArg), true, false) },
+	},
+	"delta": {
+		args: []valueType{valueMatrix},
+		call: func(args []any) any { return extrapolatedRate(args[0].(rangeArg), false, false) },
+	},
+	"irate": {
+		args: []valueType{valueMatrix},
+		call: func(args []any) any { return instantRate(args[0].(rangeArg)) },
+	},
+	"histogram_quantile": {
+		args: []valueType{valueScalar, valueVector},
+		call: func(args []any) any { return histogramQuantile(args[0].(float64), args[1].(vector)) },
+	},
+}
+
+// extrapolatedRate is ported from promql/functions.go of Prometheus, it
+// calculates the increase of counters or the delta of gauges, extrapolated
+// to the edges of the range
+func extrapolatedRate(arg rangeArg, isCounter, isRate bool) vector {
+	rangeStart := arg.ts.Add(-arg.sel.Range)
+	result := make(vector, 0, len(arg.series))
+	for _, ss := range arg.series {
+		samples := ss.Values
+		if len(samples) < 2 {
+			continue
+		}
+		first, last := samples[0], samples[len(samples)-1]
+		resultValue := float64(last.Value - first.Value)
+		if isCounter {
+			// counter resets
+			for i := 1; i < len(samples); i++ {
+				if samples[i].Value < samples[i-1].Value {
+					resultValue += float64(samples[i-1].Value)
+				}
+			}
+		}
+
+		durationToStart := float64(first.Timestamp-rangeStart) / 1000
+		durationToEnd := float64(arg.ts-last.Timestamp) / 1000
+		sampledInterval := float64(last.Timestamp-first.Timestamp) / 1000
+		averageDurationBetweenSamples := sampledInterval / float64(len(samples)-1)
+
+		// counters can't be negative, so do not extrapolate below zero
+		if isCounter && resultValue > 0 && first.Value >= 0 {
+			durationToZero := sampledInterval * (float64(first.Value) / resultValue)
+			if durationToZero < durationToStart {
+				durationToStart = durationToZero
+			}
+		}
+
+		// extrapolate if the gap to the edge is less than 110% of the
+		// average interval, otherwise only extrapolate by half of it
+		extrapolationThreshold := averageDurationBetweenSamples * 1.1
+		extrapolateToInterval := sampledInterval
+		if durationToStart < extrapolationThreshold {
+			extrapolateToInterval += durationToStart
+		} else {
+			extrapolateToInterval += averageDurationBetweenSamples / 2
+		}
+		if durationToEnd < extrapolationThreshold {
+			extrapolateToInterval += durationToEnd
+		} else {
+			extrapolateToInterval += averageDurationBetweenSamples / 2
+		}
+		resultValue *= extrapolateToInterval / sampledInterval
+		if isRate {
+			resultValue /= arg.sel.Range.Seconds()
+		}
+		result = append(result, sample{metric: dropMetricName(ss.Metric), v: resultValue})
+	}
+	return result
+}
+
+// instantRate calculates the per-second rate of the last two samples
+func instantRate(arg rangeArg) vector {
+	result := make(vector, 0, len(arg.series))
+	for _, ss := range arg.series {
+		samples := ss.Values
+		if len(samples) < 2 {
+			continue
+		}
+		last, prev := samples[len(samples)-1], samples[len(samples)-2]
+		v := float64(last.Value - prev.Value)
+		if last.Value < prev.Value {
+			// counter reset
+			v = float64(last.Value)
+		}
+		interval := float64(last.Timestamp-prev.Timestamp) / 1000
+		if interval == 0 {
+			continue
+		}
+		result = append(result, sample{metric: dropMetricName(ss.Metric), v: v / interval})
+	}
+	return result
+}
+
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+// histogramQuantile calculates the quantile from buckets of classic
+// histograms, buckets are grouped by all labels except "le"
+func histogramQuantile(q float64, vec vector) vector {
+	type group struct {
+		metric  model.Metric
+		buckets []bucket
+	}
+	groups := make(map[model.Fingerprint]*group)
+	order := make([]model.Fingerprint, 0)
+	for _, s := range vec {
+		le, ok := s.metric[model.BucketLabel]
+		if !ok {
+			continue
+		}
+		ub, err := strconv.ParseFloat(string(le), 64)
+		if err != nil {
+			continue
+		}
+		m := dropMetricName(s.metric).Clone()
+		delete(m, model.BucketLabel)
+		fp := m.Fingerprint()
+		g, ok := groups[fp]
+		if !ok {
+			g = &group{metric: m}
+			groups[fp] = g
+			order = append(order, fp)
+		}
+		g.buckets = append(g.buckets, bucket{upperBound: ub, count: s.v})
+	}
+
+	result := make(vector, 0, len(groups))
+	for _, fp := range order {
+		g := groups[fp]
+		result = append(result, sample{metric: g.metric, v: bucketQuantile(q, g.buckets)})
+	}
+	return result
+}
+
+// bucketQuantile is ported from promql/quantile.go of Prometheus
+func bucketQuantile(q float64, buckets []bucket) float64 {
+	switch {
+	case math.IsNaN(q):
+		return nan
+	case q < 0:
+		return math.Inf(-1)
+	case q > 1:
+		return posInf
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].upperBound < buckets[j].upperBound })
+	if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].upperBound, 1) {
+		return nan
+	}
+
+	// merge buckets with the same upper bound
+	merged := buckets[:1]
+	for _, b := range buckets[1:] {
+		if b.upperBound == merged[len(merged)-1].upperBound {
+			merged[len(merged)-1].count += b.count
+		} else {
+			merged = append(merged, b)
+		}
+	}
+	buckets = merged
+	// counts of buckets should be monotonic, but they may not be as
+	// buckets are scraped at different time
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].count < buckets[i-1].count {
+			buckets[i].count = buckets[i-1].count
+		}
+	}
+
+	if len(buckets) < 2 {
+		return nan
+	}
+	observations := buckets[len(buckets)-1].count
+	if observations == 0 {
+		return nan
+	}
+	rank := q * observations
+	b := sort.Search(len(buckets)-1, func(i int) bool { return buckets[i].count >= rank })
+
+	if b == len(buckets)-1 {
+		return buckets[len(buckets)-2].upperBound
+	}
+	if b == 0 && buckets[0].upperBound <= 0 {
+		return buckets[0].upperBound
+	}
+	var (
+		bucketStart float64
+		bucketEnd   = buckets[b].upperBound
+		count       = buckets[b].count
+	)
+	if b > 0 {
+		bucketStart = buckets[b-1].upperBound
+		count -= buckets[b-1].count
+		rank -= buckets[b-1].count
+	}
+	return bucketStart + (bucketEnd-bucketStart)*(rank/count)
+}
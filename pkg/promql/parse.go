@@ -0,0 +1,597 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/common/model"
+)
+
+// Expr is a node of the parsed expression
+type Expr interface {
+	String() string
+}
+
+// NumberLiteral is a scalar number
+type NumberLiteral struct {
+	Val float64
+}
+
+// MatchType is the operator of a label matcher
+type MatchType string
+
+// types of label matchers
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher matches a label against a value
+type LabelMatcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	re    *regexp.Regexp
+}
+
+// VectorSelector selects series by name and labels, it's a range vector
+// selector if Range is set
+type VectorSelector struct {
+	Name     string
+	Matchers []*LabelMatcher
+	Range    time.Duration
+}
+
+// Call is a function call
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+// AggregateExpr aggregates a vector by labels
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Grouping []string
+	Without  bool
+}
+
+// BinaryExpr is an arithmetic operation
+type BinaryExpr struct {
+	Op  string
+	LHS Expr
+	RHS Expr
+}
+
+var aggregators = map[string]struct{}{
+	"sum":   {},
+	"avg":   {},
+	"min":   {},
+	"max":   {},
+	"count": {},
+}
+
+func (e *NumberLiteral) String() string {
+	return strconv.FormatFloat(e.Val, 'g', -1, 64)
+}
+
+func (m *LabelMatcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// Matches checks if the value matches
+func (m *LabelMatcher) Matches(v string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return v == m.Value
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(v)
+	case MatchNotRegexp:
+		return !m.re.MatchString(v)
+	}
+	return false
+}
+
+func (e *VectorSelector) String() string {
+	var b strings.Builder
+	b.WriteString(e.Name)
+	if len(e.Matchers) > 0 {
+		ms := make([]string, 0, len(e.Matchers))
+		for _, m := range e.Matchers {
+			ms = append(ms, m.String())
+		}
+		fmt.Fprintf(&b, "{%s}", strings.Join(ms, ","))
+	}
+	if e.Range > 0 {
+		fmt.Fprintf(&b, "[%s]", model.Duration(e.Range))
+	}
+	return b.String()
+}
+
+func (e *Call) String() string {
+	args := make([]string, 0, len(e.Args))
+	for _, a := range e.Args {
+		args = append(args, a.String())
+	}
+	return fmt.Sprintf("%s(%s)", e.Func, strings.Join(args, ", "))
+}
+
+func (e *AggregateExpr) String() string {
+	grouping := ""
+	if e.Without {
+		grouping = fmt.Sprintf(" without (%s)", strings.Join(e.Grouping, ", "))
+	} else if len(e.Grouping) > 0 {
+		grouping = fmt.Sprintf(" by (%s)", strings.Join(e.Grouping, ", "))
+	}
+	return fmt.Sprintf("%s%s(%s)", e.Op, grouping, e.Expr)
+}
+
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
+}
+
+// MetricNames returns names of all metrics selected in the expression, an
+// empty name is returned if any selector has no name
+func MetricNames(e Expr) []string {
+	names := make([]string, 0)
+	seen := make(map[string]struct{})
+	var walk func(e Expr)
+	walk = func(e Expr) {
+		switch n := e.(type) {
+		case *VectorSelector:
+			if _, ok := seen[n.Name]; !ok {
+				seen[n.Name] = struct{}{}
+				names = append(names, n.Name)
+			}
+		case *Call:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *AggregateExpr:
+			walk(n.Expr)
+		case *BinaryExpr:
+			walk(n.LHS)
+			walk(n.RHS)
+		}
+	}
+	walk(e)
+	return names
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokDuration
+	tokOp // operators and punctuations
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func lex(input string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || c == ':' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(input) && (input[j] == '_' || input[j] == ':' ||
+				unicode.IsLetter(rune(input[j])) || unicode.IsDigit(rune(input[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, input[i:j], i})
+			i = j
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(input) && unicode.IsDigit(rune(input[i+1]))):
+			j := i + 1
+			for j < len(input) && (unicode.IsDigit(rune(input[j])) || input[j] == '.' ||
+				input[j] == 'e' || input[j] == 'E' ||
+				((input[j] == '+' || input[j] == '-') && (input[j-1] == 'e' || input[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, input[i:j], i})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(input) && input[j] != byte(c) {
+				if input[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			raw := input[i+1 : j]
+			if c == '\'' {
+				raw = strings.ReplaceAll(strings.ReplaceAll(raw, `\'`, `'`), `"`, `\"`)
+			}
+			s, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %s", i, err)
+			}
+			tokens = append(tokens, token{tokString, s, i})
+			i = j + 1
+		case c == '[':
+			j := strings.IndexByte(input[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated range at position %d", i)
+			}
+			tokens = append(tokens, token{tokDuration, strings.TrimSpace(input[i+1 : i+j]), i})
+			i += j + 1
+		default:
+			if i+1 < len(input) {
+				switch two := input[i : i+2]; two {
+				case "!=", "=~", "!~":
+					tokens = append(tokens, token{tokOp, two, i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("{}(),=+-*/", c) {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i)
+			}
+			tokens = append(tokens, token{tokOp, string(c), i})
+			i++
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseExpr parses a PromQL expression, only a subset of the language is
+// supported: selectors, arithmetic operators, aggregations and some functions
+func ParseExpr(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", t.val, t.pos)
+	}
+	return e, checkExpr(e, false)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(op string) error {
+	if t := p.next(); t.typ != tokOp || t.val != op {
+		if t.typ == tokEOF {
+			return fmt.Errorf("expected '%s' but got end of input", op)
+		}
+		return fmt.Errorf("expected '%s' but got '%s' at position %d", op, t.val, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.typ != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.val == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	lhs, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next().val
+		rhs, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseTerm() (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/") {
+		op := p.next().val
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.isOp("-", "+") {
+		op := p.next().val
+		e, err := p.parseUnary()
+		if err != nil || op == "+" {
+			return e, err
+		}
+		if n, ok := e.(*NumberLiteral); ok {
+			n.Val = -n.Val
+			return n, nil
+		}
+		return &BinaryExpr{Op: "*", LHS: &NumberLiteral{Val: -1}, RHS: e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", t.val, t.pos)
+		}
+		return &NumberLiteral{Val: v}, nil
+	case tokIdent:
+		p.next()
+		if _, ok := aggregators[t.val]; ok && (p.isOp("(") || p.peekIdent("by", "without")) {
+			return p.parseAggregation(t.val)
+		}
+		if p.isOp("(") {
+			return p.parseCall(t)
+		}
+		switch strings.ToLower(t.val) {
+		case "inf":
+			return &NumberLiteral{Val: posInf}, nil
+		case "nan":
+			return &NumberLiteral{Val: nan}, nil
+		}
+		return p.parseSelector(t.val)
+	case tokOp:
+		switch t.val {
+		case "(":
+			p.next()
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "{":
+			return p.parseSelector("")
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of input")
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", t.val, t.pos)
+}
+
+func (p *parser) peekIdent(vals ...string) bool {
+	t := p.peek()
+	if t.typ != tokIdent {
+		return false
+	}
+	for _, v := range vals {
+		if t.val == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseAggregation(op string) (Expr, error) {
+	agg := &AggregateExpr{Op: op}
+	parseGrouping := func() error {
+		agg.Without = p.next().val == "without"
+		if err := p.expect("("); err != nil {
+			return err
+		}
+		agg.Grouping = make([]string, 0)
+		for !p.isOp(")") {
+			t := p.next()
+			if t.typ != tokIdent {
+				return fmt.Errorf("expected label name but got '%s' at position %d", t.val, t.pos)
+			}
+			agg.Grouping = append(agg.Grouping, t.val)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		return p.expect(")")
+	}
+
+	// the grouping clause could be either before or after the expression
+	if p.peekIdent("by", "without") {
+		if err := parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	agg.Expr = e
+	if agg.Grouping == nil && p.peekIdent("by", "without") {
+		if err := parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+	return agg, nil
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	if _, ok := functions[name.val]; !ok {
+		return nil, fmt.Errorf("unsupported function '%s' at position %d", name.val, name.pos)
+	}
+	p.next() // (
+	call := &Call{Func: name.val}
+	for !p.isOp(")") {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	return call, p.expect(")")
+}
+
+func (p *parser) parseSelector(name string) (Expr, error) {
+	sel := &VectorSelector{Name: name}
+	if p.isOp("{") {
+		p.next()
+		for !p.isOp("}") {
+			t := p.next()
+			if t.typ != tokIdent {
+				return nil, fmt.Errorf("expected label name but got '%s' at position %d", t.val, t.pos)
+			}
+			op := p.next()
+			if op.typ != tokOp {
+				return nil, fmt.Errorf("expected label matching operator at position %d", op.pos)
+			}
+			v := p.next()
+			if v.typ != tokString {
+				return nil, fmt.Errorf("expected label value but got '%s' at position %d", v.val, v.pos)
+			}
+			m := &LabelMatcher{Name: t.val, Type: MatchType(op.val), Value: v.val}
+			switch m.Type {
+			case MatchEqual, MatchNotEqual:
+			case MatchRegexp, MatchNotRegexp:
+				re, err := regexp.Compile("^(?:" + v.val + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("invalid regular expression '%s': %s", v.val, err)
+				}
+				m.re = re
+			default:
+				return nil, fmt.Errorf("unexpected label matching operator '%s' at position %d", op.val, op.pos)
+			}
+			if m.Name == model.MetricNameLabel && m.Type == MatchEqual && sel.Name == "" {
+				sel.Name = m.Value
+			} else {
+				sel.Matchers = append(sel.Matchers, m)
+			}
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect("}"); err != nil {
+			return nil, err
+		}
+	}
+	if sel.Name == "" && len(sel.Matchers) == 0 {
+		return nil, fmt.Errorf("vector selector must contain at least one matcher")
+	}
+	if t := p.peek(); t.typ == tokDuration {
+		p.next()
+		d, err := model.ParseDuration(t.val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range '%s' at position %d: %s", t.val, t.pos, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("range must be positive at position %d", t.pos)
+		}
+		sel.Range = time.Duration(d)
+	}
+	return sel, nil
+}
+
+// checkExpr validates types of arguments, range vectors are only allowed
+// as arguments of some functions
+func checkExpr(e Expr, rangeAllowed bool) error {
+	switch n := e.(type) {
+	case *VectorSelector:
+		if n.Range > 0 && !rangeAllowed {
+			return fmt.Errorf("range vector '%s' is only allowed in functions like rate()", n)
+		}
+	case *Call:
+		fn := functions[n.Func]
+		if len(n.Args) != len(fn.args) {
+			return fmt.Errorf("function %s() expects %d arguments but got %d", n.Func, len(fn.args), len(n.Args))
+		}
+		for i, a := range n.Args {
+			switch fn.args[i] {
+			case valueMatrix:
+				if sel, ok := a.(*VectorSelector); !ok || sel.Range == 0 {
+					return fmt.Errorf("function %s() expects a range vector but got '%s'", n.Func, a)
+				}
+			case valueScalar:
+				if _, ok := a.(*NumberLiteral); !ok {
+					return fmt.Errorf("function %s() expects a number but got '%s'", n.Func, a)
+				}
+			default:
+				if err := checkExpr(a, false); err != nil {
+					return err
+				}
+			}
+		}
+	case *AggregateExpr:
+		return checkExpr(n.Expr, false)
+	case *BinaryExpr:
+		if err := checkExpr(n.LHS, false); err != nil {
+			return err
+		}
+		return checkExpr(n.RHS, false)
+	}
+	return nil
+}
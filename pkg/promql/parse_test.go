@@ -0,0 +1,78 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpr(t *testing.T) {
+	assert := require.New(t)
+
+	cases := []struct {
+		input  string
+		output string
+	}{
+		{`up`, `up`},
+		{`up{job="tikv", instance!~'a.*'}`, `up{job="tikv",instance!~"a.*"}`},
+		{`{__name__="up", job=~"ti.*"}`, `up{job=~"ti.*"}`},
+		{`rate(tikv_grpc_msg_duration_seconds_count[1m])`, `rate(tikv_grpc_msg_duration_seconds_count[1m])`},
+		{`sum(rate(a[5m])) by (instance, type)`, `sum by (instance, type)(rate(a[5m]))`},
+		{`sum by (le) (irate(a_bucket[1m]))`, `sum by (le)(irate(a_bucket[1m]))`},
+		{`count without (instance) (up)`, `count without (instance)(up)`},
+		{`histogram_quantile(0.99, sum(rate(a_bucket[1m])) by (le))`, `histogram_quantile(0.99, sum by (le)(rate(a_bucket[1m])))`},
+		{`a + b * 2 - -1`, `((a + (b * 2)) - -1)`},
+		{`(a + b) / 1e3`, `((a + b) / 1000)`},
+		{`-a`, `(-1 * a)`},
+	}
+	for _, c := range cases {
+		e, err := ParseExpr(c.input)
+		assert.Nil(err, c.input)
+		assert.Equal(c.output, e.String(), c.input)
+	}
+
+	for _, input := range []string{
+		``,
+		`up{`,
+		`up{job="tikv"`,
+		`up{job=tikv}`,
+		`{}`,
+		`up[5m]`,
+		`rate(up)`,
+		`rate(up[5x])`,
+		`topk(5, up)`,
+		`histogram_quantile(up, a_bucket)`,
+		`sum(up) by (`,
+		`up{job=~"("}`,
+		`up "a"`,
+		`up @ 100`,
+	} {
+		_, err := ParseExpr(input)
+		assert.NotNil(err, input)
+	}
+}
+
+func TestMetricNames(t *testing.T) {
+	assert := require.New(t)
+
+	e, err := ParseExpr(`sum(rate(a[1m])) / sum(rate(b[1m])) + a`)
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b"}, MetricNames(e))
+
+	e, err = ParseExpr(`{job="tikv"}`)
+	assert.Nil(err)
+	assert.Equal([]string{""}, MetricNames(e))
+}
@@ -16,10 +16,29 @@ package utils
 import (
 	"io/fs"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/pingcap/tiup/pkg/localdata"
 )
 
+// DiagDataDir returns the dir storing user defined files of diag, it's
+// ~/.tiup/storage/diag by default
+func DiagDataDir() (string, error) {
+	if dir := os.Getenv(localdata.EnvNameComponentDataDir); dir != "" {
+		return dir, nil
+	}
+	if tiupHome := os.Getenv(localdata.EnvNameHome); tiupHome != "" {
+		return filepath.Join(tiupHome, localdata.StorageParentDir, "diag"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".tiup", localdata.StorageParentDir, "diag"), nil
+}
+
 // DirSize returns the total file size of a dir
 func DirSize(dir string) (int64, error) {
 	var totalSize int64
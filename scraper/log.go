@@ -24,11 +24,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pingcap/diag/collector/log/item"
 	"github.com/pingcap/diag/collector/log/parser"
 )
 
 const (
-	seekLimit      = 1024 * 1024 * 1024 // 1MB
+	seekLimit      = 1024 * 1024 // 1MB
 	LogTypeStd     = "std"
 	LogTypeSlow    = "slow"
 	LogTypeRocksDB = "rocksdb"
@@ -46,10 +47,14 @@ func IsValidLogType(logtype string) bool {
 
 // LogScraper scraps log files of components
 type LogScraper struct {
-	Paths []string        // paths of log files
-	Types map[string]bool // log type
-	Start time.Time       // start time
-	End   time.Time       // end time
+	Paths    []string         // paths of log files
+	Types    map[string]bool  // log type
+	Start    time.Time        // start time
+	End      time.Time        // end time
+	SliceDir string           // write slices of std and slow logs to the dir, empty means no slicing
+	Estimate bool             // only estimate sizes of slices by the time range, nothing is written
+	Levels   []item.LevelType // levels of entries to keep in slices
+	Keywords []string         // keywords of entries to keep in slices
 }
 
 // Scrap implements the Scraper interface
@@ -60,6 +65,9 @@ func (s *LogScraper) Scrap(result *Sample) error {
 	if result.LogTypes == nil {
 		result.LogTypes = make(FileTypes)
 	}
+	if s.SliceDir != "" && result.LogSlices == nil {
+		result.LogSlices = make(FileSlices)
+	}
 	fileList := make([]string, 0)
 
 	// extend all file paths
@@ -80,13 +88,18 @@ func (s *LogScraper) Scrap(result *Sample) error {
 			}
 
 			logtype, in, err := getLogType(fp, fi, s.Start, s.End)
-			if s.Types[logtype] && in {
-				result.Log[fp] = fi.Size()
-				result.LogTypes[fp] = logtype
-			}
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "error checking %s: %s\n", fi.Name(), err)
 			}
+			if !s.Types[logtype] || !in {
+				continue
+			}
+			if s.sliceable(fp, logtype) {
+				s.slice(result, fp, fi, logtype)
+				continue
+			}
+			result.Log[fp] = fi.Size()
+			result.LogTypes[fp] = logtype
 		} else {
 			fmt.Fprintf(os.Stderr, "error checking %s: %s\n", fi.Name(), err)
 		}
@@ -95,6 +108,54 @@ func (s *LogScraper) Scrap(result *Sample) error {
 	return nil
 }
 
+// sliceable returns true if entries of the log file could be parsed and
+// filtered, stderr logs are not
+func (s *LogScraper) sliceable(fpath, logtype string) bool {
+	if s.SliceDir == "" || strings.Contains(filepath.Base(fpath), "stderr") {
+		return false
+	}
+	return logtype == LogTypeStd || logtype == LogTypeSlow
+}
+
+// slice writes the matched entries of the log file to the slice dir, the
+// whole file is used if slicing fails, and it is skipped if nothing matches,
+// in estimate mode only the size of the slice is estimated
+func (s *LogScraper) slice(result *Sample, fpath string, fi fs.FileInfo, logtype string) {
+	filter := &LogFilter{
+		Start:    s.Start,
+		End:      s.End,
+		Levels:   s.Levels,
+		Keywords: s.Keywords,
+	}
+	dst := filepath.Join(s.SliceDir, sliceName(fpath))
+	if s.Estimate {
+		if size := estimateSlice(fpath, fi, logtype, s.Start, s.End); size > 0 {
+			result.Log[fpath] = size
+			result.LogTypes[fpath] = logtype
+			result.LogSlices[fpath] = dst
+		}
+		return
+	}
+	count, err := SliceLog(fpath, dst, logtype, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error slicing %s: %s\n", fpath, err)
+		result.Log[fpath] = fi.Size()
+		result.LogTypes[fpath] = logtype
+		return
+	}
+	if count == 0 {
+		return
+	}
+	sfi, err := os.Stat(dst)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error checking %s: %s\n", dst, err)
+		return
+	}
+	result.Log[fpath] = sfi.Size()
+	result.LogTypes[fpath] = logtype
+	result.LogSlices[fpath] = dst
+}
+
 func getLogType(fpath string, fi fs.FileInfo, start, end time.Time) (logtype string, inrange bool, err error) {
 	fileName := filepath.Base(fpath)
 	// collect stderr log despite time range
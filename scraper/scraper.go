@@ -20,8 +20,12 @@ type Option struct {
 	ConfigPaths       []string        // paths of config files
 	FilePaths         []string        // paths of normal files
 	PrometheusDataDir string
-	Start             string // start time
-	End               string // end time
+	Start             string   // start time
+	End               string   // end time
+	SliceDir          string   // dir to write slices of logs, empty means no slicing
+	EstimateSlices    bool     // estimate sizes of slices instead of writing them
+	LogLevels         []string // levels of log entries to keep in slices
+	LogKeywords       []string // keywords of log entries to keep in slices
 }
 
 // FileStat is the size information of a file to scrap
@@ -30,13 +34,18 @@ type FileStat map[string]int64
 
 type FileTypes map[string]string
 
+// FileSlices is the slices of log files to fetch instead of the whole files
+// map: filename (full path) -> path of the gzipped slice
+type FileSlices map[string]string
+
 // Sample is the result of scrapping
 type Sample struct {
-	Log      FileStat  `json:"log_files,omitempty"`
-	Config   FileStat  `json:"config_files,omitempty"`
-	File     FileStat  `json:"files,omitempty"`
-	TSDB     FileStat  `json:"prometheus_data,omitempty"`
-	LogTypes FileTypes `json:"log_types,omitempty"`
+	Log       FileStat   `json:"log_files,omitempty"`
+	Config    FileStat   `json:"config_files,omitempty"`
+	File      FileStat   `json:"files,omitempty"`
+	TSDB      FileStat   `json:"prometheus_data,omitempty"`
+	LogTypes  FileTypes  `json:"log_types,omitempty"`
+	LogSlices FileSlices `json:"log_slices,omitempty"`
 }
 
 // Scrapper is used to scrap a kind of files
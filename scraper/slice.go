@@ -0,0 +1,303 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/pingcap/diag/collector/log/parser"
+)
+
+// seekBlockSize is the precision of seeking the start time in a log file,
+// the rest is scanned line by line
+const seekBlockSize = 64 * 1024
+
+// LogFilter selects entries of a log file, an entry is a line with a
+// parsable head and all following lines without one
+type LogFilter struct {
+	Start    time.Time
+	End      time.Time
+	Levels   []item.LevelType // empty means all levels, not applied to slow logs
+	Keywords []string         // an entry is kept if it contains any of them, empty means all
+}
+
+// ParseLevels converts level names to level types
+func ParseLevels(names []string) ([]item.LevelType, error) {
+	levels := make([]item.LevelType, 0, len(names))
+	for _, name := range names {
+		level := parser.ParseLogLevel([]byte(strings.TrimSpace(name)))
+		if level == item.LevelInvalid {
+			return nil, fmt.Errorf("invalid log level: %s", name)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+type headParser func(line []byte) (*time.Time, item.LevelType)
+
+func newHeadParser(logtype string) headParser {
+	if logtype == LogTypeSlow {
+		p := &parser.SlowQueryParser{}
+		return func(line []byte) (*time.Time, item.LevelType) {
+			return p.ParseHead(bytes.TrimRight(line, "\r\n"))
+		}
+	}
+	parsers := parser.ListStd()
+	return func(line []byte) (*time.Time, item.LevelType) {
+		line = bytes.TrimRight(line, "\r\n")
+		for _, p := range parsers {
+			if t, level := p.ParseHead(line); t != nil {
+				return t, level
+			}
+		}
+		return nil, item.LevelInvalid
+	}
+}
+
+func (f *LogFilter) match(logtype string, level item.LevelType, content []byte) bool {
+	if len(f.Levels) > 0 && logtype != LogTypeSlow {
+		found := false
+		for _, l := range f.Levels {
+			if l == level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Keywords) == 0 {
+		return true
+	}
+	for _, kw := range f.Keywords {
+		if bytes.Contains(content, []byte(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceName returns the file name of the slice of a log file, the hash of
+// the full path keeps slices of files with the same base name apart
+func sliceName(fpath string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(fpath)))
+	return fmt.Sprintf("%s.%s.gz", filepath.Base(fpath), hex.EncodeToString(sum[:8]))
+}
+
+// SliceLog writes the entries of src matching the filter to dst as gzip, and
+// returns the number of entries written, dst is removed if nothing matches
+func SliceLog(src, dst, logtype string, filter *LogFilter) (int, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	parse := newHeadParser(logtype)
+
+	var r io.Reader = f
+	if strings.HasSuffix(src, ".gz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		defer gr.Close()
+		r = gr
+	} else if fi, err := f.Stat(); err == nil {
+		offset, err := seekStart(f, fi.Size(), parse, filter.Start)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	gw := gzip.NewWriter(out)
+
+	var (
+		count   int
+		entry   []byte
+		inRange bool // the current entry is in the time range
+		level   item.LevelType
+	)
+	flush := func() error {
+		if inRange && len(entry) > 0 && filter.match(logtype, level, entry) {
+			if _, err := gw.Write(entry); err != nil {
+				return err
+			}
+			count++
+		}
+		entry = entry[:0]
+		return nil
+	}
+
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, rerr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if t, l := parse(line); t != nil {
+				if err := flush(); err != nil {
+					return 0, err
+				}
+				// logs are ordered by time, no more entries after the end
+				if t.After(filter.End) {
+					break
+				}
+				inRange = !t.Before(filter.Start)
+				level = l
+			}
+			entry = append(entry, line...)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return 0, rerr
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	if err := gw.Close(); err != nil {
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, os.Remove(dst)
+	}
+	return count, nil
+}
+
+// estimateSlice estimates the size of the slice of a log file by the part of
+// the time range in the file, assuming entries are evenly distributed from
+// the first entry to the modification time, the filters are not counted
+func estimateSlice(fpath string, fi fs.FileInfo, logtype string, start, end time.Time) int64 {
+	head, err := readHeadTime(fpath, logtype)
+	last := fi.ModTime()
+	if err != nil || head == nil || !last.After(*head) {
+		return fi.Size()
+	}
+	from, to := *head, last
+	if start.After(from) {
+		from = start
+	}
+	if end.Before(to) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return int64(float64(fi.Size()) * float64(to.Sub(from)) / float64(last.Sub(*head)))
+}
+
+// readHeadTime returns the time of the first line of a log file, it is nil
+// if the line is not an entry head
+func readHeadTime(fpath, logtype string) (*time.Time, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(fpath, ".gz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	t, _ := newHeadParser(logtype)(line)
+	return t, nil
+}
+
+// seekStart binary searches an offset of a line before the first entry of
+// the start time, so large files are not read from the beginning
+func seekStart(f *os.File, size int64, parse headParser, start time.Time) (int64, error) {
+	var best int64
+	lo, hi := int64(0), size
+	for hi-lo > seekBlockSize {
+		mid := lo + (hi-lo)/2
+		pos, t, err := firstHeadAfter(f, mid, parse)
+		if err != nil {
+			return 0, err
+		}
+		if t == nil || !t.Before(start) {
+			hi = mid
+			continue
+		}
+		best = pos
+		lo = mid
+	}
+	return best, nil
+}
+
+// firstHeadAfter returns the offset and time of the first entry head after
+// the offset, the time is nil if none is found in seekLimit bytes
+func firstHeadAfter(f *os.File, offset int64, parse headParser) (int64, *time.Time, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil, err
+	}
+	reader := bufio.NewReaderSize(f, 64*1024)
+	pos := offset
+	if offset > 0 {
+		// skip the partial line
+		skipped, err := reader.ReadBytes('\n')
+		pos += int64(len(skipped))
+		if err != nil {
+			return pos, nil, nil
+		}
+	}
+	for pos-offset < seekLimit {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if t, _ := parse(line); t != nil {
+				return pos, t, nil
+			}
+		}
+		pos += int64(len(line))
+		if err != nil {
+			break
+		}
+	}
+	return pos, nil, nil
+}
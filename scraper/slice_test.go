@@ -0,0 +1,163 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/diag/collector/log/item"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogBegin = time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+
+// writeTestLog writes a log with an entry per second, every 10th entry is a
+// warning with a stack in the following line
+func writeTestLog(t *testing.T, fp string, entries int) {
+	var buf bytes.Buffer
+	for i := 0; i < entries; i++ {
+		ts := testLogBegin.Add(time.Duration(i) * time.Second).Format("2006/01/02 15:04:05.000 -07:00")
+		if i%10 == 0 {
+			fmt.Fprintf(&buf, "[%s] [WARN] [test.go:1] [\"slow request\"] [id=%d]\n  stack of %d\n", ts, i, i)
+		} else {
+			fmt.Fprintf(&buf, "[%s] [INFO] [test.go:2] [\"request\"] [id=%d]\n", ts, i)
+		}
+	}
+	require.Nil(t, os.WriteFile(fp, buf.Bytes(), 0644))
+}
+
+func readSlice(t *testing.T, fp string) []string {
+	f, err := os.Open(fp)
+	require.Nil(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.Nil(t, err)
+	data, err := io.ReadAll(r)
+	require.Nil(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestSliceLog(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "tikv.log")
+	writeTestLog(t, src, 20000)
+
+	// the start is found by seeking in the large file
+	filter := &LogFilter{
+		Start: testLogBegin.Add(15000 * time.Second),
+		End:   testLogBegin.Add(15009 * time.Second),
+	}
+	dst := filepath.Join(dir, "slices", sliceName(src))
+	count, err := SliceLog(src, dst, LogTypeStd, filter)
+	assert.Nil(err)
+	assert.Equal(10, count)
+	lines := readSlice(t, dst)
+	assert.Len(lines, 11)
+	assert.Contains(lines[0], "[id=15000]")
+	assert.Equal("  stack of 15000", lines[1])
+	assert.Contains(lines[10], "[id=15009]")
+
+	// levels and keywords
+	filter.End = testLogBegin.Add(15100 * time.Second)
+	filter.Levels = []item.LevelType{item.LevelWARN}
+	filter.Keywords = []string{"stack of 15020", "stack of 15040"}
+	count, err = SliceLog(src, dst, LogTypeStd, filter)
+	assert.Nil(err)
+	assert.Equal(2, count)
+
+	// nothing matched
+	filter.Keywords = []string{"not found"}
+	count, err = SliceLog(src, dst, LogTypeStd, filter)
+	assert.Nil(err)
+	assert.Equal(0, count)
+	_, err = os.Stat(dst)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestLogScraperSlice(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "tidb.log")
+	writeTestLog(t, src, 100)
+	assert.Nil(os.WriteFile(filepath.Join(dir, "tidb_stderr.log"), []byte("panic\n"), 0644))
+	levels, err := ParseLevels([]string{"warn"})
+	assert.Nil(err)
+	_, err = ParseLevels([]string{"verbose"})
+	assert.NotNil(err)
+
+	s := &LogScraper{
+		Paths:    []string{filepath.Join(dir, "*")},
+		Types:    map[string]bool{LogTypeStd: true},
+		Start:    testLogBegin,
+		End:      testLogBegin.Add(time.Hour),
+		SliceDir: filepath.Join(dir, "slices"),
+		Levels:   levels,
+	}
+	result := &Sample{}
+	assert.Nil(s.Scrap(result))
+	assert.Len(result.Log, 2)
+	assert.Equal(FileSlices{src: filepath.Join(dir, "slices", sliceName(src))}, result.LogSlices)
+	assert.Len(readSlice(t, result.LogSlices[src]), 20)
+}
+
+func TestEstimateSlice(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "tidb.log")
+	writeTestLog(t, src, 100)
+	// the last entry is written 100s after the first one
+	assert.Nil(os.Chtimes(src, testLogBegin.Add(100*time.Second), testLogBegin.Add(100*time.Second)))
+	fi, err := os.Stat(src)
+	assert.Nil(err)
+
+	s := &LogScraper{
+		Paths:    []string{src},
+		Types:    map[string]bool{LogTypeStd: true},
+		Start:    testLogBegin.Add(75 * time.Second),
+		End:      testLogBegin.Add(time.Hour),
+		SliceDir: filepath.Join(dir, "slices"),
+		Estimate: true,
+	}
+	result := &Sample{}
+	assert.Nil(s.Scrap(result))
+	assert.Equal(fi.Size()/4, result.Log[src])
+	assert.Equal(FileSlices{src: filepath.Join(dir, "slices", sliceName(src))}, result.LogSlices)
+	// nothing is written
+	_, err = os.Stat(s.SliceDir)
+	assert.True(os.IsNotExist(err))
+
+	assert.Zero(estimateSlice(src, fi, LogTypeStd, testLogBegin.Add(time.Hour), testLogBegin.Add(2*time.Hour)))
+}
+
+func TestSliceName(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal(sliceName("/a/b/tidb.log"), sliceName("/a//b/./tidb.log"))
+	assert.NotEqual(sliceName("/a/b_c"), sliceName("/a_b/c"))
+	assert.NotEqual(sliceName("/a/tidb.log"), sliceName("/b/tidb.log"))
+	assert.True(strings.HasPrefix(sliceName("/a/tidb.log"), "tidb.log."))
+	assert.True(strings.HasSuffix(sliceName("/a/tidb.log"), ".gz"))
+}